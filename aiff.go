@@ -0,0 +1,274 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// aiffChunk is the position of a chunk in an AIFF file.
+type aiffChunk struct {
+	ID     string
+	Offset int64 // Offset of the chunk data.
+	Size   int64 // Size of the chunk data, excluding the pad byte.
+}
+
+// aiffTextChunks maps the AIFF text chunks to the keys used in Raw.
+var aiffTextChunks = map[string]string{
+	"NAME": "name",
+	"AUTH": "author",
+	"ANNO": "annotation",
+	"(c) ": "copyright",
+}
+
+// readAIFFChunks reads the chunk positions of the AIFF (or AIFF-C) data in r, returning
+// the chunks and the offset of the end of the FORM chunk.
+func readAIFFChunks(r io.ReadSeeker) ([]aiffChunk, int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	b, err := readBytes(r, 12)
+	if err != nil {
+		return nil, 0, err
+	}
+	if string(b[0:4]) != "FORM" || (string(b[8:12]) != "AIFF" && string(b[8:12]) != "AIFC") {
+		return nil, 0, errors.New("expected 'FORM' containing 'AIFF' or 'AIFC'")
+	}
+	end := 8 + int64(binary.BigEndian.Uint32(b[4:8]))
+
+	var chunks []aiffChunk
+	for offset := int64(12); offset+8 <= end; {
+		b, err := readBytes(r, 8)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break // truncated file
+			}
+			return nil, 0, err
+		}
+
+		c := aiffChunk{
+			ID:     string(b[0:4]),
+			Offset: offset + 8,
+			Size:   int64(binary.BigEndian.Uint32(b[4:8])),
+		}
+		chunks = append(chunks, c)
+
+		// Chunks are padded to an even length.
+		offset = c.Offset + c.Size + c.Size%2
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+	}
+	return chunks, end, nil
+}
+
+// ReadAIFFTags reads AIFF metadata from the io.ReadSeeker, returning the resulting
+// metadata in a Metadata implementation, or non-nil error if there was a problem.
+// Metadata is read from the "ID3 " chunk (an ID3v2 tag), with the NAME, AUTH, ANNO
+// and "(c) " text chunks used for any fields which are not in the ID3v2 tag.  If there is
+// no "ID3 " chunk then Format returns UnknownFormat.
+func ReadAIFFTags(r io.ReadSeeker) (Metadata, error) {
+	return readAIFFTags(r, nil, ReadOptions{})
+}
+
+func readAIFFTags(r io.ReadSeeker, w *warnings, opts ReadOptions) (Metadata, error) {
+	chunks, _, err := readAIFFChunks(r)
+	if err != nil {
+		return nil, err
+	}
+
+	m := metadataAIFF{
+		Metadata: metadataID3v2{header: &id3v2Header{}, frames: make(map[string]interface{})},
+		text:     make(map[string]string),
+	}
+	for _, c := range chunks {
+		if strings.EqualFold(c.ID, "ID3 ") {
+			id3, err := readID3v2Tags(io.NewSectionReader(readerAt{r}, c.Offset, c.Size), w, opts)
+			if err != nil {
+				return nil, fmt.Errorf("error reading AIFF ID3 chunk: %v", err)
+			}
+			m.Metadata = id3
+			continue
+		}
+
+		if c.ID == "COMM" {
+			if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			b, err := readBytes(r, uint(c.Size))
+			if err != nil {
+				return nil, err
+			}
+			m.bitrate = aiffBitrate(b)
+			continue
+		}
+
+		if k, ok := aiffTextChunks[c.ID]; ok {
+			if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			s, err := readString(r, uint(c.Size))
+			if err != nil {
+				return nil, err
+			}
+			m.text[k] = strings.TrimRight(s, "\x00")
+		}
+	}
+	return m, nil
+}
+
+// readerAt implements io.ReaderAt for an io.ReadSeeker.
+type readerAt struct {
+	io.ReadSeeker
+}
+
+func (r readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(r, p)
+}
+
+// metadataAIFF is the implementation of Metadata for AIFF files.  Accessors are provided
+// by the ID3v2 tag (which is empty if there is no "ID3 " chunk) unless overridden below.
+type metadataAIFF struct {
+	Metadata
+	text    map[string]string // AIFF text chunks
+	bitrate int               // from the COMM chunk, zero if unavailable
+}
+
+func (m metadataAIFF) FileType() FileType { return AIFF }
+
+func (m metadataAIFF) Bitrate() (int, bool) { return m.bitrate, m.bitrate > 0 }
+
+// aiffBitrate returns the bitrate of the audio described by the COMM chunk data b: channels
+// (2 bytes), sample frames (4), sample size (2) and sample rate (80-bit extended).  AIFF-C
+// adds the compression type, and zero is returned unless the audio is uncompressed.
+func aiffBitrate(b []byte) int {
+	if len(b) < 18 {
+		return 0
+	}
+	if len(b) >= 22 {
+		switch string(b[18:22]) {
+		case "NONE", "sowt", "twos", "raw ":
+		default:
+			return 0
+		}
+	}
+	channels := int(binary.BigEndian.Uint16(b[0:2]))
+	bits := int(binary.BigEndian.Uint16(b[6:8]))
+
+	// The sample rate is an 80-bit extended precision number: sign and 15-bit exponent
+	// (with a bias of 16383), then a 64-bit mantissa with an explicit integer bit.
+	exp := int(binary.BigEndian.Uint16(b[8:10])&0x7FFF) - 16383
+	mantissa := binary.BigEndian.Uint64(b[10:18])
+	if exp < 0 || exp > 63 {
+		return 0
+	}
+	rate := int(mantissa >> (63 - uint(exp)))
+	return channels * bits * rate
+}
+
+func (m metadataAIFF) Raw() map[string]interface{} {
+	raw := make(map[string]interface{})
+	for k, v := range m.Metadata.Raw() {
+		raw[k] = v
+	}
+	for k, v := range m.text {
+		raw[k] = v
+	}
+	return raw
+}
+
+func (m metadataAIFF) Title() string {
+	if t := m.Metadata.Title(); t != "" {
+		return t
+	}
+	return m.text["name"]
+}
+
+func (m metadataAIFF) Artist() string {
+	if a := m.Metadata.Artist(); a != "" {
+		return a
+	}
+	return m.text["author"]
+}
+
+func (m metadataAIFF) Copyright() string {
+	if c := m.Metadata.Copyright(); c != "" {
+		return c
+	}
+	return m.text["copyright"]
+}
+
+func (m metadataAIFF) Comment() string {
+	if c := m.Metadata.Comment(); c != "" {
+		return c
+	}
+	return m.text["annotation"]
+}
+
+// WriteAIFFTags writes the fields in data to the AIFF data in rw as an ID3v2.4 tag (see
+// WriteID3v2Tags), replacing the existing "ID3 " chunk or adding one at the end of the FORM chunk.
+// The existing chunk space is reused if the new tag fits, otherwise the following data is moved.
+func WriteAIFFTags(rw io.ReadWriteSeeker, data map[string]string) error {
+	data = normaliseFields(data)
+	chunks, end, err := readAIFFChunks(rw)
+	if err != nil {
+		return err
+	}
+
+	// Position and size (including the header and pad byte) of the existing chunk.
+	offset, old := end, int64(0)
+	style := defaultID3v2WriteStyle
+	var kept []byte
+	for _, c := range chunks {
+		if strings.EqualFold(c.ID, "ID3 ") {
+			offset, old = c.Offset-8, 8+c.Size+c.Size%2
+			r := io.NewSectionReader(readerAt{rw}, c.Offset, c.Size)
+			style = readID3v2WriteStyle(r)
+			if kept, err = keptID3v2Frames(r, data); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	frames := append(buildID3v24Frames(data, style), kept...)
+	if len(frames) > id3v2MaxSize {
+		return errors.New("ID3v2 tag too large")
+	}
+
+	size := 10 + int64(len(frames))
+	if 8+size > old {
+		size += id3v2Padding
+	} else {
+		size = old - 8
+	}
+	size += size % 2 // keep the chunk size even so no pad byte is needed
+
+	b := make([]byte, 8+size)
+	copy(b, "ID3 ")
+	binary.BigEndian.PutUint32(b[4:8], uint32(size))
+	copy(b[8:], "ID3")
+	b[11] = 4 // version 2.4.0
+	copy(b[14:18], format7BitChunkedUint(uint(size-10), 4))
+	copy(b[18:], frames)
+
+	if err := replaceRegion(rw, offset, old, b); err != nil {
+		return err
+	}
+
+	// Update the FORM chunk size.
+	if _, err := rw.Seek(4, io.SeekStart); err != nil {
+		return err
+	}
+	return binary.Write(rw, binary.BigEndian, uint32(end-8+int64(len(b))-old))
+}
@@ -0,0 +1,152 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// testAIFFChunk returns an AIFF chunk with the given ID and data (padded to an even length).
+func testAIFFChunk(id string, data []byte) []byte {
+	b := append([]byte(id), 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[4:8], uint32(len(data)))
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// testAIFF returns AIFF data with a COMM and SSND chunk (for 1 channel of 16-bit audio)
+// followed by the given chunks.
+func testAIFF(chunks ...[]byte) []byte {
+	comm := []byte{
+		0, 1, // channels
+		0, 0, 0, 4, // sample frames
+		0, 16, // sample size
+		0x40, 0x0E, 0xAC, 0x44, 0, 0, 0, 0, 0, 0, // 44100 as 80-bit extended
+	}
+	ssnd := append(make([]byte, 8), 1, 2, 3, 4, 5, 6, 7, 8)
+
+	b := []byte("FORM\x00\x00\x00\x00AIFF")
+	b = append(b, testAIFFChunk("COMM", comm)...)
+	b = append(b, testAIFFChunk("SSND", ssnd)...)
+	for _, c := range chunks {
+		b = append(b, c...)
+	}
+	binary.BigEndian.PutUint32(b[4:8], uint32(len(b)-8))
+	return b
+}
+
+// testAIFFAudio returns the data of the SSND chunk in the AIFF data in r.
+func testAIFFAudio(t *testing.T, r io.ReadSeeker) []byte {
+	t.Helper()
+	chunks, end, err := readAIFFChunks(r)
+	if err != nil {
+		t.Fatalf("readAIFFChunks() = %v", err)
+	}
+	if size, err := r.Seek(0, io.SeekEnd); err != nil || size != end {
+		t.Errorf("FORM chunk ends at %d, expected %d (%v)", end, size, err)
+	}
+	for _, c := range chunks {
+		if c.ID == "SSND" {
+			if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+				t.Fatal(err)
+			}
+			b, err := readBytes(r, uint(c.Size))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return b
+		}
+	}
+	t.Fatal("no SSND chunk")
+	return nil
+}
+
+func TestReadAIFFTextChunks(t *testing.T) {
+	b := testAIFF(
+		testAIFFChunk("NAME", []byte("Test Title")),
+		testAIFFChunk("AUTH", []byte("Test Artist")),
+		testAIFFChunk("ANNO", []byte("Test Comment")),
+	)
+
+	m, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, AIFF, m.FileType())
+	testValue(t, UnknownFormat, m.Format())
+	testValue(t, "Test Title", m.Title())
+	testValue(t, "Test Artist", m.Artist())
+	testValue(t, "Test Comment", m.Comment())
+	testValue(t, "Test Title", m.Raw()["name"])
+}
+
+func TestReadAIFFID3Chunk(t *testing.T) {
+	tag := testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Data: []byte("\x00ID3 Title")},
+		id3v2RawFrame{Name: "TALB", Data: []byte("\x00Test Album")},
+	)
+	b := testAIFF(
+		testAIFFChunk("NAME", []byte("Chunk Title")),
+		testAIFFChunk("AUTH", []byte("Chunk Artist")),
+		testAIFFChunk("ID3 ", tag),
+	)
+
+	m, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, ID3v2_3, m.Format())
+	testValue(t, "ID3 Title", m.Title())     // ID3 tag takes precedence
+	testValue(t, "Chunk Artist", m.Artist()) // falls back to text chunk
+	testValue(t, "Test Album", m.Album())
+}
+
+func TestWriteAIFFTags(t *testing.T) {
+	orig := testAIFF(testAIFFChunk("NAME", []byte("Old")))
+	f := tempFile(t, orig)
+	audio := testAIFFAudio(t, bytes.NewReader(orig))
+
+	data := map[string]string{
+		FieldTitle:       "Test Title",
+		FieldArtist:      "Test Artist",
+		FieldTrackNumber: "3",
+		FieldTrackTotal:  "6",
+	}
+	if err := WriteAIFFTags(f, data); err != nil {
+		t.Fatalf("WriteAIFFTags() = %v", err)
+	}
+	size := int64(len(readAll(t, f)))
+
+	// A second (smaller) write reuses the chunk.
+	data[FieldArtist] = "A"
+	if err := WriteAIFFTags(f, data); err != nil {
+		t.Fatalf("WriteAIFFTags() = %v", err)
+	}
+	testValue(t, size, int64(len(readAll(t, f))))
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, ID3v2_4, m.Format())
+	testValue(t, "Test Title", m.Title())
+	testValue(t, "A", m.Artist())
+	track, total := m.Track()
+	testValue(t, 3, track)
+	testValue(t, 6, total)
+
+	if got := testAIFFAudio(t, f); !bytes.Equal(got, audio) {
+		t.Errorf("audio data changed after write")
+	}
+}
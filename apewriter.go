@@ -0,0 +1,109 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+)
+
+// apeKeys maps field names to APEv2 item keys (see http://wiki.hydrogenaud.io/index.php?title=APE_key).
+var apeKeys = map[string]string{
+	FieldTitle:       "Title",
+	FieldArtist:      "Artist",
+	FieldAlbum:       "Album",
+	FieldAlbumArtist: "Album Artist",
+	FieldComposer:    "Composer",
+	FieldGenre:       "Genre",
+	FieldComment:     "Comment",
+	FieldCopyright:   "Copyright",
+	FieldPublisher:   "Publisher",
+	FieldLyrics:      "Lyrics",
+	FieldMood:        "Mood",
+}
+
+// apeTag returns an APEv2 tag (with header and footer) representing data, which must already
+// be normalised.  Fields without a standard APEv2 key are written using the field name.
+func apeTag(data map[string]string) ([]byte, error) {
+	items := make(map[string]string, len(data))
+	for k, v := range data {
+		switch k {
+		case FieldDate, FieldYear:
+			items["Year"] = fieldYear(data)
+
+		case FieldTrackNumber:
+			items["Track"] = formatXofN(v, data[FieldTrackTotal])
+
+		case FieldDiscNumber:
+			items["Disc"] = formatXofN(v, data[FieldDiscTotal])
+
+		case FieldTrackTotal, FieldDiscTotal:
+			// Written with the corresponding number.
+
+		default:
+			if ak, ok := apeKeys[k]; ok {
+				k = ak
+			}
+			if len(k) < 2 || len(k) > 255 {
+				return nil, errors.New("invalid APEv2 key: " + k)
+			}
+			items[k] = v
+		}
+	}
+
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var body []byte
+	for _, k := range keys {
+		v := items[k]
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(v)))
+		body = binary.LittleEndian.AppendUint32(body, 0) // flags: UTF-8 text
+		body = append(body, k...)
+		body = append(body, 0)
+		body = append(body, v...)
+	}
+
+	headerFooter := func(flags uint32) []byte {
+		b := make([]byte, apeFooterSize)
+		copy(b, "APETAGEX")
+		binary.LittleEndian.PutUint32(b[8:12], 2000) // version
+		binary.LittleEndian.PutUint32(b[12:16], uint32(len(body)+apeFooterSize))
+		binary.LittleEndian.PutUint32(b[16:20], uint32(len(keys)))
+		binary.LittleEndian.PutUint32(b[20:24], flags)
+		return b
+	}
+
+	b := headerFooter(1<<31 | 1<<29) // contains a header, this is the header
+	b = append(b, body...)
+	return append(b, headerFooter(1<<31)...), nil
+}
+
+// AppendTrailerTags writes the fields in data to the end of rw as an APEv2 tag followed by an
+// ID3v1.1 tag (see WriteID3Both), replacing any existing APEv2 and ID3v1 tags at the end of rw.
+// Unlike the other Write functions, the audio data is never moved.  If the new tags are smaller
+// than the existing ones then rw must implement Truncate (i.e. *os.File).
+func AppendTrailerTags(rw io.ReadWriteSeeker, data map[string]string) error {
+	data = normaliseFields(data)
+	ape, err := apeTag(data)
+	if err != nil {
+		return err
+	}
+
+	old, err := trailerTagSize(rw)
+	if err != nil {
+		return err
+	}
+	end, err := rw.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	return replaceRegion(rw, end-old, old, append(ape, id3v1Tag(data)...))
+}
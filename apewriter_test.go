@@ -0,0 +1,98 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// testReadAPEItems returns the items of the APEv2 tag which ends b.
+func testReadAPEItems(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	if len(b) < apeFooterSize || string(b[len(b)-apeFooterSize:len(b)-apeFooterSize+8]) != "APETAGEX" {
+		t.Fatalf("no APEv2 footer")
+	}
+	footer := b[len(b)-apeFooterSize:]
+	size := int(binary.LittleEndian.Uint32(footer[12:16]))
+	count := int(binary.LittleEndian.Uint32(footer[16:20]))
+	body := b[len(b)-size : len(b)-apeFooterSize]
+
+	items := make(map[string]string)
+	for i := 0; i < count; i++ {
+		n := int(binary.LittleEndian.Uint32(body[0:4]))
+		k := bytes.IndexByte(body[8:], 0)
+		items[string(body[8:8+k])] = string(body[9+k : 9+k+n])
+		body = body[9+k+n:]
+	}
+	return items
+}
+
+func TestAppendTrailerTags(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		trailer []byte // existing trailer
+	}{
+		{"append", nil},
+		{"replace ID3v1", id3v1Tag(map[string]string{FieldTitle: "Old Title"})},
+		{"replace APEv2", testAPETag("Title", "A Much Longer Old Title Than The New One")},
+		{"replace both", bytes.Join([][]byte{
+			testAPETag("Title", "A Much Longer Old Title Than The New One"),
+			id3v1Tag(map[string]string{FieldTitle: "Old Title"}),
+		}, nil)},
+	}
+
+	for _, tt := range tests {
+		f := tempFile(t, append(append([]byte{}, audio...), tt.trailer...))
+		data := map[string]string{
+			"title":       "Новая песня", // not representable in ID3v1
+			"artist":      "Artist",
+			"tracknumber": "3",
+			"tracktotal":  "10",
+			"date":        "2001-02-03",
+		}
+		if err := AppendTrailerTags(f, data); err != nil {
+			t.Fatalf("%v: AppendTrailerTags() = %v", tt.name, err)
+		}
+
+		b := readAll(t, f)
+		if !bytes.HasPrefix(b, audio) {
+			t.Fatalf("%v: audio data changed", tt.name)
+		}
+		ape, err := apeTag(normaliseFields(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != len(audio)+len(ape)+id3v1Size {
+			t.Errorf("%v: got %d bytes, expected %d", tt.name, len(b), len(audio)+len(ape)+id3v1Size)
+		}
+
+		want := map[string]string{
+			"Title":  "Новая песня",
+			"Artist": "Artist",
+			"Track":  "3/10",
+			"Year":   "2001",
+		}
+		if got := testReadAPEItems(t, b[:len(b)-id3v1Size]); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: APEv2 items = %v, expected %v", tt.name, got, want)
+		}
+
+		m, err := ReadID3v1Tags(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%v: ReadID3v1Tags() = %v", tt.name, err)
+		}
+		testValue(t, "Artist", m.Artist())
+		track, _ := m.Track()
+		testValue(t, 3, track)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// AudioProperties describes the audio data of a file.  Fields which are not known are zero.
+type AudioProperties struct {
+	SampleRate    int    // Hz.
+	Channels      int    // Number of channels.
+	BitsPerSample int    // Zero for lossy formats.
+	TotalSamples  uint64 // Samples per channel.
+
+	// Bitrates (in bits per second) given in the header of the audio data.  Vorbis encoders
+	// write the nominal bitrate (and the limits for managed bitrate encoding) as hints, which
+	// may not match the actual bitrate.
+	NominalBitrate int
+	MinBitrate     int
+	MaxBitrate     int
+}
+
+// Duration returns the duration of the audio, or zero if it is unknown.
+func (a AudioProperties) Duration() time.Duration {
+	if a.SampleRate <= 0 {
+		return 0
+	}
+	rate := uint64(a.SampleRate)
+	secs, rem := a.TotalSamples/rate, a.TotalSamples%rate
+	return time.Duration(secs)*time.Second + time.Duration(rem)*time.Second/time.Duration(rate)
+}
+
+// AudioMetadata is implemented by the Metadata returned for FLAC and Ogg Vorbis files, which
+// describe the audio data.
+type AudioMetadata interface {
+	// AudioProperties returns the properties of the audio data, the boolean is false if they
+	// could not be read.
+	AudioProperties() (AudioProperties, bool)
+}
+
+func (m *metadataFLAC) AudioProperties() (AudioProperties, bool) {
+	if m.streamInfo == nil {
+		return AudioProperties{}, false
+	}
+	return AudioProperties{
+		SampleRate:    m.streamInfo.SampleRate,
+		Channels:      m.streamInfo.Channels,
+		BitsPerSample: m.streamInfo.BitsPerSample,
+		TotalSamples:  m.streamInfo.TotalSamples,
+	}, true
+}
+
+func (m *metadataOGG) AudioProperties() (AudioProperties, bool) {
+	if m.audio == nil {
+		return AudioProperties{}, false
+	}
+	return *m.audio, true
+}
+
+func (m *metadataFLAC) Bitrate() (int, bool) {
+	if m.streamInfo == nil || m.streamInfo.TotalSamples == 0 || m.audioSize <= 0 {
+		return 0, false
+	}
+	rate := uint64(m.streamInfo.SampleRate)
+	return int(uint64(m.audioSize) * 8 * rate / m.streamInfo.TotalSamples), true
+}
+
+func (m *metadataOGG) Bitrate() (int, bool) {
+	// Only Vorbis has a bitrate in the identification header.
+	if m.audio == nil || m.audio.NominalBitrate == 0 {
+		return 0, false
+	}
+	return m.audio.NominalBitrate, true
+}
+
+// vorbisIdentPrefix is the start of the Vorbis identification header packet.
+var vorbisIdentPrefix = []byte("\x01vorbis")
+
+// readVorbisIdentHeader parses the Vorbis identification header b (without the packet type
+// and "vorbis" prefix), see https://xiph.org/vorbis/doc/Vorbis_I_spec.html#x1-630004.2.2.
+func readVorbisIdentHeader(b []byte) (*AudioProperties, error) {
+	if len(b) < 23 {
+		return nil, errors.New("invalid Vorbis identification header")
+	}
+	if v := binary.LittleEndian.Uint32(b[0:4]); v != 0 {
+		return nil, errors.New("unsupported Vorbis version")
+	}
+
+	// Bitrates are signed, with zero (or -1 in older encoders) meaning unset.
+	bitrate := func(b []byte) int {
+		if n := int32(binary.LittleEndian.Uint32(b)); n > 0 {
+			return int(n)
+		}
+		return 0
+	}
+	return &AudioProperties{
+		Channels:       int(b[4]),
+		SampleRate:     int(binary.LittleEndian.Uint32(b[5:9])),
+		MaxBitrate:     bitrate(b[9:13]),
+		NominalBitrate: bitrate(b[13:17]),
+		MinBitrate:     bitrate(b[17:21]),
+	}, nil
+}
+
+// oggLastPageSearch is the number of bytes at the end of Ogg data searched for the last page.
+const oggLastPageSearch = 64 << 10
+
+// oggLastGranulePosition returns the granule position of the last page of the Ogg data in r
+// (for Vorbis, the number of samples per channel), restoring the position of r.  The boolean
+// is false if there is no such page.
+func oggLastGranulePosition(r io.ReadSeeker) (uint64, bool) {
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	defer r.Seek(pos, io.SeekStart)
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	start := size - oggLastPageSearch
+	if start < 0 {
+		start = 0
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, false
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, false
+	}
+
+	// A granule position of -1 means that no packet finishes on the page.
+	for i := bytes.LastIndex(b, []byte("OggS")); i >= 0; i = bytes.LastIndex(b[:i], []byte("OggS")) {
+		if i+14 > len(b) {
+			continue
+		}
+		if g := binary.LittleEndian.Uint64(b[i+6 : i+14]); g != 1<<64-1 {
+			return g, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,147 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAudioProperties(t *testing.T) {
+	vorbis := AudioProperties{SampleRate: 44100, Channels: 2, TotalSamples: 149880, NominalBitrate: 64000}
+	tests := []struct {
+		path     string
+		expected AudioProperties
+		duration time.Duration
+	}{
+		{"with_tags/sample.ogg", vorbis, 3398639455},
+		{"with_tags/sample.multipage.ogg", vorbis, 3398639455},
+		{"without_tags/sample.ogg", vorbis, 3398639455},
+		{"with_tags/sample.flac", AudioProperties{SampleRate: 11025, Channels: 1, BitsPerSample: 16, TotalSamples: 37478}, 3399365079},
+	}
+
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.path, err)
+			continue
+		}
+		am, ok := m.(AudioMetadata)
+		if !ok {
+			t.Errorf("%v: %T does not implement AudioMetadata", tt.path, m)
+			continue
+		}
+		a, ok := am.AudioProperties()
+		if !ok || a != tt.expected {
+			t.Errorf("%v: AudioProperties() = %+v, %v, expected %+v", tt.path, a, ok, tt.expected)
+		}
+		if d := a.Duration(); d != tt.duration {
+			t.Errorf("%v: Duration() = %v, expected %v", tt.path, d, tt.duration)
+		}
+	}
+
+	// The identification header is kept when the comment is rewritten.
+	f := tempCopy(t, "with_tags/sample.ogg")
+	if err := WriteOGGTags(f, map[string]string{FieldTitle: "New Title"}); err != nil {
+		t.Fatalf("WriteOGGTags() = %v", err)
+	}
+	f.Seek(0, io.SeekStart)
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if a, _ := m.(AudioMetadata).AudioProperties(); a != vorbis {
+		t.Errorf("AudioProperties() = %+v after WriteOGGTags, expected %+v", a, vorbis)
+	}
+}
+
+func TestReadVorbisIdentHeader(t *testing.T) {
+	b := []byte{
+		0, 0, 0, 0, // version
+		1,                      // channels
+		0x80, 0xBB, 0x00, 0x00, // 48000 Hz
+		0x00, 0xE2, 0x04, 0x00, // maximum 320000
+		0x00, 0xF4, 0x01, 0x00, // nominal 128000
+		0xFF, 0xFF, 0xFF, 0xFF, // minimum unset (-1)
+		0xB8, 1, // block sizes, framing
+	}
+	a, err := readVorbisIdentHeader(b)
+	if err != nil {
+		t.Fatalf("readVorbisIdentHeader() = %v", err)
+	}
+	expected := AudioProperties{SampleRate: 48000, Channels: 1, MaxBitrate: 320000, NominalBitrate: 128000}
+	if *a != expected {
+		t.Errorf("readVorbisIdentHeader() = %+v, expected %+v", *a, expected)
+	}
+
+	if _, err := readVorbisIdentHeader(b[:10]); err == nil {
+		t.Errorf("readVorbisIdentHeader() of short header = nil, expected error")
+	}
+}
+
+func TestBitrate(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Xing header gives 65536 bytes in 256 frames of 1152 samples at 44100 Hz.
+	vbr := append(testID3v2Tag(), append(testLAMEFrame("LAME3.100", 57, 0x24, 576, 1000), audio...)...)
+
+	open := func(path string) io.ReadSeeker {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+
+	tests := []struct {
+		name    string
+		r       io.ReadSeeker
+		bitrate int
+		ok      bool
+	}{
+		// Calculated from the size and duration of the audio.
+		{"FLAC", open("testdata/with_tags/sample.flac"), 141000, true},
+		{"MP4", open("testdata/with_tags/sample.m4a"), 76000, true},
+
+		// From the headers.
+		{"Ogg Vorbis", open("testdata/with_tags/sample.ogg"), 64000, true},
+		{"MP3", open("testdata/with_tags/sample.id3v24.mp3"), 128000, true},
+		{"MP3 (Xing)", bytes.NewReader(vbr), 78400, true},
+		{"DSF", open("testdata/with_tags/sample.dsf"), 2 * 2822400, true},
+		{"AIFF", bytes.NewReader(testAIFF()), 44100 * 16, true},
+		{"DSDIFF", bytes.NewReader(testDFF()), 2822400, true},
+
+		{"ID3v1", open("testdata/with_tags/sample.id3v11.mp3"), 0, false},
+	}
+
+	for _, tt := range tests {
+		m, err := ReadFrom(tt.r)
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", tt.name, err)
+			continue
+		}
+		bitrate, ok := m.Bitrate()
+		if ok != tt.ok {
+			t.Errorf("%v: Bitrate() = %v, %v, expected ok: %v", tt.name, bitrate, ok, tt.ok)
+			continue
+		}
+		// Allow 1% either way.
+		if diff := bitrate - tt.bitrate; diff*100 > tt.bitrate || -diff*100 > tt.bitrate {
+			t.Errorf("%v: Bitrate() = %v, expected: %v (±1%%)", tt.name, bitrate, tt.bitrate)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// ChannelMetadata is implemented by the Metadata returned for FLAC files, which describe the
+// channels of the audio data.
+type ChannelMetadata interface {
+	// ChannelMask returns the speaker positions of the channels (as a WAVEFORMATEXTENSIBLE
+	// dwChannelMask), the boolean is false if there is no channel mask.
+	ChannelMask() (uint32, bool)
+
+	// ChannelLayout returns the channel layout (i.e. "2.0" or "5.1"), or an empty string if
+	// it is unknown.
+	ChannelLayout() string
+}
+
+// channelMaskLFE is the channel mask bit of the low-frequency effects channel.
+const channelMaskLFE = 0x8
+
+// flacDefaultLayouts are the channel layouts used by FLAC for each number of channels when
+// there is no channel mask (see https://xiph.org/flac/format.html#frame_header).
+var flacDefaultLayouts = [...]string{1: "1.0", 2: "2.0", 3: "3.0", 4: "4.0", 5: "5.0", 6: "5.1", 7: "6.1", 8: "7.1"}
+
+// ChannelMask returns the value of the WAVEFORMATEXTENSIBLE_CHANNEL_MASK field (as written by
+// the flac encoder for audio with a non-default channel assignment), i.e. "0x0003".
+func (m *metadataVorbis) ChannelMask() (uint32, bool) {
+	s := strings.ToLower(strings.TrimSpace(m.c["waveformatextensible_channel_mask"]))
+	if !strings.HasPrefix(s, "0x") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[2:], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint32(n), true
+}
+
+// channelMaskLayout returns the layout described by the channel mask: the number of full range
+// channels and the number of LFE channels.
+func channelMaskLayout(mask uint32) string {
+	lfe := 0
+	if mask&channelMaskLFE != 0 {
+		lfe = 1
+	}
+	return fmt.Sprintf("%d.%d", bits.OnesCount32(mask&^channelMaskLFE), lfe)
+}
+
+func (m *metadataFLAC) ChannelLayout() string {
+	if m.streamInfo == nil {
+		return ""
+	}
+	channels := m.streamInfo.Channels
+	// A mask which doesn't match the number of channels is ignored.
+	if mask, ok := m.ChannelMask(); ok && bits.OnesCount32(mask) == channels {
+		return channelMaskLayout(mask)
+	}
+	if channels < len(flacDefaultLayouts) {
+		return flacDefaultLayouts[channels]
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testFLACStreamInfoData returns the content of a STREAMINFO block for 16 bit, 44.1kHz audio
+// with the given number of channels.
+func testFLACStreamInfoData(channels int) []byte {
+	b := make([]byte, 34)
+	binary.BigEndian.PutUint64(b[10:18], 44100<<44|uint64(channels-1)<<41|15<<36)
+	return b
+}
+
+func TestChannelLayout(t *testing.T) {
+	tests := []struct {
+		channels int
+		mask     string
+		wantMask uint32
+		layout   string
+	}{
+		{2, "", 0, "2.0"},
+		{6, "", 0, "5.1"},
+		{6, "0x003F", 0x3F, "5.1"},
+		{6, "0x060F", 0x60F, "5.1"}, // side surrounds
+		{4, "0x0033", 0x33, "4.0"},
+		{3, "0x000B", 0xB, "2.1"},
+		{8, "0x063F", 0x63F, "7.1"},
+		{2, "0x003F", 0x3F, "2.0"}, // mask doesn't match the number of channels
+		{1, "bogus", 0, "1.0"},
+	}
+
+	for _, tt := range tests {
+		fields := map[string]string{"TITLE": "Surround"}
+		if tt.mask != "" {
+			fields["WAVEFORMATEXTENSIBLE_CHANNEL_MASK"] = tt.mask
+		}
+		b := []byte("fLaC")
+		b = append(b, testFLACBlock(streamInfoBlock, false, testFLACStreamInfoData(tt.channels))...)
+		b = append(b, testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, fields))...)
+
+		m, err := ReadFrom(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("ReadFrom() = %v", err)
+		}
+		cm, ok := m.(ChannelMetadata)
+		if !ok {
+			t.Fatalf("FLAC Metadata does not implement ChannelMetadata")
+		}
+
+		mask, ok := cm.ChannelMask()
+		if mask != tt.wantMask || ok != (tt.wantMask != 0) {
+			t.Errorf("%d channels, mask %q: ChannelMask() = %#x, %v, expected %#x", tt.channels, tt.mask, mask, ok, tt.wantMask)
+		}
+		if got := cm.ChannelLayout(); got != tt.layout {
+			t.Errorf("%d channels, mask %q: ChannelLayout() = %q, expected %q", tt.channels, tt.mask, got, tt.layout)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+// ClassicalInfo is the work and movement of a track of classical music, as written by iTunes
+// (and MusicBrainz Picard).
+type ClassicalInfo struct {
+	Work           string // Title of the work (see Metadata.Work).
+	MovementName   string
+	MovementNumber int
+	MovementCount  int // Number of movements in the work.
+	Composer       string
+}
+
+// classicalInfo returns c and whether it has a work or movement (the composer alone is not
+// enough, as it is used for all kinds of music).
+func classicalInfo(c ClassicalInfo) (ClassicalInfo, bool) {
+	return c, c.Work != "" || c.MovementName != "" || c.MovementNumber != 0
+}
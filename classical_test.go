@@ -0,0 +1,85 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestClassicalInfo(t *testing.T) {
+	want := ClassicalInfo{
+		Work:           "Symphony No. 5",
+		MovementName:   "Allegro con brio",
+		MovementNumber: 1,
+		MovementCount:  4,
+		Composer:       "Ludwig van Beethoven",
+	}
+
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3 := append(testID3v2Tag(
+		id3v2RawFrame{Name: "TIT1", Data: []byte("\x00Symphony No. 5")},
+		id3v2RawFrame{Name: "GRP1", Data: []byte("\x00Beethoven")},
+		id3v2RawFrame{Name: "MVNM", Data: []byte("\x00Allegro con brio")},
+		id3v2RawFrame{Name: "MVIN", Data: []byte("\x001/4")},
+		id3v2RawFrame{Name: "TCOM", Data: []byte("\x00Ludwig van Beethoven")},
+	), audio...)
+
+	mp4 := tempCopy(t, "without_tags/sample.m4a")
+	addTestMP4Items(t, mp4,
+		mp4Item("\xa9wrk", mp4ClassText, []byte("Symphony No. 5")),
+		mp4Item("\xa9mvn", mp4ClassText, []byte("Allegro con brio")),
+		mp4Item("\xa9mvi", mp4ClassInt, []byte{0, 1}),
+		mp4Item("\xa9mvc", mp4ClassInt, []byte{0, 4}),
+		mp4Item("\xa9wrt", mp4ClassText, []byte("Ludwig van Beethoven")),
+	)
+
+	flac := tempCopy(t, "without_tags/sample.flac")
+	err = WriteFLACTags(flac, map[string]string{
+		"WORK":          "Symphony No. 5",
+		"MOVEMENTNAME":  "Allegro con brio",
+		"MOVEMENT":      "1",
+		"MOVEMENTTOTAL": "4",
+		"COMPOSER":      "Ludwig van Beethoven",
+	})
+	if err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		r    io.ReadSeeker
+	}{
+		{"ID3v2", bytes.NewReader(mp3)},
+		{"MP4", mp4},
+		{"FLAC", flac},
+	}
+	for _, tt := range tests {
+		if _, err := tt.r.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(tt.r)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", tt.name, err)
+		}
+		got, ok := m.ClassicalInfo()
+		if !ok || got != want {
+			t.Errorf("%v: ClassicalInfo() = %+v, %v, expected %+v, true", tt.name, got, ok, want)
+		}
+	}
+
+	m, err := ReadFrom(tempCopy(t, "with_tags/sample.flac"))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if _, ok := m.ClassicalInfo(); ok {
+		t.Errorf("ClassicalInfo() ok = true for a file without a work or movement")
+	}
+}
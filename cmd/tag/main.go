@@ -4,6 +4,14 @@
 
 /*
 The tag tool reads metadata from media files (as supported by the tag library).
+
+Tags can be written from a JSON object of field names and values (see the Field constants
+in the tag library), i.e.
+
+	tag -set fields.json -o copy.flac original.flac
+
+writes the fields in fields.json to a copy of original.flac, and then shows the metadata of the
+copy.  Fields which are not in fields.json are kept.  Without -o the file is changed in place.
 */
 package main
 
@@ -11,7 +19,10 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/dhowden/tag"
 	"github.com/dhowden/tag/mbz"
@@ -25,6 +36,9 @@ var usage = func() {
 var (
 	raw        = flag.Bool("raw", false, "show raw tag data")
 	extractMBZ = flag.Bool("mbz", false, "extract MusicBrainz tag data (if available)")
+	set        = flag.String("set", "", "write the fields in the JSON `file` (an object of field names and values), keeping other fields")
+	output     = flag.String("o", "", "write to a copy of the input at `path`, rather than in place (with -set)")
+	extractArt = flag.String("extract-art", "", "save the embedded pictures to the directory `dir`")
 )
 
 func main() {
@@ -36,7 +50,13 @@ func main() {
 		return
 	}
 
-	f, err := os.Open(flag.Arg(0))
+	path, err := writeFields(flag.Arg(0), *set, *output)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	f, err := os.Open(path)
 	if err != nil {
 		fmt.Printf("error loading file: %v", err)
 		return
@@ -74,6 +94,112 @@ func main() {
 
 		fmt.Printf("\nMusicBrainz Info:\n%v\n", string(b))
 	}
+
+	if *extractArt != "" {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			fmt.Printf("error seeking file: %v\n", err)
+			return
+		}
+		if err := savePictures(*extractArt, path, f); err != nil {
+			fmt.Printf("error extracting pictures: %v\n", err)
+			return
+		}
+	}
+}
+
+// savePictures saves the pictures embedded in the file at path (read from r) to dir, named
+// after the file and numbered in order.
+func savePictures(dir, path string, r io.ReadSeeker) error {
+	pics, err := tag.ExtractPictures(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for i, p := range pics {
+		name := filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i+1, p.Extension()))
+		if err := os.WriteFile(name, p.Data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Saved %v (%v)\n", name, p.Type)
+	}
+	return nil
+}
+
+// writeFields writes the fields in the JSON file at fieldsPath (the -set flag) to the file at
+// path, or to a copy at output (the -o flag) if it isn't empty, and returns the path of the file
+// which was written.  Nothing is written if fieldsPath is empty, so showing the metadata of a
+// file never changes it.
+func writeFields(path, fieldsPath, output string) (string, error) {
+	if fieldsPath == "" {
+		return path, nil
+	}
+	if output != "" {
+		if err := copyFile(output, path); err != nil {
+			return "", fmt.Errorf("error copying file: %v", err)
+		}
+		path = output
+	}
+	if err := setTags(path, fieldsPath); err != nil {
+		return "", fmt.Errorf("error writing tags: %v", err)
+	}
+	return path, nil
+}
+
+// setTags writes the fields in the JSON file at fieldsPath to the file at path.  Fields which
+// are not in the JSON file are kept (see tag.TagFile.Write).
+func setTags(path, fieldsPath string) error {
+	b, err := os.ReadFile(fieldsPath)
+	if err != nil {
+		return err
+	}
+	var data map[string]string
+	if err := json.Unmarshal(b, &data); err != nil {
+		return fmt.Errorf("error decoding %v: %v", fieldsPath, err)
+	}
+
+	t, err := tag.OpenForTagging(path)
+	if err != nil {
+		return err
+	}
+	if err := t.Write(data); err != nil {
+		t.Close()
+		return err
+	}
+	return t.Close()
+}
+
+// copyFile copies the file at src to dst, which must not be the same file (as creating dst
+// would truncate src).
+func copyFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if out, err := os.Stat(dst); err == nil {
+		st, err := in.Stat()
+		if err != nil {
+			return err
+		}
+		if os.SameFile(st, out) {
+			return fmt.Errorf("%v is the same file as %v", dst, src)
+		}
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
 func printMetadata(m tag.Metadata) {
@@ -81,6 +207,7 @@ func printMetadata(m tag.Metadata) {
 	fmt.Printf("File Type: %v\n", m.FileType())
 
 	fmt.Printf(" Title: %v\n", m.Title())
+	fmt.Printf(" Subtitle: %v\n", m.Subtitle())
 	fmt.Printf(" Album: %v\n", m.Album())
 	fmt.Printf(" Artist: %v\n", m.Artist())
 	fmt.Printf(" Composer: %v\n", m.Composer())
@@ -92,8 +219,34 @@ func printMetadata(m tag.Metadata) {
 
 	disc, discCount := m.Disc()
 	fmt.Printf(" Disc: %v of %v\n", disc, discCount)
+	fmt.Printf(" Disc Subtitle: %v\n", m.DiscSubtitle())
 
 	fmt.Printf(" Picture: %v\n", m.Picture())
+	fmt.Printf(" Pictures: %v\n", len(m.Pictures()))
 	fmt.Printf(" Lyrics: %v\n", m.Lyrics())
 	fmt.Printf(" Comment: %v\n", m.Comment())
+	fmt.Printf(" Media Type: %v\n", m.MediaType())
+	fmt.Printf(" Mood: %v\n", m.Mood())
+	fmt.Printf(" Rating: %v\n", m.Rating())
+	fmt.Printf(" Content Group: %v\n", m.ContentGroup())
+	fmt.Printf(" Work: %v\n", m.Work())
+	if c, ok := m.ClassicalInfo(); ok {
+		fmt.Printf(" Movement: %v (%v of %v)\n", c.MovementName, c.MovementNumber, c.MovementCount)
+	}
+	if bitrate, ok := m.Bitrate(); ok {
+		fmt.Printf(" Bitrate: %v kbit/s\n", bitrate/1000)
+	}
+	fmt.Printf(" Conductor: %v\n", m.Conductor())
+	fmt.Printf(" Remixer: %v\n", m.Remixer())
+	for _, c := range m.InvolvedPeople() {
+		fmt.Printf(" Credit: %v (%v)\n", c.Name, c.Role)
+	}
+	it := m.ITunesTags()
+	fmt.Printf(" Album Artist Sort: %v\n", it.AlbumArtistSort)
+	fmt.Printf(" Grouping: %v\n", it.Grouping)
+	fmt.Printf(" Compilation: %v\n", it.Compilation)
+	if p, ok := m.PodcastInfo(); ok {
+		fmt.Printf(" Podcast Feed: %v\n", p.FeedURL)
+		fmt.Printf(" Podcast GUID: %v\n", p.EpisodeGUID)
+	}
 }
@@ -0,0 +1,197 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+func TestSetTags(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.flac")
+	orig, err := os.ReadFile("../../testdata/without_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, orig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fields := filepath.Join(dir, "fields.json")
+	if err := os.WriteFile(fields, []byte(`{"title": "Imaginations from the Other Side", "ARTIST": "Blind Guardian"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Write to a copy (-o), leaving the input unchanged.
+	dst := filepath.Join(dir, "out.flac")
+	if err := copyFile(dst, src); err != nil {
+		t.Fatalf("copyFile() = %v", err)
+	}
+	if err := setTags(dst, fields); err != nil {
+		t.Fatalf("setTags() = %v", err)
+	}
+	if b, err := os.ReadFile(src); err != nil || !bytes.Equal(b, orig) {
+		t.Errorf("input file changed (%v)", err)
+	}
+
+	// -o naming the input is rejected, rather than truncating it.
+	if err := copyFile(src, src); err == nil {
+		t.Errorf("copyFile() to the input file = nil, expected error")
+	}
+	if b, err := os.ReadFile(src); err != nil || !bytes.Equal(b, orig) {
+		t.Errorf("input file changed (%v)", err)
+	}
+
+	f, err := os.Open(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := tag.ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if m.Title() != "Imaginations from the Other Side" || m.Artist() != "Blind Guardian" {
+		t.Errorf("Title(), Artist() = %q, %q, expected the fields from the JSON file", m.Title(), m.Artist())
+	}
+
+	if err := os.WriteFile(fields, []byte(`["not", "an", "object"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := setTags(dst, fields); err == nil {
+		t.Errorf("setTags() with invalid JSON = nil, expected error")
+	}
+}
+
+func TestWriteFields(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.flac")
+	orig, err := os.ReadFile("../../testdata/without_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, orig, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fields := filepath.Join(dir, "fields.json")
+	if err := os.WriteFile(fields, []byte(`{"title": "Title"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without -set nothing is written, even with -o.
+	dst := filepath.Join(dir, "out.flac")
+	path, err := writeFields(src, "", dst)
+	if err != nil || path != src {
+		t.Fatalf("writeFields() = %q, %v, expected %q", path, err, src)
+	}
+	if b, err := os.ReadFile(src); err != nil || !bytes.Equal(b, orig) {
+		t.Errorf("input file changed without -set (%v)", err)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("output file written without -set (%v)", err)
+	}
+
+	// With -set the file is changed in place.
+	path, err = writeFields(src, fields, "")
+	if err != nil || path != src {
+		t.Fatalf("writeFields() = %q, %v, expected %q", path, err, src)
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	m, err := tag.ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if m.Title() != "Title" {
+		t.Errorf("Title() = %q, expected %q", m.Title(), "Title")
+	}
+}
+
+func TestWriteFieldsKeepsFields(t *testing.T) {
+	for _, name := range []string{"sample.flac", "sample.ogg", "sample.m4a", "sample.id3v24.mp3"} {
+		dir := t.TempDir()
+		src := filepath.Join(dir, name)
+		b, err := os.ReadFile("../../testdata/with_tags/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(src, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		orig, err := tag.ReadFrom(bytes.NewReader(b))
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", name, err)
+		}
+		fields := filepath.Join(dir, "fields.json")
+		if err := os.WriteFile(fields, []byte(`{"title": "New"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := writeFields(src, fields, ""); err != nil {
+			t.Fatalf("%v: writeFields() = %v", name, err)
+		}
+		f, err := os.Open(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := tag.ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", name, err)
+		}
+		if m.Title() != "New" {
+			t.Errorf("%v: Title() = %q, expected %q", name, m.Title(), "New")
+		}
+		if m.Artist() == "" || m.Artist() != orig.Artist() {
+			t.Errorf("%v: Artist() = %q, expected %q", name, m.Artist(), orig.Artist())
+		}
+		if m.Album() == "" || m.Album() != orig.Album() {
+			t.Errorf("%v: Album() = %q, expected %q", name, m.Album(), orig.Album())
+		}
+		if m.Genre() == "" || m.Genre() != orig.Genre() {
+			t.Errorf("%v: Genre() = %q, expected %q", name, m.Genre(), orig.Genre())
+		}
+	}
+}
+
+func TestSavePictures(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "in.flac")
+	if err := copyFile(path, "../../testdata/without_tags/sample.flac"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data := []byte("\xff\xd8\xff\xe0 JFIF data")
+	if err := tag.SetPicture(f, &tag.Picture{MIMEType: "image/jpeg", Data: data}); err != nil {
+		t.Fatalf("SetPicture() = %v", err)
+	}
+
+	art := filepath.Join(dir, "art")
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := savePictures(art, path, f); err != nil {
+		t.Fatalf("savePictures() = %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(art, "in-1.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Errorf("saved picture = %x, expected %x", b, data)
+	}
+}
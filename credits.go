@@ -0,0 +1,50 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import "strings"
+
+// Credit is a person involved in the recording of a track, along with their role.
+type Credit struct {
+	Role string // i.e. "engineer", "producer" or "mix".
+	Name string
+}
+
+// readCreditsFrame reads an ID3v2 involved people list (IPLS in ID3v2.3, TIPL in ID3v2.4) which
+// is a text frame of alternating null-terminated roles and names.
+func readCreditsFrame(b []byte) ([]Credit, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	txt, err := decodeText(b[0], b[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(txt, "\x00")
+	if len(parts)%2 == 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1] // terminated list
+	}
+
+	var credits []Credit
+	for i := 0; i+1 < len(parts); i += 2 {
+		credits = append(credits, Credit{
+			Role: strings.TrimPrefix(parts[i], "\ufeff"), // strings after the first have their own BOM
+			Name: strings.TrimPrefix(parts[i+1], "\ufeff"),
+		})
+	}
+	return credits, nil
+}
+
+// vorbisCreditRoles maps the Vorbis comment (and MP4 freeform) fields used by MusicBrainz
+// Picard for the roles in an ID3v2 involved people list.
+var vorbisCreditRoles = []struct{ field, role string }{
+	{"arranger", "arranger"},
+	{"engineer", "engineer"},
+	{"producer", "producer"},
+	{"djmixer", "DJ-mix"},
+	{"mixer", "mix"},
+}
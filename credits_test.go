@@ -0,0 +1,83 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// testUTF16WithBOM returns s encoded as little-endian UTF-16 with a byte order mark.
+func testUTF16WithBOM(s string) []byte {
+	b := []byte{0xFF, 0xFE}
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return b
+}
+
+func TestInvolvedPeopleID3v23(t *testing.T) {
+	b := testID3v2Tag(
+		id3v2RawFrame{Name: "TPE3", Data: []byte("\x00Herbert von Karajan")},
+		id3v2RawFrame{Name: "TPE4", Data: []byte("\x00A Remixer")},
+		id3v2RawFrame{Name: "IPLS", Data: []byte("\x00engineer\x00Gunter Hermanns\x00producer\x00Michel Glotz\x00")},
+	)
+
+	m, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "Herbert von Karajan", m.Conductor())
+	testValue(t, "A Remixer", m.Remixer())
+
+	want := []Credit{
+		{Role: "engineer", Name: "Gunter Hermanns"},
+		{Role: "producer", Name: "Michel Glotz"},
+	}
+	if got := m.InvolvedPeople(); !reflect.DeepEqual(got, want) {
+		t.Errorf("InvolvedPeople() = %v, expected %v", got, want)
+	}
+}
+
+func TestInvolvedPeopleID3v24(t *testing.T) {
+	var tipl []byte
+	tipl = append(tipl, encodingUTF16WithBOM)
+	tipl = append(tipl, testUTF16WithBOM("engineer")...)
+	tipl = append(tipl, 0, 0)
+	tipl = append(tipl, testUTF16WithBOM("Gunter Hermanns")...)
+
+	rt := &id3v2RawTag{Version: 4, Frames: []id3v2RawFrame{
+		{Name: "TPE3", Data: []byte("\x03Herbert von Karajan")},
+		{Name: "TIPL", Data: tipl},
+	}}
+
+	m, err := ReadFrom(bytes.NewReader(rt.bytes(0)))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "Herbert von Karajan", m.Conductor())
+
+	want := []Credit{{Role: "engineer", Name: "Gunter Hermanns"}}
+	if got := m.InvolvedPeople(); !reflect.DeepEqual(got, want) {
+		t.Errorf("InvolvedPeople() = %v, expected %v", got, want)
+	}
+}
+
+func TestInvolvedPeopleVorbis(t *testing.T) {
+	m := readVorbisFields(t, map[string]string{
+		"CONDUCTOR": "Herbert von Karajan",
+		"REMIXER":   "A Remixer",
+		"ENGINEER":  "Gunter Hermanns",
+	})
+	testValue(t, "Herbert von Karajan", m.Conductor())
+	testValue(t, "A Remixer", m.Remixer())
+
+	want := []Credit{{Role: "engineer", Name: "Gunter Hermanns"}}
+	if got := m.InvolvedPeople(); !reflect.DeepEqual(got, want) {
+		t.Errorf("InvolvedPeople() = %v, expected %v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CueSheet is the content of a FLAC CUESHEET metadata block, which describes the track
+// layout of the audio (typically as ripped from a CD).
+// See https://xiph.org/flac/format.html#metadata_block_cuesheet.
+type CueSheet struct {
+	MediaCatalogNumber string // Media catalog number, i.e. the UPC/EAN of a CD.
+	LeadInSamples      uint64 // Number of lead-in samples (only meaningful for CDs).
+	CompactDisc        bool   // The cue sheet corresponds to a CD.
+	Tracks             []CueSheetTrack
+}
+
+// CueSheetTrack is a track in a CueSheet.  The last track is the lead-out track (number 170
+// for CDs, 255 otherwise).
+type CueSheetTrack struct {
+	Offset      uint64 // Offset of the track in samples, relative to the start of the audio.
+	Number      int
+	ISRC        string // International Standard Recording Code, if known.
+	NonAudio    bool   // The track contains data rather than audio.
+	PreEmphasis bool
+	Indices     []CueSheetIndex
+}
+
+// CueSheetIndex is an index point in a CueSheetTrack.
+type CueSheetIndex struct {
+	Offset uint64 // Offset of the index point in samples, relative to the track offset.
+	Number int
+}
+
+// FLACCueSheet returns the CueSheet read from the CUESHEET block of the FLAC data in r,
+// or an error if there is no CUESHEET block.
+func FLACCueSheet(r io.ReadSeeker) (*CueSheet, error) {
+	b, err := readFLACBlock(r, cueSheetBlock)
+	if err != nil {
+		return nil, err
+	}
+	return readCueSheet(bytes.NewReader(b))
+}
+
+// readCueSheet reads the content of a CUESHEET block from r.
+func readCueSheet(r io.Reader) (*CueSheet, error) {
+	// Media catalog number (128), lead-in samples (8), CD flag and reserved (259),
+	// number of tracks (1).
+	b, err := readBytes(r, 396)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CUESHEET block: %v", err)
+	}
+
+	c := &CueSheet{
+		MediaCatalogNumber: string(bytes.TrimRight(b[0:128], "\x00")),
+		LeadInSamples:      binary.BigEndian.Uint64(b[128:136]),
+		CompactDisc:        getBit(b[136], 7),
+	}
+
+	n := int(b[395])
+	if n == 0 {
+		return nil, errors.New("invalid CUESHEET block: no tracks")
+	}
+	c.Tracks = make([]CueSheetTrack, n)
+	for i := range c.Tracks {
+		// Offset (8), number (1), ISRC (12), type, pre-emphasis and reserved (14),
+		// number of index points (1).
+		b, err := readBytes(r, 36)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CUESHEET track: %v", err)
+		}
+
+		t := &c.Tracks[i]
+		t.Offset = binary.BigEndian.Uint64(b[0:8])
+		t.Number = int(b[8])
+		t.ISRC = string(bytes.TrimRight(b[9:21], "\x00"))
+		t.NonAudio = getBit(b[21], 7)
+		t.PreEmphasis = getBit(b[21], 6)
+
+		t.Indices = make([]CueSheetIndex, b[35])
+		for j := range t.Indices {
+			// Offset (8), number (1), reserved (3).
+			b, err := readBytes(r, 12)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CUESHEET track index: %v", err)
+			}
+			t.Indices[j] = CueSheetIndex{
+				Offset: binary.BigEndian.Uint64(b[0:8]),
+				Number: int(b[8]),
+			}
+		}
+	}
+	return c, nil
+}
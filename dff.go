@@ -0,0 +1,233 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// dffChunk is the position of a chunk in a DSDIFF file.
+type dffChunk struct {
+	ID     string
+	Offset int64 // Offset of the chunk data.
+	Size   int64 // Size of the chunk data, excluding the pad byte.
+}
+
+// readDFFChunks reads the positions of the chunks in r between offset and end.  DSDIFF chunks
+// have a 4 byte ID and a 64-bit big-endian size, and are padded to an even length.
+func readDFFChunks(r io.ReadSeeker, offset, end int64) ([]dffChunk, error) {
+	var chunks []dffChunk
+	for offset+12 <= end {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		b, err := readBytes(r, 12)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break // truncated file
+			}
+			return nil, err
+		}
+
+		size := binary.BigEndian.Uint64(b[4:12])
+		if size > uint64(end-offset-12) {
+			if size > 1<<62 {
+				return nil, fmt.Errorf("invalid DSDIFF %q chunk size: %d", b[0:4], size)
+			}
+			size = uint64(end - offset - 12) // truncated chunk
+		}
+		c := dffChunk{
+			ID:     string(b[0:4]),
+			Offset: offset + 12,
+			Size:   int64(size),
+		}
+		chunks = append(chunks, c)
+		offset = c.Offset + c.Size + c.Size%2
+	}
+	return chunks, nil
+}
+
+// ReadDFFTags reads DSDIFF metadata from the io.ReadSeeker, returning the resulting
+// metadata in a Metadata implementation, or non-nil error if there was a problem.
+// Metadata is read from the "ID3 " chunk (an ID3v2 tag, which is not part of the DSDIFF
+// specification but is widely used), with the DIIN (edited master information) artist and
+// title and the first COMT comment used for any fields which are not in the ID3v2 tag.  If
+// there is no "ID3 " chunk then Format returns UnknownFormat.
+// See https://dsd-guide.com/sites/default/files/white-papers/DSDIFF_1.5_Spec.pdf
+func ReadDFFTags(r io.ReadSeeker) (Metadata, error) {
+	return readDFFTags(r, nil, ReadOptions{})
+}
+
+func readDFFTags(r io.ReadSeeker, w *warnings, opts ReadOptions) (Metadata, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	b, err := readBytes(r, 16)
+	if err != nil {
+		return nil, err
+	}
+	if string(b[0:4]) != "FRM8" || string(b[12:16]) != "DSD " {
+		return nil, errors.New("expected 'FRM8' containing 'DSD '")
+	}
+	end := 12 + int64(binary.BigEndian.Uint64(b[4:12]))
+	if end < 16 {
+		return nil, errors.New("invalid DSDIFF FRM8 chunk size")
+	}
+
+	chunks, err := readDFFChunks(r, 16, end)
+	if err != nil {
+		return nil, err
+	}
+
+	m := metadataDFF{
+		Metadata: metadataID3v2{header: &id3v2Header{}, frames: make(map[string]interface{})},
+		text:     make(map[string]string),
+	}
+	for _, c := range chunks {
+		switch c.ID {
+		case "ID3 ":
+			id3, err := readID3v2Tags(io.NewSectionReader(readerAt{r}, c.Offset, c.Size), w, opts)
+			if err != nil {
+				return nil, fmt.Errorf("error reading DSDIFF ID3 chunk: %v", err)
+			}
+			m.Metadata = id3
+
+		case "DIIN":
+			sub, err := readDFFChunks(r, c.Offset, c.Offset+c.Size)
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range sub {
+				var k string
+				switch s.ID {
+				case "DIAR":
+					k = "artist"
+				case "DITI":
+					k = "title"
+				default:
+					continue
+				}
+				if _, err := r.Seek(s.Offset, io.SeekStart); err != nil {
+					return nil, err
+				}
+				b, err := readBytes(r, uint(s.Size))
+				if err != nil {
+					return nil, err
+				}
+				m.text[k] = readDFFText(b)
+			}
+
+		case "PROP":
+			// Sound property chunks: FS (sample rate), CHNL (channels) and CMPR
+			// (compression type).
+			if c.Size < 4 {
+				break
+			}
+			sub, err := readDFFChunks(r, c.Offset+4, c.Offset+c.Size)
+			if err != nil {
+				return nil, err
+			}
+			var rate, channels int
+			compressed := false
+			for _, s := range sub {
+				if _, err := r.Seek(s.Offset, io.SeekStart); err != nil {
+					return nil, err
+				}
+				b, err := readBytes(r, uint(minInt(int(s.Size), 4)))
+				if err != nil {
+					return nil, err
+				}
+				switch {
+				case s.ID == "FS  " && len(b) == 4:
+					rate = getInt(b)
+				case s.ID == "CHNL" && len(b) >= 2:
+					channels = getInt(b[0:2])
+				case s.ID == "CMPR" && len(b) == 4:
+					compressed = string(b) != "DSD "
+				}
+			}
+			if !compressed {
+				m.bitrate = rate * channels // DSD audio has 1 bit per sample
+			}
+
+		case "COMT":
+			if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			b, err := readBytes(r, uint(c.Size))
+			if err != nil {
+				return nil, err
+			}
+			// Number of comments (2 bytes), then for each comment: timestamp (6 bytes),
+			// type and reference (2 bytes each) and the text prefixed by a 4 byte count.
+			if len(b) >= 2+10 && binary.BigEndian.Uint16(b[0:2]) > 0 {
+				m.text["comment"] = readDFFText(b[2+10:])
+			}
+		}
+	}
+	return m, nil
+}
+
+// readDFFText returns the text in b which is prefixed by its length (a 4 byte big-endian
+// integer).
+func readDFFText(b []byte) string {
+	if len(b) < 4 {
+		return ""
+	}
+	l := getInt(b[:4])
+	b = b[4:]
+	if l > len(b) || l < 0 {
+		l = len(b)
+	}
+	return strings.TrimRight(string(b[:l]), "\x00")
+}
+
+// metadataDFF is the implementation of Metadata for DSDIFF files.  Accessors are provided
+// by the ID3v2 tag (which is empty if there is no "ID3 " chunk) unless overridden below.
+type metadataDFF struct {
+	Metadata
+	text    map[string]string // DIIN and COMT text
+	bitrate int               // from the PROP chunk, zero if unavailable or compressed (DST)
+}
+
+func (m metadataDFF) FileType() FileType { return DFF }
+
+func (m metadataDFF) Bitrate() (int, bool) { return m.bitrate, m.bitrate > 0 }
+
+func (m metadataDFF) Raw() map[string]interface{} {
+	raw := make(map[string]interface{})
+	for k, v := range m.Metadata.Raw() {
+		raw[k] = v
+	}
+	for k, v := range m.text {
+		raw[k] = v
+	}
+	return raw
+}
+
+func (m metadataDFF) Title() string {
+	if t := m.Metadata.Title(); t != "" {
+		return t
+	}
+	return m.text["title"]
+}
+
+func (m metadataDFF) Artist() string {
+	if a := m.Metadata.Artist(); a != "" {
+		return a
+	}
+	return m.text["artist"]
+}
+
+func (m metadataDFF) Comment() string {
+	if c := m.Metadata.Comment(); c != "" {
+		return c
+	}
+	return m.text["comment"]
+}
@@ -0,0 +1,85 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// testDFFChunk returns a DSDIFF chunk with the given ID and data (padded to an even length).
+func testDFFChunk(id string, data []byte) []byte {
+	b := append([]byte(id), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(b[4:12], uint64(len(data)))
+	b = append(b, data...)
+	if len(data)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// testDFFText returns s prefixed by its length as a 4 byte big-endian integer.
+func testDFFText(s string) []byte {
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(s)))
+	return append(b, s...)
+}
+
+// testDFF returns DSDIFF data with an FVER, PROP and DSD chunk followed by the given chunks.
+func testDFF(chunks ...[]byte) []byte {
+	b := []byte("FRM8\x00\x00\x00\x00\x00\x00\x00\x00DSD ")
+	b = append(b, testDFFChunk("FVER", []byte{1, 5, 0, 0})...)
+	prop := append([]byte("SND "), testDFFChunk("FS  ", []byte{0x00, 0x2B, 0x11, 0x00})...) // 2822400 Hz
+	prop = append(prop, testDFFChunk("CHNL", []byte{0, 1, 'S', 'L', 'F', 'T'})...)
+	b = append(b, testDFFChunk("PROP", prop)...)
+	b = append(b, testDFFChunk("DSD ", []byte{1, 2, 3, 4, 5, 6, 7, 8})...)
+	for _, c := range chunks {
+		b = append(b, c...)
+	}
+	binary.BigEndian.PutUint64(b[4:12], uint64(len(b)-12))
+	return b
+}
+
+func TestReadDFFTextChunks(t *testing.T) {
+	diin := append(testDFFChunk("DIAR", testDFFText("Test Artist")), testDFFChunk("DITI", testDFFText("Test Title"))...)
+	comt := []byte{0, 1, 0x07, 0xE5, 1, 2, 3, 4, 0, 0, 0, 0}
+	comt = append(comt, testDFFText("Test Comment")...)
+	b := testDFF(
+		testDFFChunk("DIIN", diin),
+		testDFFChunk("COMT", comt),
+	)
+
+	m, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, DFF, m.FileType())
+	testValue(t, UnknownFormat, m.Format())
+	testValue(t, "Test Title", m.Title())
+	testValue(t, "Test Artist", m.Artist())
+	testValue(t, "Test Comment", m.Comment())
+	testValue(t, "Test Title", m.Raw()["title"])
+}
+
+func TestReadDFFID3Chunk(t *testing.T) {
+	tag := testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Data: []byte("\x00ID3 Title")},
+		id3v2RawFrame{Name: "TALB", Data: []byte("\x00Test Album")},
+	)
+	b := testDFF(
+		testDFFChunk("DIIN", append(testDFFChunk("DIAR", testDFFText("Chunk Artist")), testDFFChunk("DITI", testDFFText("Chunk Title"))...)),
+		testDFFChunk("ID3 ", tag),
+	)
+
+	m, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, DFF, m.FileType())
+	testValue(t, ID3v2_3, m.Format())
+	testValue(t, "ID3 Title", m.Title())     // ID3 tag takes precedence
+	testValue(t, "Chunk Artist", m.Artist()) // falls back to DIIN chunk
+	testValue(t, "Test Album", m.Album())
+}
@@ -5,6 +5,7 @@
 package tag
 
 import (
+	"encoding/binary"
 	"errors"
 	"io"
 )
@@ -13,6 +14,10 @@ import (
 // metadata in a Metadata implementation, or non-nil error if there was a problem.
 // samples: http://www.2l.no/hires/index.html
 func ReadDSFTags(r io.ReadSeeker) (Metadata, error) {
+	return readDSFTags(r, nil, ReadOptions{})
+}
+
+func readDSFTags(r io.ReadSeeker, w *warnings, opts ReadOptions) (Metadata, error) {
 	dsd, err := readString(r, 4)
 	if err != nil {
 		return nil, err
@@ -31,21 +36,33 @@ func ReadDSFTags(r io.ReadSeeker) (Metadata, error) {
 		return nil, err
 	}
 
+	// The fmt chunk follows the DSD chunk: ID (4 bytes), size (8), format version (4),
+	// format ID (4), channel type (4), channel count (4), sample rate (4) and bits per
+	// sample (4), all little-endian.
+	var bitrate int
+	if b, err := readBytes(r, 36); err == nil && string(b[0:4]) == "fmt " {
+		channels := binary.LittleEndian.Uint32(b[24:28])
+		rate := binary.LittleEndian.Uint32(b[28:32])
+		bits := binary.LittleEndian.Uint32(b[32:36])
+		bitrate = int(channels * rate * bits)
+	}
+
 	_, err = r.Seek(int64(id3Pointer), io.SeekStart)
 	if err != nil {
 		return nil, err
 	}
 
-	id3, err := ReadID3v2Tags(r)
+	id3, err := readID3v2Tags(r, w, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return metadataDSF{id3}, nil
+	return metadataDSF{id3: id3, bitrate: bitrate}, nil
 }
 
 type metadataDSF struct {
-	id3 Metadata
+	id3     Metadata
+	bitrate int // from the fmt chunk, zero if unavailable
 }
 
 func (m metadataDSF) Format() Format {
@@ -96,6 +113,10 @@ func (m metadataDSF) Picture() *Picture {
 	return m.id3.Picture()
 }
 
+func (m metadataDSF) Pictures() []*Picture {
+	return m.id3.Pictures()
+}
+
 func (m metadataDSF) Lyrics() string {
 	return m.id3.Lyrics()
 }
@@ -104,6 +125,78 @@ func (m metadataDSF) Comment() string {
 	return m.id3.Comment()
 }
 
+func (m metadataDSF) MediaType() string {
+	return m.id3.MediaType()
+}
+
+func (m metadataDSF) Mood() string {
+	return m.id3.Mood()
+}
+
+func (m metadataDSF) Rating() int {
+	return m.id3.Rating()
+}
+
+func (m metadataDSF) ContentGroup() string {
+	return m.id3.ContentGroup()
+}
+
+func (m metadataDSF) Work() string {
+	return m.id3.Work()
+}
+
+func (m metadataDSF) ClassicalInfo() (ClassicalInfo, bool) {
+	return m.id3.ClassicalInfo()
+}
+
+func (m metadataDSF) Bitrate() (int, bool) {
+	return m.bitrate, m.bitrate > 0
+}
+
+func (m metadataDSF) Gapless() (GaplessInfo, bool) {
+	return m.id3.Gapless()
+}
+
+func (m metadataDSF) Subtitle() string {
+	return m.id3.Subtitle()
+}
+
+func (m metadataDSF) DiscSubtitle() string {
+	return m.id3.DiscSubtitle()
+}
+
+func (m metadataDSF) Conductor() string {
+	return m.id3.Conductor()
+}
+
+func (m metadataDSF) Remixer() string {
+	return m.id3.Remixer()
+}
+
+func (m metadataDSF) InvolvedPeople() []Credit {
+	return m.id3.InvolvedPeople()
+}
+
+func (m metadataDSF) Copyright() string {
+	return m.id3.Copyright()
+}
+
+func (m metadataDSF) Publisher() string {
+	return m.id3.Publisher()
+}
+
+func (m metadataDSF) Owner() string {
+	return m.id3.Owner()
+}
+
+func (m metadataDSF) PodcastInfo() (PodcastInfo, bool) {
+	return m.id3.PodcastInfo()
+}
+
+func (m metadataDSF) ITunesTags() ITunesInfo {
+	return m.id3.ITunesTags()
+}
+
 func (m metadataDSF) Raw() map[string]interface{} {
 	return m.id3.Raw()
 }
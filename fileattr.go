@@ -0,0 +1,30 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"os"
+	"time"
+)
+
+// WithPreservedAttributes calls fn, which modifies the file at path (i.e. by calling one of the
+// Write functions), and then restores the permissions and modification time which the file had
+// beforehand (like touch -r).  The attributes are restored even if fn returns an error, in which
+// case the error from fn is returned.
+func WithPreservedAttributes(path string, fn func() error) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	if cerr := os.Chmod(path, fi.Mode().Perm()); err == nil {
+		err = cerr
+	}
+	if cerr := os.Chtimes(path, time.Now(), fi.ModTime()); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithPreservedAttributes(t *testing.T) {
+	f := tempCopy(t, "without_tags/sample.flac")
+	path := f.Name()
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	err := WithPreservedAttributes(path, func() error {
+		if err := WriteFLACTags(f, map[string]string{"TITLE": "Title"}); err != nil {
+			return err
+		}
+		return os.Chmod(path, 0o600) // changed by the write
+	})
+	if err != nil {
+		t.Fatalf("WithPreservedAttributes() = %v", err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Errorf("ModTime() = %v, expected %v", fi.ModTime(), mtime)
+	}
+	testValue(t, os.FileMode(0o640), fi.Mode().Perm())
+	testValue(t, "Title", testReadFLAC(t, f).Title())
+}
+
+func TestWithPreservedAttributesError(t *testing.T) {
+	f := tempCopy(t, "without_tags/sample.flac")
+	path := f.Name()
+
+	mtime := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	want := errors.New("write failed")
+	err := WithPreservedAttributes(path, func() error {
+		if _, err := f.WriteAt([]byte("x"), 0); err != nil {
+			return err
+		}
+		return want
+	})
+	if err != want {
+		t.Errorf("WithPreservedAttributes() = %v, expected %v", err, want)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Errorf("ModTime() = %v, expected %v", fi.ModTime(), mtime)
+	}
+}
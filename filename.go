@@ -0,0 +1,34 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// filenameTrackRe matches a leading track number (optionally preceded by a disc number)
+// in a filename, i.e. "01 - ", "3. " or "1-02 ".
+var filenameTrackRe = regexp.MustCompile(`^[0-9]{1,3}(?:[-.][0-9]{1,3})?[ ._-]+`)
+
+// TitleOrFilename returns the title of m, or if m is nil or has no title, a title derived
+// from the filename in path (with the extension and any leading track number removed, and
+// underscores replaced by spaces).
+func TitleOrFilename(m Metadata, path string) string {
+	if m != nil {
+		if t := strings.TrimSpace(m.Title()); t != "" {
+			return t
+		}
+	}
+
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.ReplaceAll(name, "_", " ")
+	if t := filenameTrackRe.ReplaceAllString(name, ""); strings.TrimSpace(t) != "" {
+		name = t
+	}
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,52 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"os"
+	"testing"
+)
+
+func TestTitleOrFilename(t *testing.T) {
+	tests := []struct {
+		path, title string
+	}{
+		{"music/01 - Song Title.mp3", "Song Title"},
+		{"music/01. Song Title.flac", "Song Title"},
+		{"music/1-02 Song Title.m4a", "Song Title"},
+		{"music/07_song_title.ogg", "song title"},
+		{"music/Song Title.mp3", "Song Title"},
+		{"music/2001 A Space Odyssey.mp3", "2001 A Space Odyssey"},
+		{"music/12.mp3", "12"},
+		{"Song.Title.v2.mp3", "Song.Title.v2"},
+	}
+
+	for _, tt := range tests {
+		if got := TitleOrFilename(nil, tt.path); got != tt.title {
+			t.Errorf("TitleOrFilename(nil, %q) = %q, expected %q", tt.path, got, tt.title)
+		}
+	}
+}
+
+func TestTitleOrFilenameMetadata(t *testing.T) {
+	for path, title := range map[string]string{
+		"with_tags/sample.flac":    "Test Title",
+		"without_tags/sample.flac": "sample",
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+		if got := TitleOrFilename(m, path); got != title {
+			t.Errorf("TitleOrFilename(%v) = %q, expected %q", path, got, title)
+		}
+	}
+}
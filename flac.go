@@ -5,7 +5,9 @@
 package tag
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
 )
 
@@ -14,69 +16,185 @@ type blockType byte
 
 // FLAC block types.
 const (
-	// Stream Info Block           0
-	// Padding Block               1
-	// Application Block           2
-	// Seektable Block             3
-	// Cue Sheet Block             5
+	streamInfoBlock    blockType = 0
+	paddingBlock       blockType = 1
+	applicationBlock   blockType = 2
+	seekTableBlock     blockType = 3
 	vorbisCommentBlock blockType = 4
+	cueSheetBlock      blockType = 5
 	pictureBlock       blockType = 6
 )
 
+var blockTypeNames = map[blockType]string{
+	streamInfoBlock:    "STREAMINFO",
+	paddingBlock:       "PADDING",
+	applicationBlock:   "APPLICATION",
+	seekTableBlock:     "SEEKTABLE",
+	vorbisCommentBlock: "VORBIS_COMMENT",
+	cueSheetBlock:      "CUESHEET",
+	pictureBlock:       "PICTURE",
+}
+
+// String implements fmt.Stringer, returning the block type name used in the FLAC specification.
+func (t blockType) String() string {
+	if n, ok := blockTypeNames[t]; ok {
+		return n
+	}
+	return fmt.Sprintf("UNKNOWN(%d)", byte(t))
+}
+
+// flacMaxJunk is the number of bytes searched for the "fLaC" marker when the FLAC data does
+// not start with it.
+const flacMaxJunk = 64 << 10
+
 // ReadFLACTags reads FLAC metadata from the io.ReadSeeker, returning the resulting
 // metadata in a Metadata implementation, or non-nil error if there was a problem.
+// Files which have junk before the "fLaC" marker (i.e. HTTP headers or a byte order mark
+// saved with a download) are read if the marker is in the first 64KB.
 func ReadFLACTags(r io.ReadSeeker) (Metadata, error) {
+	return readFLACTags(r, nil, ReadOptions{})
+}
+
+// readFLACTags implements ReadFLACTags, adding any non-fatal problems to w.
+func readFLACTags(r io.ReadSeeker, w *warnings, opts ReadOptions) (Metadata, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
 	flac, err := readString(r, 4)
 	if err != nil {
 		return nil, err
 	}
 	if flac != "fLaC" {
-		return nil, errors.New("expected 'fLaC'")
+		n, err := findFLACMarker(r, start)
+		if err != nil {
+			return nil, err
+		}
+		w.add(VORBIS, "skipped %d bytes before 'fLaC'", n)
 	}
 
 	m := &metadataFLAC{
-		newMetadataVorbis(),
+		metadataVorbis: newMetadataVorbis(),
 	}
 
+	seen := make(map[blockType]bool)
 	for {
-		last, err := m.readFLACMetadataBlock(r)
+		t, last, err := m.readFLACMetadataBlock(r, w, opts)
 		if err != nil {
 			return nil, err
 		}
 
+		// There must be at most one of each of these blocks, see https://xiph.org/flac/format.html.
+		switch t {
+		case streamInfoBlock, seekTableBlock, vorbisCommentBlock:
+			if seen[t] {
+				w.add(VORBIS, "duplicate %v metadata block", t)
+			}
+			seen[t] = true
+		}
+
 		if last {
 			break
 		}
 	}
+
+	// The size of the audio data is used for the bitrate, the position of r is restored.
+	if pos, err := r.Seek(0, io.SeekCurrent); err == nil {
+		if end, err := r.Seek(0, io.SeekEnd); err == nil {
+			m.audioSize = end - pos
+		}
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			return nil, err
+		}
+	}
 	return m, nil
 }
 
+// findFLACMarker searches the first flacMaxJunk bytes of r from start for the "fLaC" marker,
+// and returns the number of bytes before it with r positioned after the marker.
+func findFLACMarker(r io.ReadSeeker, start int64) (int64, error) {
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+	b := make([]byte, flacMaxJunk+4)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, err
+	}
+	i := bytes.Index(b[:n], []byte("fLaC"))
+	if i < 0 {
+		return 0, errors.New("expected 'fLaC'")
+	}
+	if _, err := r.Seek(start+int64(i)+4, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return int64(i), nil
+}
+
 type metadataFLAC struct {
 	*metadataVorbis
+	streamInfo *flacStreamInfo // nil if the STREAMINFO block is invalid
+	audioSize  int64           // bytes of audio data following the metadata blocks
 }
 
-func (m *metadataFLAC) readFLACMetadataBlock(r io.ReadSeeker) (last bool, err error) {
-	blockHeader, err := readBytes(r, 1)
+// readFLACBlockHeader reads a FLAC metadata block header from r, returning the type of
+// the block, whether it is the last metadata block and the length of the block data.
+func readFLACBlockHeader(r io.Reader) (t blockType, last bool, blockLen uint, err error) {
+	b, err := readBytes(r, 4)
 	if err != nil {
 		return
 	}
 
-	if getBit(blockHeader[0], 7) {
-		blockHeader[0] ^= (1 << 7)
-		last = true
-	}
+	last = getBit(b[0], 7)
+	t = blockType(b[0] &^ (1 << 7))
+	blockLen = uint(getInt(b[1:4]))
+	return
+}
 
-	blockLen, err := readInt(r, 3)
+func (m *metadataFLAC) readFLACMetadataBlock(r io.ReadSeeker, w *warnings, opts ReadOptions) (t blockType, last bool, err error) {
+	t, last, blockLen, err := readFLACBlockHeader(r)
 	if err != nil {
 		return
 	}
 
-	switch blockType(blockHeader[0]) {
+	switch t {
+	case streamInfoBlock:
+		var b []byte
+		if b, err = readBytes(r, blockLen); err != nil {
+			return
+		}
+		si, serr := readFLACStreamInfo(b)
+		if serr != nil {
+			w.add(VORBIS, "%v", serr)
+			break
+		}
+		if m.streamInfo == nil {
+			m.streamInfo = si
+		}
+
 	case vorbisCommentBlock:
-		err = m.readVorbisComment(r)
+		if _, ok := m.c["vendor"]; !ok {
+			err = m.readVorbisComment(r)
+			break
+		}
+
+		// Duplicate block (see readFLACTags), fields from the first block take precedence.
+		d := newMetadataVorbis()
+		if err = d.readVorbisComment(r); err != nil {
+			return
+		}
+		m.merge(d)
 
 	case pictureBlock:
-		err = m.readPictureBlock(r)
+		var start int64
+		if start, err = r.Seek(0, io.SeekCurrent); err != nil {
+			return
+		}
+		err = m.readPictureBlock(r, opts.LazyPictures)
+		if err == errPictureTooLarge {
+			w.add(VORBIS, "skipped PICTURE block: %v", err)
+			_, err = r.Seek(start+int64(blockLen), io.SeekStart)
+		}
 
 	default:
 		_, err = r.Seek(int64(blockLen), io.SeekCurrent)
@@ -87,3 +205,72 @@ func (m *metadataFLAC) readFLACMetadataBlock(r io.ReadSeeker) (last bool, err er
 func (m *metadataFLAC) FileType() FileType {
 	return FLAC
 }
+
+// FLACRawComment returns the raw content of the VORBIS_COMMENT block (excluding the block
+// header) of the FLAC data in r.  See PrepareVorbisComment for the inverse.
+func FLACRawComment(r io.ReadSeeker) ([]byte, error) {
+	return readFLACBlock(r, vorbisCommentBlock)
+}
+
+// readFLACBlock returns the content of the first metadata block of type t in the FLAC data
+// in r, or an error if there is no such block.
+func readFLACBlock(r io.ReadSeeker, t blockType) ([]byte, error) {
+	flac, err := readString(r, 4)
+	if err != nil {
+		return nil, err
+	}
+	if flac != "fLaC" {
+		return nil, errors.New("expected 'fLaC'")
+	}
+
+	for {
+		bt, last, blockLen, err := readFLACBlockHeader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if bt == t {
+			return readBytes(r, blockLen)
+		}
+
+		if last {
+			return nil, fmt.Errorf("no %v block found", t)
+		}
+
+		if _, err := r.Seek(int64(blockLen), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// FLACPadding returns the total number of bytes of PADDING block data in the FLAC data in r,
+// which is the space available for metadata to grow without moving the audio data.
+func FLACPadding(r io.ReadSeeker) (int, error) {
+	flac, err := readString(r, 4)
+	if err != nil {
+		return 0, err
+	}
+	if flac != "fLaC" {
+		return 0, errors.New("expected 'fLaC'")
+	}
+
+	n := 0
+	for {
+		t, last, blockLen, err := readFLACBlockHeader(r)
+		if err != nil {
+			return 0, err
+		}
+
+		if t == paddingBlock {
+			n += int(blockLen)
+		}
+
+		if last {
+			return n, nil
+		}
+
+		if _, err := r.Seek(int64(blockLen), io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
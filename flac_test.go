@@ -0,0 +1,238 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// testFLACBlock returns a FLAC metadata block with the given type and data.
+func testFLACBlock(t blockType, last bool, data []byte) []byte {
+	h := byte(t)
+	if last {
+		h |= 1 << 7
+	}
+	n := len(data)
+	return append([]byte{h, byte(n >> 16), byte(n >> 8), byte(n)}, data...)
+}
+
+// testFLACAudioSize is the number of audio frame bytes added by testFLAC.
+const testFLACAudioSize = 5
+
+// testFLAC returns FLAC data with the given metadata blocks (following an empty STREAMINFO
+// block) and some audio frame bytes.
+func testFLAC(blocks ...[]byte) []byte {
+	b := []byte("fLaC")
+	b = append(b, testFLACBlock(0, len(blocks) == 0, make([]byte, 34))...)
+	for _, x := range blocks {
+		b = append(b, x...)
+	}
+	return append(b, 0xFF, 0xF8, 0x01, 0x02, 0x03)
+}
+
+func TestFLACRawComment(t *testing.T) {
+	comment := []byte{
+		4, 0, 0, 0, 't', 'e', 's', 't', // vendor
+		1, 0, 0, 0, // comment count
+		7, 0, 0, 0, 'T', 'I', 'T', 'L', 'E', '=', 'x',
+	}
+	b := testFLAC(
+		testFLACBlock(vorbisCommentBlock, false, comment),
+		testFLACBlock(1, true, make([]byte, 16)),
+	)
+
+	got, err := FLACRawComment(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("FLACRawComment() = %v", err)
+	}
+	if !bytes.Equal(got, comment) {
+		t.Errorf("FLACRawComment() = %x, expected %x", got, comment)
+	}
+
+	prepared, err := PrepareVorbisComment(map[string]string{"vendor": "test", "title": "x"})
+	if err != nil {
+		t.Fatalf("PrepareVorbisComment() = %v", err)
+	}
+	if !bytes.Equal(prepared, comment) {
+		t.Errorf("PrepareVorbisComment() = %x, expected %x", prepared, comment)
+	}
+}
+
+func TestFLACRawCommentSample(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	b, err := FLACRawComment(f)
+	if err != nil {
+		t.Fatalf("FLACRawComment() = %v", err)
+	}
+	testValue(t, 246, len(b))
+}
+
+func TestFLACRawCommentMissing(t *testing.T) {
+	if _, err := FLACRawComment(bytes.NewReader(testFLAC())); err == nil {
+		t.Errorf("expected error for FLAC without VORBIS_COMMENT block")
+	}
+}
+
+// testFLACPictureData returns the content of a FLAC PICTURE block (front cover).
+func testFLACPictureData(mime string, data []byte) []byte {
+	var b []byte
+	u32 := func(n int) { b = append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n)) }
+	u32(3)
+	u32(len(mime))
+	b = append(b, mime...)
+	u32(0) // description
+	u32(0) // width
+	u32(0) // height
+	u32(0) // colour depth
+	u32(0) // colours used
+	u32(len(data))
+	return append(b, data...)
+}
+
+// testCueSheetData returns the content of a CUESHEET block for a CD with the given catalog
+// number and track offsets (in samples), followed by the lead-out track.
+func testCueSheetData(catalog string, offsets ...uint64) []byte {
+	u64 := func(b []byte, n uint64) []byte {
+		return append(b, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+
+	b := make([]byte, 128)
+	copy(b, catalog)
+	b = u64(b, 88200)
+	b = append(b, 1<<7) // CD
+	b = append(b, make([]byte, 258)...)
+	b = append(b, byte(len(offsets)+1))
+
+	for i, off := range offsets {
+		b = u64(b, off)
+		b = append(b, byte(i+1))
+		isrc := make([]byte, 12)
+		copy(isrc, fmt.Sprintf("GBAYE000%04d", i+1))
+		b = append(b, isrc...)
+		b = append(b, make([]byte, 14)...)
+		b = append(b, 2) // index points
+		b = u64(b, 0)
+		b = append(b, 0, 0, 0, 0)
+		b = u64(b, 588)
+		b = append(b, 1, 0, 0, 0)
+	}
+
+	// Lead-out track.
+	b = u64(b, offsets[len(offsets)-1]+44100)
+	b = append(b, 170)
+	b = append(b, make([]byte, 12+14)...)
+	return append(b, 0)
+}
+
+func TestFLACCueSheet(t *testing.T) {
+	b := testFLAC(
+		testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, nil)),
+		testFLACBlock(cueSheetBlock, true, testCueSheetData("0724384260925", 0, 44100, 88200)),
+	)
+
+	c, err := FLACCueSheet(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("FLACCueSheet() = %v", err)
+	}
+
+	testValue(t, "0724384260925", c.MediaCatalogNumber)
+	testValue(t, uint64(88200), c.LeadInSamples)
+	testValue(t, true, c.CompactDisc)
+	testValue(t, 4, len(c.Tracks))
+
+	tr := c.Tracks[1]
+	testValue(t, 2, tr.Number)
+	testValue(t, uint64(44100), tr.Offset)
+	testValue(t, "GBAYE0000002", tr.ISRC)
+	testValue(t, false, tr.NonAudio)
+	testValue(t, 2, len(tr.Indices))
+	testValue(t, CueSheetIndex{Offset: 588, Number: 1}, tr.Indices[1])
+
+	leadOut := c.Tracks[3]
+	testValue(t, 170, leadOut.Number)
+	testValue(t, 0, len(leadOut.Indices))
+}
+
+func TestFLACCueSheetMissing(t *testing.T) {
+	if _, err := FLACCueSheet(bytes.NewReader(testFLAC())); err == nil {
+		t.Errorf("expected error for FLAC without CUESHEET block")
+	}
+}
+
+func TestFLACPadding(t *testing.T) {
+	for path, want := range map[string]int{
+		"with_tags/sample.flac":    7988,
+		"without_tags/sample.flac": 8215,
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		n, err := FLACPadding(f)
+		if err != nil {
+			t.Fatalf("%v: FLACPadding() = %v", path, err)
+		}
+		if n != want {
+			t.Errorf("%v: FLACPadding() = %d, expected %d", path, n, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		data []byte
+		want int
+	}{
+		{testFLAC(), 0},
+		{testFLAC(
+			testFLACBlock(paddingBlock, false, make([]byte, 10)),
+			testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, nil)),
+			testFLACBlock(paddingBlock, true, make([]byte, 20)),
+		), 30},
+	} {
+		n, err := FLACPadding(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("FLACPadding() = %v", err)
+		}
+		testValue(t, tt.want, n)
+	}
+}
+
+func TestReadFLACTagsJunk(t *testing.T) {
+	sample, err := os.ReadFile("testdata/with_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ReadFLACTags(bytes.NewReader(sample))
+	if err != nil {
+		t.Fatalf("ReadFLACTags() = %v", err)
+	}
+
+	junk := []byte("HTTP/1.1 200 OK\r\nContent-Type: audio/flac\r\n\r\n\xef\xbb\xbf")
+	junk = append(junk, make([]byte, 512-len(junk))...)
+	m, err := ReadFLACTags(bytes.NewReader(append(junk, sample...)))
+	if err != nil {
+		t.Fatalf("ReadFLACTags() = %v", err)
+	}
+	testValue(t, want.Title(), m.Title())
+	testValue(t, want.Artist(), m.Artist())
+	bitrate, _ := m.(*metadataFLAC).Bitrate()
+	wantBitrate, _ := want.(*metadataFLAC).Bitrate()
+	testValue(t, wantBitrate, bitrate)
+
+	// The marker is only searched for near the start.
+	junk = make([]byte, flacMaxJunk+1)
+	if _, err := ReadFLACTags(bytes.NewReader(append(junk, sample...))); err == nil {
+		t.Errorf("ReadFLACTags() = nil, expected error")
+	}
+}
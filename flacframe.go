@@ -0,0 +1,304 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// flacStreamInfo is the content of a FLAC STREAMINFO block.
+// See https://xiph.org/flac/format.html#metadata_block_streaminfo.
+type flacStreamInfo struct {
+	MinBlockSize  int // Samples, excluding the last block.
+	MaxBlockSize  int // Samples.
+	MinFrameSize  int // Bytes, zero if unknown.
+	MaxFrameSize  int // Bytes, zero if unknown.
+	SampleRate    int // Hz.
+	Channels      int
+	BitsPerSample int
+	TotalSamples  uint64 // Samples per channel, zero if unknown.
+	MD5           [16]byte
+}
+
+// readFLACStreamInfo parses the content of a STREAMINFO block.
+func readFLACStreamInfo(b []byte) (*flacStreamInfo, error) {
+	if len(b) < 34 {
+		return nil, errors.New("invalid STREAMINFO block")
+	}
+
+	x := binary.BigEndian.Uint64(b[10:18])
+	si := &flacStreamInfo{
+		MinBlockSize:  getInt(b[0:2]),
+		MaxBlockSize:  getInt(b[2:4]),
+		MinFrameSize:  getInt(b[4:7]),
+		MaxFrameSize:  getInt(b[7:10]),
+		SampleRate:    int(x >> 44),
+		Channels:      int(x>>41&0x7) + 1,
+		BitsPerSample: int(x>>36&0x1F) + 1,
+		TotalSamples:  x & (1<<36 - 1),
+	}
+	copy(si.MD5[:], b[18:34])
+	return si, nil
+}
+
+// flacFrame is the position of a FLAC audio frame.
+type flacFrame struct {
+	Sample    uint64 // Number of the first sample in the frame.
+	Offset    int64  // Offset of the frame header from the first frame.
+	BlockSize int    // Number of samples in the frame.
+}
+
+// flacBlockSizes maps the block size codes of a frame header to block sizes, zero
+// means reserved or stored at the end of the header.
+var flacBlockSizes = [16]int{0, 192, 576, 1152, 2304, 4608, 0, 0, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768}
+
+// readFLACFrameHeader parses the FLAC frame header at the start of b, returning the frame
+// (with a zero offset) and false if b does not begin with a valid frame header.
+// See https://xiph.org/flac/format.html#frame_header.
+func readFLACFrameHeader(b []byte, si *flacStreamInfo) (f flacFrame, ok bool) {
+	if len(b) < 6 || b[0] != 0xFF || b[1]&0xFE != 0xF8 {
+		return
+	}
+	variable := b[1]&1 == 1
+
+	blockSizeCode, rateCode := b[2]>>4, b[2]&0xF
+	if blockSizeCode == 0 || rateCode == 0xF || b[3]>>4 > 10 || b[3]&0x0F == 0x07 || b[3]&1 != 0 {
+		return
+	}
+
+	// UTF-8 style coded frame number (fixed block size) or sample number (variable),
+	// the number of leading one bits in the first byte is the number of bytes.
+	ones := 0
+	for ones < 8 && b[4]&(0x80>>uint(ones)) != 0 {
+		ones++
+	}
+	n, num := 1, uint64(b[4])
+	if ones > 0 {
+		if ones == 1 || ones > 7 || len(b) < 4+ones {
+			return
+		}
+		n, num = ones, uint64(b[4]&(0xFF>>uint(ones+1)))
+		for _, c := range b[5 : 4+n] {
+			if c&0xC0 != 0x80 {
+				return
+			}
+			num = num<<6 | uint64(c&0x3F)
+		}
+	}
+	i := 4 + n
+
+	f.BlockSize = flacBlockSizes[blockSizeCode]
+	switch blockSizeCode {
+	case 6:
+		if len(b) < i+1 {
+			return
+		}
+		f.BlockSize = int(b[i]) + 1
+		i++
+	case 7:
+		if len(b) < i+2 {
+			return
+		}
+		f.BlockSize = getInt(b[i:i+2]) + 1
+		i += 2
+	}
+
+	switch rateCode {
+	case 12:
+		i++
+	case 13, 14:
+		i += 2
+	}
+	if len(b) < i+1 || flacCRC8(b[:i]) != b[i] {
+		return
+	}
+
+	f.Sample = num
+	if !variable {
+		f.Sample = num * uint64(si.MaxBlockSize)
+	}
+	return f, true
+}
+
+// flacCRC8 returns the CRC-8 (polynomial x^8 + x^2 + x^1 + x^0) of b, as used in FLAC
+// frame headers.
+func flacCRC8(b []byte) byte {
+	var crc byte
+	for _, c := range b {
+		crc ^= c
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// flacMaxFrameHeaderSize is the size of the largest FLAC frame header.
+const flacMaxFrameHeaderSize = 16
+
+// scanFLACFrames returns the positions of the audio frames in r, which must be positioned
+// at the first frame.  Frame headers are found by scanning for sync codes, and a candidate is
+// only accepted if it starts at the sample following the previous frame (which rejects sync
+// codes which occur by chance in the audio data).
+func scanFLACFrames(r io.Reader, si *flacStreamInfo) ([]flacFrame, error) {
+	br := bufio.NewReaderSize(r, shiftBufferSize)
+
+	var frames []flacFrame
+	var offset int64
+	var next uint64
+	for {
+		b, err := br.Peek(flacMaxFrameHeaderSize)
+		if len(b) < 2 {
+			if err == io.EOF {
+				return frames, nil
+			}
+			return nil, err
+		}
+
+		if b[0] == 0xFF {
+			if f, ok := readFLACFrameHeader(b, si); ok && f.Sample == next {
+				f.Offset = offset
+				frames = append(frames, f)
+				next += uint64(f.BlockSize)
+			}
+			br.Discard(1)
+			offset++
+			continue
+		}
+
+		// Skip to the next possible sync code.
+		buf, _ := br.Peek(br.Buffered())
+		n := bytes.IndexByte(buf, 0xFF)
+		if n < 0 {
+			n = len(buf)
+		}
+		br.Discard(n)
+		offset += int64(n)
+	}
+}
+
+// flacCRC16 returns the CRC-16 (polynomial x^16 + x^15 + x^2 + x^0) of b, as used at the end
+// of FLAC frames.
+func flacCRC16(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		crc = flacCRC16Update(crc, c)
+	}
+	return crc
+}
+
+// flacCRC16Update returns the CRC-16 (see flacCRC16) crc of some data updated with the byte c.
+func flacCRC16Update(crc uint16, c byte) uint16 {
+	crc ^= uint16(c) << 8
+	for i := 0; i < 8; i++ {
+		if crc&0x8000 != 0 {
+			crc = crc<<1 ^ 0x8005
+		} else {
+			crc <<= 1
+		}
+	}
+	return crc
+}
+
+// flacFrameEnd returns the size of the shortest prefix of b (which starts with a frame header)
+// ending with a matching CRC-16 and followed by a frame sync code, or zero if there is none.
+// This finds the end of a frame followed by a frame with an invalid header, which isn't found
+// by scanFLACFrames.
+func flacFrameEnd(b []byte) int {
+	var crc uint16
+	for i := 0; i+4 <= len(b); i++ {
+		if crc == binary.BigEndian.Uint16(b[i:i+2]) && b[i+2] == 0xFF && b[i+3]&0xFE == 0xF8 {
+			return i + 2
+		}
+		crc = flacCRC16Update(crc, b[i])
+	}
+	return 0
+}
+
+// FLACFrameError is the error returned by VerifyFLACAudio when an invalid audio frame is found.
+type FLACFrameError struct {
+	Offset int64  // Offset of the frame from the start of the data.
+	Reason string // Description of the problem.
+}
+
+func (e *FLACFrameError) Error() string {
+	return fmt.Sprintf("invalid FLAC frame at offset %d: %v", e.Offset, e.Reason)
+}
+
+// VerifyFLACAudio checks the audio frames of the FLAC data in r, without decoding the audio.
+// Each frame must have a valid header (sync code and CRC-8) which follows on from the previous
+// frame, and the CRC-16 at the end of each frame must match the frame data.  The frames must
+// cover all the samples given in the STREAMINFO block (if known), and the last frame must end
+// at the end of the data (or at an ID3v1 or APEv2 tag appended to it).  Returns a
+// *FLACFrameError giving the offset of the first bad frame if the audio data is invalid.
+func VerifyFLACAudio(r io.ReadSeeker) error {
+	blocks, audioOffset, err := readFLACBlocks(r)
+	if err != nil {
+		return err
+	}
+	si, err := readFLACStreamInfo(blocks[0].Data)
+	if err != nil {
+		return err
+	}
+
+	trailer, err := trailerTagSize(r)
+	if err != nil {
+		return err
+	}
+	end, err := r.Seek(-trailer, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(audioOffset, io.SeekStart); err != nil {
+		return err
+	}
+	frames, err := scanFLACFrames(io.LimitReader(r, end-audioOffset), si)
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 || frames[0].Offset != 0 {
+		return &FLACFrameError{Offset: audioOffset, Reason: "expected frame header"}
+	}
+
+	var samples uint64
+	for i, f := range frames {
+		offset := audioOffset + f.Offset
+		next := end
+		if i+1 < len(frames) {
+			next = audioOffset + frames[i+1].Offset
+		}
+
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		b, err := readBytes(r, uint(next-offset))
+		if err != nil {
+			return err
+		}
+		if len(b) < 2 || flacCRC16(b[:len(b)-2]) != binary.BigEndian.Uint16(b[len(b)-2:]) {
+			// The next frame may have been skipped by scanFLACFrames because its header
+			// is invalid, rather than this frame being corrupt.
+			if n := flacFrameEnd(b); n > 0 {
+				return &FLACFrameError{Offset: offset + int64(n), Reason: "invalid frame header"}
+			}
+			return &FLACFrameError{Offset: offset, Reason: "CRC-16 mismatch"}
+		}
+		samples += uint64(f.BlockSize)
+	}
+
+	if si.TotalSamples != 0 && samples != si.TotalSamples {
+		return &FLACFrameError{Offset: end, Reason: fmt.Sprintf("frames contain %d samples, expected %d", samples, si.TotalSamples)}
+	}
+	return nil
+}
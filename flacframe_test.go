@@ -0,0 +1,94 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"io"
+	"testing"
+)
+
+func TestVerifyFLACAudio(t *testing.T) {
+	for _, path := range []string{"with_tags/sample.flac", "without_tags/sample.flac"} {
+		f := tempCopy(t, path)
+		if err := VerifyFLACAudio(f); err != nil {
+			t.Errorf("%v: VerifyFLACAudio() = %v", path, err)
+		}
+	}
+}
+
+func TestVerifyFLACAudioCorrupt(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	_, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+	size := int64(len(readAll(t, f)))
+
+	// Corrupt a byte in the middle of the audio data.
+	pos := audioOffset + (size-audioOffset)/2
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, pos); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{b[0] ^ 0x55}, pos); err != nil {
+		t.Fatal(err)
+	}
+
+	err = VerifyFLACAudio(f)
+	fe, ok := err.(*FLACFrameError)
+	if !ok {
+		t.Fatalf("VerifyFLACAudio() = %v, expected *FLACFrameError", err)
+	}
+	if fe.Offset < audioOffset || fe.Offset > pos {
+		t.Errorf("FLACFrameError.Offset = %d, expected frame containing offset %d", fe.Offset, pos)
+	}
+}
+
+func TestVerifyFLACAudioCorruptHeader(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	blocks, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+	si, err := readFLACStreamInfo(blocks[0].Data)
+	if err != nil {
+		t.Fatalf("readFLACStreamInfo() = %v", err)
+	}
+	frames, err := scanFLACFrames(f, si)
+	if err != nil {
+		t.Fatalf("scanFLACFrames() = %v", err)
+	}
+
+	// Corrupt the frame number of a frame header, so its CRC-8 doesn't match.
+	pos := audioOffset + frames[len(frames)/2].Offset
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, pos+4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{b[0] ^ 0x01}, pos+4); err != nil {
+		t.Fatal(err)
+	}
+
+	err = VerifyFLACAudio(f)
+	fe, ok := err.(*FLACFrameError)
+	if !ok {
+		t.Fatalf("VerifyFLACAudio() = %v, expected *FLACFrameError", err)
+	}
+	testValue(t, pos, fe.Offset)
+	testValue(t, "invalid frame header", fe.Reason)
+}
+
+func TestVerifyFLACAudioTrailingTag(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write(id3v1Tag(map[string]string{FieldTitle: "Title"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyFLACAudio(f); err != nil {
+		t.Errorf("VerifyFLACAudio() = %v, expected ID3v1 tag to be ignored", err)
+	}
+}
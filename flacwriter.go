@@ -0,0 +1,716 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// flacMaxBlockLen is the largest length of FLAC metadata block data.
+const flacMaxBlockLen = 1<<24 - 1
+
+// FLACWriteOptions are the options of the functions which write FLAC metadata (see
+// WriteFLACTagsWithOptions, UpdateFLACTagsWithOptions and RemovePicturesWithOptions).  The zero
+// value is used by the functions without options.
+type FLACWriteOptions struct {
+	// PaddingLast rewrites the metadata so that there is a single PADDING block, which is the
+	// last metadata block (as expected by some validators).  Otherwise the existing layout of the
+	// metadata blocks is kept where possible.
+	PaddingLast bool
+
+	// NoShift stops the audio data from being moved when the size of the metadata changes:
+	// ErrWouldShift is returned without making changes when the new metadata doesn't fit exactly
+	// in the space used by the existing metadata (including padding), so batch jobs can skip
+	// large files which would have to be rewritten.
+	NoShift bool
+}
+
+// ErrWouldShift is the error returned when writing would move the audio data and
+// FLACWriteOptions.NoShift is set.
+var ErrWouldShift = errors.New("writing metadata would move the audio data")
+
+// flacPadding is the number of bytes of padding added when FLACWriteOptions.PaddingLast is
+// set and there is no existing padding.
+const flacPadding = 1024
+
+// flacBlock is a FLAC metadata block.
+type flacBlock struct {
+	Type blockType
+	Data []byte
+}
+
+// readFLACBlocks reads all the metadata blocks of the FLAC data in r, returning the blocks
+// and the offset of the first audio frame.
+func readFLACBlocks(r io.ReadSeeker) ([]flacBlock, int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	flac, err := readString(r, 4)
+	if err != nil {
+		return nil, 0, err
+	}
+	if flac != "fLaC" {
+		return nil, 0, errors.New("expected 'fLaC'")
+	}
+
+	offset := int64(4)
+	var blocks []flacBlock
+	for {
+		t, last, blockLen, err := readFLACBlockHeader(r)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		b, err := readBytes(r, blockLen)
+		if err != nil {
+			return nil, 0, err
+		}
+		blocks = append(blocks, flacBlock{Type: t, Data: b})
+		offset += 4 + int64(blockLen)
+
+		if last {
+			return blocks, offset, nil
+		}
+	}
+}
+
+// pictureTypeCode returns the APIC/FLAC picture type code for the name t (see pictureTypes),
+// or 0 ("Other") if t is not known.
+func pictureTypeCode(t string) byte {
+	for k, v := range pictureTypes {
+		if v == t {
+			return k
+		}
+	}
+	return 0
+}
+
+// buildFLACPictureBlock returns the content of a FLAC PICTURE block (excluding the block header)
+// containing pic.  All integers are 32-bit big-endian, and the lengths precede the MIME type,
+// description and picture data respectively.  See https://xiph.org/flac/format.html#metadata_block_picture.
+// The MIME type is checked against the picture data (see pictureMIMEType).
+func buildFLACPictureBlock(pic *Picture) ([]byte, error) {
+	mime, err := pictureMIMEType(pic)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 0, 32+len(mime)+len(pic.Description)+len(pic.Data))
+	b = binary.BigEndian.AppendUint32(b, uint32(pictureTypeCode(pic.Type)))
+	b = binary.BigEndian.AppendUint32(b, uint32(len(mime)))
+	b = append(b, mime...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(pic.Description)))
+	b = append(b, pic.Description...)
+	b = binary.BigEndian.AppendUint32(b, uint32(pic.Width))
+	b = binary.BigEndian.AppendUint32(b, uint32(pic.Height))
+	b = binary.BigEndian.AppendUint32(b, 0) // colour depth (unknown)
+	b = binary.BigEndian.AppendUint32(b, 0) // number of colours (zero for non-indexed pictures)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(pic.Data)))
+	return append(b, pic.Data...), nil
+}
+
+// setFLACPicture adds a PICTURE block containing pic to the FLAC data in rw, replacing any
+// PICTURE blocks with the same picture type.  Trailing padding is resized to absorb the change
+// in size where possible.
+func setFLACPicture(rw io.ReadWriteSeeker, pic *Picture) error {
+	b, err := buildFLACPictureBlock(pic)
+	if err != nil {
+		return err
+	}
+	blocks, audioOffset, err := readFLACBlocks(rw)
+	if err != nil {
+		return err
+	}
+	delta := 4 + len(b)
+	code := pictureTypeCode(pic.Type)
+	kept := blocks[:0]
+	for _, blk := range blocks {
+		// The picture type is the first field of the block (32-bit big-endian).
+		if blk.Type == pictureBlock && len(blk.Data) >= 4 && getInt(blk.Data[0:4]) == int(code) {
+			delta -= 4 + len(blk.Data)
+			continue
+		}
+		kept = append(kept, blk)
+	}
+	blocks = insertFLACBlock(kept, flacBlock{Type: pictureBlock, Data: b})
+
+	resizeFLACPadding(blocks, delta)
+	return writeFLACBlocks(rw, blocks, audioOffset, FLACWriteOptions{})
+}
+
+// insertFLACBlock returns blocks with b inserted after the last non-PADDING block, so that
+// any trailing padding remains available for later edits.  The last-metadata-block flag is
+// not stored in flacBlock and is set by encodeFLACBlocks.
+func insertFLACBlock(blocks []flacBlock, b flacBlock) []flacBlock {
+	i := len(blocks)
+	for i > 0 && blocks[i-1].Type == paddingBlock {
+		i--
+	}
+	blocks = append(blocks, flacBlock{})
+	copy(blocks[i+1:], blocks[i:])
+	blocks[i] = b
+	return blocks
+}
+
+// encodeFLACBlocks returns the "fLaC" marker followed by the given metadata blocks.  The
+// last-metadata-block flag is set on the final block, and cleared on all others.
+func encodeFLACBlocks(blocks []flacBlock) ([]byte, error) {
+	if len(blocks) == 0 || blocks[0].Type != streamInfoBlock {
+		return nil, errors.New("first FLAC metadata block must be STREAMINFO")
+	}
+
+	n := 4
+	for _, b := range blocks {
+		if len(b.Data) > flacMaxBlockLen {
+			return nil, fmt.Errorf("FLAC metadata block too large: %d bytes", len(b.Data))
+		}
+		n += 4 + len(b.Data)
+	}
+
+	out := make([]byte, 0, n)
+	out = append(out, "fLaC"...)
+	for i, b := range blocks {
+		h := byte(b.Type)
+		if i == len(blocks)-1 {
+			h |= 1 << 7
+		}
+		out = append(out, h)
+		out = append(out, formatUintBigEndian(uint(len(b.Data)), 3)...)
+		out = append(out, b.Data...)
+	}
+	return out, nil
+}
+
+// writeFLACBlocks replaces the metadata of the FLAC data in rw (which ends at audioOffset)
+// with the given blocks.
+func writeFLACBlocks(rw io.ReadWriteSeeker, blocks []flacBlock, audioOffset int64, opts FLACWriteOptions) error {
+	b, err := encodeFLACBlocks(blocks)
+	if err != nil {
+		return err
+	}
+	if opts.NoShift && int64(len(b)) != audioOffset {
+		return ErrWouldShift
+	}
+	return replaceRegion(rw, 0, audioOffset, b)
+}
+
+// FLACBlock is a FLAC metadata block (see ReadFLACBlocks and WriteFLACBlocks).
+type FLACBlock struct {
+	Type byte   // Block type, i.e. 4 for VORBIS_COMMENT (see https://xiph.org/flac/format.html#metadata_block_header).
+	Data []byte // Block data, excluding the block header.
+}
+
+// ReadFLACBlocks reads all the metadata blocks of the FLAC data in r, the first of which is
+// the STREAMINFO block.  On success r is positioned at the first audio frame, so it can be
+// passed as the audio to WriteFLACBlocks.
+func ReadFLACBlocks(r io.ReadSeeker) ([]FLACBlock, error) {
+	blocks, _, err := readFLACBlocks(r)
+	if err != nil {
+		return nil, err
+	}
+	if blocks[0].Type != streamInfoBlock {
+		return nil, errors.New("first FLAC metadata block must be STREAMINFO")
+	}
+
+	res := make([]FLACBlock, len(blocks))
+	for i, b := range blocks {
+		res[i] = FLACBlock{Type: byte(b.Type), Data: b.Data}
+	}
+	return res, nil
+}
+
+// WriteFLACBlocks writes FLAC data to w: the "fLaC" marker, the STREAMINFO block with the
+// given content, the given metadata blocks and then the audio data read from audio.  The
+// last-metadata-block flag is set on the final block.  Returns an error without writing
+// anything if streamInfo is not a valid STREAMINFO block, or if blocks contains a STREAMINFO
+// block (which must be first, and only once).  To rewrite the blocks read by ReadFLACBlocks,
+// pass blocks[0].Data as streamInfo and blocks[1:] as blocks.
+func WriteFLACBlocks(w io.Writer, streamInfo []byte, blocks []FLACBlock, audio io.Reader) error {
+	if len(streamInfo) != 34 {
+		return fmt.Errorf("invalid STREAMINFO block: %d bytes, expected 34", len(streamInfo))
+	}
+
+	all := make([]flacBlock, 0, len(blocks)+1)
+	all = append(all, flacBlock{Type: streamInfoBlock, Data: streamInfo})
+	for _, b := range blocks {
+		if blockType(b.Type) == streamInfoBlock {
+			return errors.New("STREAMINFO must only be the first FLAC metadata block")
+		}
+		if b.Type >= 127 {
+			return fmt.Errorf("invalid FLAC metadata block type: %d", b.Type)
+		}
+		all = append(all, flacBlock{Type: blockType(b.Type), Data: b.Data})
+	}
+
+	b, err := encodeFLACBlocks(all)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(b); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, audio)
+	return err
+}
+
+// removeFLACBlocks removes all metadata blocks of type t from the FLAC data in rw, returning
+// the number of bytes removed.
+func removeFLACBlocks(rw io.ReadWriteSeeker, t blockType, opts FLACWriteOptions) (int64, error) {
+	blocks, audioOffset, err := readFLACBlocks(rw)
+	if err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	kept := blocks[:0]
+	for _, b := range blocks {
+		if b.Type == t {
+			removed += 4 + int64(len(b.Data))
+			continue
+		}
+		kept = append(kept, b)
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, writeFLACBlocks(rw, kept, audioOffset, opts)
+}
+
+// WriteFLACTags replaces the Vorbis comment fields of the FLAC data in rw with the fields in
+// data (see PrepareVorbisComment).  Fields which are not in data are removed, whereas a field
+// with an empty value is written as an empty comment.  The existing vendor string is kept unless
+// data contains a "vendor" key.  Use UpdateFLACTags to change only some fields.
+func WriteFLACTags(rw io.ReadWriteSeeker, data map[string]string) error {
+	return WriteFLACTagsWithOptions(rw, data, FLACWriteOptions{})
+}
+
+// WriteFLACTagsWithOptions is like WriteFLACTags, but with the given options.
+func WriteFLACTagsWithOptions(rw io.ReadWriteSeeker, data map[string]string, opts FLACWriteOptions) error {
+	blocks, audioOffset, err := readFLACBlocks(rw)
+	if err != nil {
+		return err
+	}
+
+	data, err = withFLACVendor(blocks, data)
+	if err != nil {
+		return err
+	}
+	comment, err := PrepareVorbisComment(data)
+	if err != nil {
+		return err
+	}
+	return writeFLACComment(rw, blocks, audioOffset, comment, opts)
+}
+
+// WriteFLACTagsWithPadding is like WriteFLACTags, but also replaces any PADDING blocks with a
+// single trailing PADDING block of paddingBytes bytes (or no PADDING block if paddingBytes is
+// zero), to leave room for future edits.  The audio data is moved if the size of the metadata
+// changes.
+func WriteFLACTagsWithPadding(rw io.ReadWriteSeeker, data map[string]string, paddingBytes int) error {
+	if paddingBytes < 0 || paddingBytes > flacMaxBlockLen {
+		return fmt.Errorf("invalid FLAC padding size: %d", paddingBytes)
+	}
+
+	blocks, audioOffset, err := readFLACBlocks(rw)
+	if err != nil {
+		return err
+	}
+
+	data, err = withFLACVendor(blocks, data)
+	if err != nil {
+		return err
+	}
+	comment, err := PrepareVorbisComment(data)
+	if err != nil {
+		return err
+	}
+	blocks, _ = setFLACComment(blocks, comment, FLACWriteOptions{})
+
+	kept := make([]flacBlock, 0, len(blocks)+1)
+	for _, b := range blocks {
+		if b.Type != paddingBlock {
+			kept = append(kept, b)
+		}
+	}
+	if paddingBytes > 0 {
+		kept = append(kept, flacBlock{Type: paddingBlock, Data: make([]byte, paddingBytes)})
+	}
+	return writeFLACBlocks(rw, kept, audioOffset, FLACWriteOptions{})
+}
+
+// withFLACVendor returns the normalised fields in data, with the vendor string of the existing
+// comment in blocks added unless data contains a "vendor" key.
+func withFLACVendor(blocks []flacBlock, data map[string]string) (map[string]string, error) {
+	data = normaliseFields(data)
+	if _, ok := data["VENDOR"]; !ok {
+		old, err := readFLACComment(blocks)
+		if err != nil {
+			return nil, err
+		}
+		data["VENDOR"] = old["VENDOR"]
+	}
+	return data, nil
+}
+
+// WriteFLACTagsBuffered is like WriteFLACTags, but reads the FLAC data from r and writes the
+// result to w, so can be used where the data is not seekable (i.e. pipes or streaming uploads).
+// The whole of r is read into memory.
+func WriteFLACTagsBuffered(w io.Writer, r io.Reader, data map[string]string) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	header, audioOffset, err := BuildFLACMetadata(bytes.NewReader(b), data)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(b[audioOffset:])
+	return err
+}
+
+// BuildFLACMetadata returns the "fLaC" marker and metadata blocks of the FLAC data in r with the
+// Vorbis comment fields replaced by those in data (as by WriteFLACTags), along with the offset in
+// r of the audio data.  r is not modified: the new file is header followed by the data in r from
+// audioOffset.
+func BuildFLACMetadata(r io.ReadSeeker, data map[string]string) (header []byte, audioOffset int64, err error) {
+	blocks, audioOffset, err := readFLACBlocks(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err = withFLACVendor(blocks, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	comment, err := PrepareVorbisComment(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	zeroFLACPadding(blocks)
+	blocks, _ = setFLACComment(blocks, comment, FLACWriteOptions{})
+
+	header, err = encodeFLACBlocks(blocks)
+	if err != nil {
+		return nil, 0, err
+	}
+	return header, audioOffset, nil
+}
+
+// UpdateFLACTags merges the fields in data into the Vorbis comment of the FLAC data in rw.
+// Fields which are not in data are kept (with all of their values, if repeated), a field with
+// a nil value is removed and a field with a non-nil value replaces the existing values (so a
+// pointer to the empty string writes an empty comment).
+func UpdateFLACTags(rw io.ReadWriteSeeker, data map[string]*string) error {
+	return UpdateFLACTagsWithOptions(rw, data, FLACWriteOptions{})
+}
+
+// UpdateFLACTagsWithOptions is like UpdateFLACTags, but with the given options.
+func UpdateFLACTagsWithOptions(rw io.ReadWriteSeeker, data map[string]*string, opts FLACWriteOptions) error {
+	blocks, audioOffset, err := readFLACBlocks(rw)
+	if err != nil {
+		return err
+	}
+
+	m, err := readFLACVorbis(blocks)
+	if err != nil {
+		return err
+	}
+	vendor, values := vorbisVendor, map[string][]string(nil)
+	if m != nil {
+		vendor, values = m.c["vendor"], m.values
+	}
+	comment, err := updateVorbisComment(vendor, values, data)
+	if err != nil {
+		return err
+	}
+	return writeFLACComment(rw, blocks, audioOffset, comment, opts)
+}
+
+// readFLACComment returns the normalised fields (including the vendor string) of the
+// VORBIS_COMMENT blocks in blocks (see readFLACVorbis), or only the default vendor string if
+// there aren't any.
+func readFLACComment(blocks []flacBlock) (map[string]string, error) {
+	m, err := readFLACVorbis(blocks)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		return map[string]string{"VENDOR": vorbisVendor}, nil
+	}
+	return normaliseFields(m.c), nil
+}
+
+// readFLACVorbis returns the comments of the VORBIS_COMMENT blocks in blocks, or nil if there
+// aren't any.  There should only be one block, but if there are duplicates then fields from
+// the first block take precedence (as when reading).
+func readFLACVorbis(blocks []flacBlock) (*metadataVorbis, error) {
+	var m *metadataVorbis
+	for _, b := range blocks {
+		if b.Type == vorbisCommentBlock {
+			d := newMetadataVorbis()
+			if err := d.readVorbisComment(bytes.NewReader(b.Data)); err != nil {
+				return nil, fmt.Errorf("error reading VORBIS_COMMENT block: %v", err)
+			}
+			if m == nil {
+				m = d
+				continue
+			}
+			m.merge(d)
+		}
+	}
+	return m, nil
+}
+
+// writeFLACComment replaces the Vorbis comment in blocks (read from rw, with the audio data
+// starting at audioOffset) with comment (see setFLACComment), and writes the blocks to rw.
+// A comment of the same length as the existing one is overwritten in place, unless the
+// padding has to be cleaned (see zeroFLACPadding).
+func writeFLACComment(rw io.ReadWriteSeeker, blocks []flacBlock, audioOffset int64, comment []byte, opts FLACWriteOptions) error {
+	dirty := zeroFLACPadding(blocks)
+	blocks, offset := setFLACComment(blocks, comment, opts)
+	if offset >= 0 && !dirty {
+		if _, err := rw.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := rw.Write(comment)
+		return err
+	}
+	return writeFLACBlocks(rw, blocks, audioOffset, opts)
+}
+
+// setFLACComment returns blocks with the first VORBIS_COMMENT block replaced by comment (or
+// a new block added if there isn't one), and any duplicate VORBIS_COMMENT blocks removed.
+// Trailing padding is resized to absorb the change in size where possible, so that the audio
+// data does not have to be moved.  If the new comment is the same length as the existing one
+// and the layout is otherwise unchanged, then the offset of the comment data is returned
+// (so that it can be overwritten in place), otherwise -1.
+func setFLACComment(blocks []flacBlock, comment []byte, opts FLACWriteOptions) ([]flacBlock, int64) {
+	var reorganised bool
+	if opts.PaddingLast {
+		blocks, reorganised = consolidateFLACPadding(blocks)
+	}
+
+	var delta int
+	i := 0
+	offset := int64(4)
+	for i < len(blocks) && blocks[i].Type != vorbisCommentBlock {
+		offset += 4 + int64(len(blocks[i].Data))
+		i++
+	}
+
+	// Remove duplicate blocks.
+	n := len(blocks)
+	if i < len(blocks) {
+		kept := blocks[:i+1]
+		for _, b := range blocks[i+1:] {
+			if b.Type == vorbisCommentBlock {
+				delta -= 4 + len(b.Data)
+				continue
+			}
+			kept = append(kept, b)
+		}
+		blocks = kept
+	}
+
+	if i < len(blocks) {
+		if !reorganised && len(blocks) == n && len(comment) == len(blocks[i].Data) {
+			blocks[i].Data = comment
+			return blocks, offset + 4
+		}
+		delta += len(comment) - len(blocks[i].Data)
+		blocks[i].Data = comment
+	} else {
+		delta += 4 + len(comment)
+		blocks = insertFLACBlock(blocks, flacBlock{Type: vorbisCommentBlock, Data: comment})
+	}
+
+	resizeFLACPadding(blocks, delta)
+	return blocks, -1
+}
+
+// consolidateFLACPadding returns blocks with all PADDING blocks replaced by a single PADDING
+// block at the end (with the same total size, or flacPadding bytes if there is no padding),
+// and true if this changed the layout.
+func consolidateFLACPadding(blocks []flacBlock) ([]flacBlock, bool) {
+	n, count := 0, 0
+	kept := make([]flacBlock, 0, len(blocks)+1)
+	for _, b := range blocks {
+		if b.Type == paddingBlock {
+			n += 4 + len(b.Data)
+			count++
+			continue
+		}
+		kept = append(kept, b)
+	}
+
+	if count == 1 && blocks[len(blocks)-1].Type == paddingBlock {
+		return blocks, false
+	}
+	if count == 0 {
+		n = 4 + flacPadding
+	}
+	return append(kept, flacBlock{Type: paddingBlock, Data: make([]byte, n-4)}), true
+}
+
+// resizeFLACPadding resizes the trailing PADDING block in blocks (if there is one) to absorb
+// a change of delta bytes in the size of the other blocks where possible, so that the audio
+// data does not have to be moved.
+func resizeFLACPadding(blocks []flacBlock, delta int) {
+	if p := &blocks[len(blocks)-1]; p.Type == paddingBlock && delta <= len(p.Data) {
+		p.Data = make([]byte, len(p.Data)-delta)
+	}
+}
+
+// zeroFLACPadding zeroes the data of the PADDING blocks in blocks (which is reused when
+// writing), and returns true if any of it was not already zero.
+func zeroFLACPadding(blocks []flacBlock) bool {
+	dirty := false
+	for _, b := range blocks {
+		if b.Type == paddingBlock && !isZero(b.Data) {
+			for i := range b.Data {
+				b.Data[i] = 0
+			}
+			dirty = true
+		}
+	}
+	return dirty
+}
+
+// isZero returns true if all of the bytes in b are zero.
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FLACPaddingIsClean returns true if the data of all the PADDING blocks of the FLAC data in r
+// is zero, as required by the specification.  Encoders and taggers sometimes leave other data
+// in the padding, which WriteFLACTags and UpdateFLACTags clean up.
+func FLACPaddingIsClean(r io.ReadSeeker) (bool, error) {
+	blocks, _, err := readFLACBlocks(r)
+	if err != nil {
+		return false, err
+	}
+	for _, b := range blocks {
+		if b.Type == paddingBlock && !isZero(b.Data) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RepairFLACBlockChain sets the last-metadata-block flag of the FLAC data in rw on the
+// block which is followed by the first audio frame, for files where no block has the flag
+// set (so that the audio data would be read as metadata).  The audio data is found by
+// checking for a valid frame header (for the first sample, see the STREAMINFO block) after
+// each metadata block.  Returns nil without making changes if a block already has the flag.
+func RepairFLACBlockChain(rw io.ReadWriteSeeker) error {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	flac, err := readString(rw, 4)
+	if err != nil {
+		return err
+	}
+	if flac != "fLaC" {
+		return errors.New("expected 'fLaC'")
+	}
+
+	var si *flacStreamInfo
+	for offset := int64(4); ; {
+		t, last, blockLen, err := readFLACBlockHeader(rw)
+		if err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+
+		b, err := readBytes(rw, blockLen)
+		if err != nil {
+			return fmt.Errorf("no audio data found after FLAC metadata blocks: %v", err)
+		}
+		if si == nil {
+			if t != streamInfoBlock {
+				return errors.New("first FLAC metadata block must be STREAMINFO")
+			}
+			if si, err = readFLACStreamInfo(b); err != nil {
+				return err
+			}
+		}
+
+		next := offset + 4 + int64(blockLen)
+		h := make([]byte, flacMaxFrameHeaderSize)
+		n, err := io.ReadFull(rw, h)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("no audio data found after FLAC metadata blocks: %v", err)
+		}
+		if f, ok := readFLACFrameHeader(h[:n], si); ok && f.Sample == 0 {
+			if _, err := rw.Seek(offset, io.SeekStart); err != nil {
+				return err
+			}
+			_, err := rw.Write([]byte{byte(t) | 1<<7})
+			return err
+		}
+
+		offset = next
+		if _, err := rw.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+}
+
+// NormalizeFLACBlocks reorders the metadata blocks of the FLAC data in rw so that the
+// STREAMINFO block is first (as required by the specification) and PADDING blocks are last,
+// with the other blocks kept in their existing order, and sets the last-metadata-block flag on
+// the final block.  This repairs files where other tools have misordered the blocks.  The
+// total size of the metadata is unchanged, so the audio data is not moved.  Returns nil
+// without making changes if the blocks are already in order.
+func NormalizeFLACBlocks(rw io.ReadWriteSeeker) error {
+	blocks, audioOffset, err := readFLACBlocks(rw)
+	if err != nil {
+		return err
+	}
+
+	rank := func(t blockType) int {
+		switch t {
+		case streamInfoBlock:
+			return 0
+		case paddingBlock:
+			return 2
+		}
+		return 1
+	}
+
+	sorted := true
+	for i := 1; i < len(blocks); i++ {
+		if rank(blocks[i-1].Type) > rank(blocks[i].Type) {
+			sorted = false
+			break
+		}
+	}
+	if sorted {
+		return nil
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return rank(blocks[i].Type) < rank(blocks[j].Type)
+	})
+	return writeFLACBlocks(rw, blocks, audioOffset, FLACWriteOptions{})
+}
@@ -0,0 +1,748 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testFLACBlockChain walks the metadata block headers of the FLAC data in r up to audioOffset
+// (ignoring the last-metadata-block flags), returning the block types and the indices of the
+// blocks with the last-metadata-block flag set.
+func testFLACBlockChain(t *testing.T, r io.ReadSeeker, audioOffset int64) (types []blockType, lastFlags []int) {
+	t.Helper()
+	if _, err := r.Seek(4, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	for offset := int64(4); offset < audioOffset; {
+		bt, last, n, err := readFLACBlockHeader(r)
+		if err != nil {
+			t.Fatalf("readFLACBlockHeader() = %v", err)
+		}
+		if last {
+			lastFlags = append(lastFlags, len(types))
+		}
+		types = append(types, bt)
+
+		offset += 4 + int64(n)
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return
+}
+
+func TestInsertFLACBlockLastFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []blockType
+	}{
+		{
+			name: "no padding",
+			data: testFLAC(testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, nil))),
+			want: []blockType{streamInfoBlock, vorbisCommentBlock, pictureBlock},
+		},
+		{
+			name: "trailing padding",
+			data: testFLAC(
+				testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, nil)),
+				testFLACBlock(paddingBlock, true, make([]byte, 32)),
+			),
+			want: []blockType{streamInfoBlock, vorbisCommentBlock, pictureBlock, paddingBlock},
+		},
+		{
+			name: "streaminfo only",
+			data: testFLAC(),
+			want: []blockType{streamInfoBlock, pictureBlock},
+		},
+	}
+
+	for _, tt := range tests {
+		f := tempFile(t, tt.data)
+
+		blocks, audioOffset, err := readFLACBlocks(f)
+		if err != nil {
+			t.Fatalf("%v: readFLACBlocks() = %v", tt.name, err)
+		}
+		picture := flacBlock{Type: pictureBlock, Data: testFLACPictureData("image/png", []byte{1, 2, 3})}
+		blocks = insertFLACBlock(blocks, picture)
+		if err := writeFLACBlocks(f, blocks, audioOffset, FLACWriteOptions{}); err != nil {
+			t.Fatalf("%v: writeFLACBlocks() = %v", tt.name, err)
+		}
+
+		end, err := f.Seek(0, io.SeekEnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types, lastFlags := testFLACBlockChain(t, f, end-testFLACAudioSize)
+		if len(lastFlags) != 1 || lastFlags[0] != len(types)-1 {
+			t.Errorf("%v: last-metadata-block flag set on blocks %v of %v, expected only the final block", tt.name, lastFlags, types)
+		}
+		if len(types) != len(tt.want) {
+			t.Errorf("%v: block types = %v, expected %v", tt.name, types, tt.want)
+			continue
+		}
+		for i := range types {
+			if types[i] != tt.want[i] {
+				t.Errorf("%v: block types = %v, expected %v", tt.name, types, tt.want)
+				break
+			}
+		}
+	}
+}
+
+// testReadFLAC reads the metadata of the FLAC data in f.
+func testReadFLAC(t *testing.T, f io.ReadSeeker) Metadata {
+	t.Helper()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadFLACTags(f)
+	if err != nil {
+		t.Fatalf("ReadFLACTags() = %v", err)
+	}
+	return m
+}
+
+func TestWriteFLACTags(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	orig := testReadFLAC(t, f)
+	size := int64(len(readAll(t, f)))
+
+	err := WriteFLACTags(f, map[string]string{
+		"title":   "New Title",
+		"comment": "",
+	})
+	if err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+
+	m := testReadFLAC(t, f)
+	testValue(t, "New Title", m.Title())
+	testValue(t, "", m.Artist()) // absent keys are removed
+
+	raw := m.Raw()
+	if v, ok := raw["comment"]; !ok || v != "" {
+		t.Errorf("Raw()[\"comment\"] = %q (%v), expected empty value", v, ok)
+	}
+	testValue(t, orig.Raw()["vendor"], raw["vendor"])
+
+	// The change in size is absorbed by the padding.
+	testValue(t, size, int64(len(readAll(t, f))))
+	if err := VerifyFLACAudio(f); err != nil {
+		t.Errorf("VerifyFLACAudio() = %v", err)
+	}
+}
+
+func TestUpdateFLACTags(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	orig := testReadFLAC(t, f)
+	if orig.Artist() == "" || orig.Comment() == "" {
+		t.Fatal("expected sample to have artist and comment")
+	}
+
+	empty := ""
+	err := UpdateFLACTags(f, map[string]*string{
+		"ARTIST":  nil,
+		"comment": &empty,
+	})
+	if err != nil {
+		t.Fatalf("UpdateFLACTags() = %v", err)
+	}
+
+	m := testReadFLAC(t, f)
+	testValue(t, orig.Title(), m.Title()) // absent keys are kept
+	testValue(t, orig.Album(), m.Album())
+
+	raw := m.Raw()
+	if _, ok := raw["artist"]; ok {
+		t.Errorf("Raw()[\"artist\"] present, expected field to be removed")
+	}
+	if v, ok := raw["comment"]; !ok || v != "" {
+		t.Errorf("Raw()[\"comment\"] = %q (%v), expected empty value", v, ok)
+	}
+}
+
+func TestUpdateFLACTagsRepeatedFields(t *testing.T) {
+	comments := map[string][]string{
+		"TITLE":            {"Title"},
+		"ARTIST":           {"Artist 1", "Artist 2"},
+		FieldVorbisPicture: {testVorbisPicture("front"), testVorbisPicture("back")},
+	}
+	b, err := encodeVorbisComment("test", comments)
+	if err != nil {
+		t.Fatalf("encodeVorbisComment() = %v", err)
+	}
+	f := tempFile(t, testFLAC(testFLACBlock(vorbisCommentBlock, true, b)))
+
+	album := "Album"
+	if err := UpdateFLACTags(f, map[string]*string{"album": &album}); err != nil {
+		t.Fatalf("UpdateFLACTags() = %v", err)
+	}
+
+	m := testReadFLAC(t, f)
+	comments["ALBUM"] = []string{album}
+	if got := VorbisCommentsFrom(m); !reflect.DeepEqual(got, comments) {
+		t.Errorf("VorbisCommentsFrom() = %v, expected %v", got, comments)
+	}
+	testValue(t, "test", m.Raw()["vendor"])
+}
+
+func TestWriteFLACTagsNoComment(t *testing.T) {
+	f := tempFile(t, testFLAC())
+	if err := WriteFLACTags(f, map[string]string{"TITLE": "Title"}); err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+
+	m := testReadFLAC(t, f)
+	testValue(t, "Title", m.Title())
+	testValue(t, vorbisVendor, m.Raw()["vendor"])
+}
+
+func TestRepairFLACBlockChain(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	orig := testReadFLAC(t, f)
+	blocks, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+
+	// Clear the last-metadata-block flag.
+	last := blocks[len(blocks)-1]
+	if _, err := f.WriteAt([]byte{byte(last.Type)}, audioOffset-4-int64(len(last.Data))); err != nil {
+		t.Fatal(err)
+	}
+	if _, n, err := readFLACBlocks(f); err == nil && n == audioOffset {
+		t.Fatalf("readFLACBlocks() found audio offset, expected missing last-metadata-block flag")
+	}
+
+	if err := RepairFLACBlockChain(f); err != nil {
+		t.Fatalf("RepairFLACBlockChain() = %v", err)
+	}
+	types, lastFlags := testFLACBlockChain(t, f, audioOffset)
+	if len(lastFlags) != 1 || lastFlags[0] != len(types)-1 {
+		t.Errorf("last-metadata-block flag set on blocks %v of %v, expected only the final block", lastFlags, types)
+	}
+	m := testReadFLAC(t, f)
+	testValue(t, orig.Title(), m.Title())
+	testValue(t, orig.Artist(), m.Artist())
+
+	// A valid chain is left unchanged.
+	want := readAll(t, f)
+	if err := RepairFLACBlockChain(f); err != nil {
+		t.Fatalf("RepairFLACBlockChain() = %v", err)
+	}
+	if !bytes.Equal(want, readAll(t, f)) {
+		t.Errorf("RepairFLACBlockChain() changed a valid file")
+	}
+}
+
+func TestUpdateFLACTagsEqualLength(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	orig := readAll(t, f)
+	blocks, _, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+
+	// Region of the VORBIS_COMMENT block data.
+	start := int64(4)
+	var size int64
+	for _, b := range blocks {
+		if b.Type == vorbisCommentBlock {
+			size = int64(len(b.Data))
+			break
+		}
+		start += 4 + int64(len(b.Data))
+	}
+	start += 4
+
+	title := testReadFLAC(t, f).Title()
+	if title == "" {
+		t.Fatal("expected sample to have a title")
+	}
+	newTitle := strings.Repeat("x", len(title))
+	if err := UpdateFLACTags(f, map[string]*string{"TITLE": &newTitle}); err != nil {
+		t.Fatalf("UpdateFLACTags() = %v", err)
+	}
+
+	got := readAll(t, f)
+	if len(got) != len(orig) {
+		t.Fatalf("size = %d, expected %d", len(got), len(orig))
+	}
+	for i := range got {
+		if got[i] != orig[i] && (int64(i) < start || int64(i) >= start+size) {
+			t.Fatalf("byte %d changed, outside of VORBIS_COMMENT block [%d, %d)", i, start, start+size)
+		}
+	}
+	testValue(t, newTitle, testReadFLAC(t, f).Title())
+}
+
+func TestWriteFLACTagsDuplicateComment(t *testing.T) {
+	f := tempFile(t, testFLAC(
+		testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, map[string]string{"TITLE": "First"})),
+		testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, map[string]string{"TITLE": "Second", "ARTIST": "Artist"})),
+		testFLACBlock(paddingBlock, true, make([]byte, 32)),
+	))
+
+	// Fields from the first block take precedence.
+	m := testReadFLAC(t, f)
+	testValue(t, "First", m.Title())
+	testValue(t, "Artist", m.Artist())
+
+	if err := UpdateFLACTags(f, map[string]*string{}); err != nil {
+		t.Fatalf("UpdateFLACTags() = %v", err)
+	}
+
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, _ := testFLACBlockChain(t, f, end-testFLACAudioSize)
+	want := []blockType{streamInfoBlock, vorbisCommentBlock, paddingBlock}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("block types = %v, expected %v", types, want)
+	}
+
+	m = testReadFLAC(t, f)
+	testValue(t, "First", m.Title())
+	testValue(t, "Artist", m.Artist())
+}
+
+func TestWriteFLACTagsPaddingLast(t *testing.T) {
+	opts := FLACWriteOptions{PaddingLast: true}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "padding mid-chain",
+			data: testFLAC(
+				testFLACBlock(paddingBlock, false, make([]byte, 32)),
+				testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, nil)),
+				testFLACBlock(pictureBlock, false, testFLACPictureData("image/png", []byte{1, 2, 3})),
+				testFLACBlock(paddingBlock, true, make([]byte, 64)),
+			),
+		},
+		{
+			name: "no padding",
+			data: testFLAC(testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, nil))),
+		},
+		{
+			name: "padding last",
+			data: testFLAC(
+				testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, nil)),
+				testFLACBlock(paddingBlock, true, make([]byte, 64)),
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		f := tempFile(t, tt.data)
+		if err := WriteFLACTagsWithOptions(f, map[string]string{"TITLE": "Title"}, opts); err != nil {
+			t.Fatalf("%v: WriteFLACTagsWithOptions() = %v", tt.name, err)
+		}
+
+		end, err := f.Seek(0, io.SeekEnd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types, _ := testFLACBlockChain(t, f, end-testFLACAudioSize)
+		var padding int
+		for _, bt := range types {
+			if bt == paddingBlock {
+				padding++
+			}
+		}
+		if padding != 1 || types[len(types)-1] != paddingBlock {
+			t.Errorf("%v: block types = %v, expected a single PADDING block at the end", tt.name, types)
+		}
+		testValue(t, "Title", testReadFLAC(t, f).Title())
+	}
+
+	// Without the option the existing layout is kept.
+	f := tempFile(t, tests[0].data)
+	if err := WriteFLACTags(f, map[string]string{"TITLE": "Title"}); err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, _ := testFLACBlockChain(t, f, end-testFLACAudioSize)
+	want := []blockType{streamInfoBlock, paddingBlock, vorbisCommentBlock, pictureBlock, paddingBlock}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("block types = %v, expected %v", types, want)
+	}
+}
+
+func TestUpdateFLACTagsYear(t *testing.T) {
+	f := tempFile(t, testFLAC(testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{"DATE": "1996-04"}))))
+	testValue(t, 1996, testReadFLAC(t, f).Year())
+
+	year := "2001"
+	if err := UpdateFLACTags(f, map[string]*string{"year": &year}); err != nil {
+		t.Fatalf("UpdateFLACTags() = %v", err)
+	}
+	m := testReadFLAC(t, f)
+	testValue(t, 2001, m.Year())
+	testValue(t, "2001", m.Raw()["date"])
+}
+
+func TestWriteFLACTagsBuffered(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	orig := testReadFLAC(t, f)
+	in := readAll(t, f)
+
+	var out bytes.Buffer
+	err := WriteFLACTagsBuffered(&out, bytes.NewReader(in), map[string]string{"TITLE": "New Title"})
+	if err != nil {
+		t.Fatalf("WriteFLACTagsBuffered() = %v", err)
+	}
+
+	// The result is the same as writing in place.
+	if err := WriteFLACTags(f, map[string]string{"TITLE": "New Title"}); err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+	if !bytes.Equal(out.Bytes(), readAll(t, f)) {
+		t.Errorf("WriteFLACTagsBuffered() output differs from WriteFLACTags()")
+	}
+
+	r := bytes.NewReader(out.Bytes())
+	m := testReadFLAC(t, r)
+	testValue(t, "New Title", m.Title())
+	testValue(t, orig.Raw()["vendor"], m.Raw()["vendor"])
+	if err := VerifyFLACAudio(r); err != nil {
+		t.Errorf("VerifyFLACAudio() = %v", err)
+	}
+}
+
+func TestBuildFLACMetadata(t *testing.T) {
+	in := readAll(t, tempCopy(t, "with_tags/sample.flac"))
+	header, audioOffset, err := BuildFLACMetadata(bytes.NewReader(in), map[string]string{"TITLE": "New Title"})
+	if err != nil {
+		t.Fatalf("BuildFLACMetadata() = %v", err)
+	}
+
+	out := append(header, in[audioOffset:]...)
+	m := testReadFLAC(t, bytes.NewReader(out))
+	testValue(t, "New Title", m.Title())
+	if err := VerifyFLACAudio(bytes.NewReader(out)); err != nil {
+		t.Errorf("VerifyFLACAudio() = %v", err)
+	}
+}
+
+func TestBuildFLACPictureBlock(t *testing.T) {
+	tests := []*Picture{
+		{
+			Ext:         "jpg",
+			MIMEType:    "image/jpeg",
+			Type:        "Cover (front)",
+			Description: "Front",
+			Data:        bytes.Repeat([]byte{0xAB}, 300),
+			Width:       640,
+			Height:      480,
+		},
+		{
+			Ext:      "png",
+			MIMEType: "image/png",
+			Type:     "Other",
+			Data:     []byte{1, 2, 3},
+			Width:    1,
+			Height:   70000, // doesn't fit in 16 bits
+		},
+	}
+
+	for _, pic := range tests {
+		b, err := buildFLACPictureBlock(pic)
+		if err != nil {
+			t.Fatalf("buildFLACPictureBlock() = %v", err)
+		}
+
+		m := newMetadataVorbis()
+		if err := m.readPictureBlock(bytes.NewReader(b), false); err != nil {
+			t.Fatalf("readPictureBlock() = %v", err)
+		}
+		if !reflect.DeepEqual(m.Picture(), pic) {
+			t.Errorf("readPictureBlock(buildFLACPictureBlock(%v)) = %v", pic, m.Picture())
+		}
+	}
+
+	// Field order and byte order, compared with an independently built block.
+	b, err := buildFLACPictureBlock(&Picture{MIMEType: "image/png", Type: "Cover (front)", Data: []byte{1, 2, 3}})
+	if err != nil {
+		t.Fatalf("buildFLACPictureBlock() = %v", err)
+	}
+	if want := testFLACPictureData("image/png", []byte{1, 2, 3}); !bytes.Equal(b, want) {
+		t.Errorf("buildFLACPictureBlock() = %x, expected %x", b, want)
+	}
+}
+
+func TestNormalizeFLACBlocks(t *testing.T) {
+	orig := tempCopy(t, "with_tags/sample.flac")
+	blocks, audioOffset, err := readFLACBlocks(orig)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+	audio := readAll(t, orig)[audioOffset:]
+
+	// Misordered blocks: padding first, and STREAMINFO after the other blocks.
+	misordered := []flacBlock{{Type: paddingBlock, Data: make([]byte, 16)}}
+	misordered = append(misordered, blocks[1:]...)
+	misordered = append(misordered, blocks[0])
+
+	b := []byte("fLaC")
+	for i, mb := range misordered {
+		b = append(b, testFLACBlock(mb.Type, i == len(misordered)-1, mb.Data)...)
+	}
+	size := int64(len(b))
+	f := tempFile(t, append(b, audio...))
+
+	if err := NormalizeFLACBlocks(f); err != nil {
+		t.Fatalf("NormalizeFLACBlocks() = %v", err)
+	}
+	types, lastFlags := testFLACBlockChain(t, f, size)
+	if types[0] != streamInfoBlock {
+		t.Errorf("block types = %v, expected STREAMINFO first", types)
+	}
+	for i, bt := range types {
+		if bt == paddingBlock && i < len(types)-1 && types[i+1] != paddingBlock {
+			t.Errorf("block types = %v, expected PADDING last", types)
+		}
+	}
+	if len(lastFlags) != 1 || lastFlags[0] != len(types)-1 {
+		t.Errorf("last-metadata-block flag set on blocks %v of %v, expected only the final block", lastFlags, types)
+	}
+	if got := readAll(t, f); !bytes.Equal(got[size:], audio) {
+		t.Errorf("audio data changed")
+	}
+
+	m := testReadFLAC(t, f)
+	want := testReadFLAC(t, orig)
+	testValue(t, want.Title(), m.Title())
+	testValue(t, want.Artist(), m.Artist())
+
+	// Ordered blocks are left unchanged.
+	normalized := readAll(t, f)
+	if err := NormalizeFLACBlocks(f); err != nil {
+		t.Fatalf("NormalizeFLACBlocks() = %v", err)
+	}
+	if !bytes.Equal(normalized, readAll(t, f)) {
+		t.Errorf("NormalizeFLACBlocks() changed a normalized file")
+	}
+}
+
+func TestReadWriteFLACBlocks(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	_, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+	audio := readAll(t, f)[audioOffset:]
+
+	blocks, err := ReadFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("ReadFLACBlocks() = %v", err)
+	}
+	if blocks[0].Type != byte(streamInfoBlock) {
+		t.Fatalf("ReadFLACBlocks() first block type = %d, expected STREAMINFO", blocks[0].Type)
+	}
+
+	// Replace the Vorbis comment and add an APPLICATION block.
+	for i, b := range blocks {
+		if b.Type == byte(vorbisCommentBlock) {
+			blocks[i].Data = testVorbisComment(t, map[string]string{"TITLE": "New Title"})
+		}
+	}
+	blocks = append(blocks, FLACBlock{Type: byte(applicationBlock), Data: []byte("test data")})
+
+	// f is positioned at the audio data.
+	var buf bytes.Buffer
+	if err := WriteFLACBlocks(&buf, blocks[0].Data, blocks[1:], f); err != nil {
+		t.Fatalf("WriteFLACBlocks() = %v", err)
+	}
+
+	r := bytes.NewReader(buf.Bytes())
+	got, err := ReadFLACBlocks(r)
+	if err != nil {
+		t.Fatalf("ReadFLACBlocks() = %v", err)
+	}
+	if !reflect.DeepEqual(got, blocks) {
+		t.Errorf("ReadFLACBlocks() = %d blocks, expected the %d written blocks", len(got), len(blocks))
+	}
+	if rest, _ := io.ReadAll(r); !bytes.Equal(rest, audio) {
+		t.Errorf("audio data changed")
+	}
+	_, lastFlags := testFLACBlockChain(t, r, int64(buf.Len()-len(audio)))
+	if len(lastFlags) != 1 || lastFlags[0] != len(blocks)-1 {
+		t.Errorf("last-metadata-block flag set on blocks %v, expected only the final block", lastFlags)
+	}
+	testValue(t, "New Title", testReadFLAC(t, r).Title())
+
+	// STREAMINFO must be valid, and first.
+	if err := WriteFLACBlocks(io.Discard, nil, blocks[1:], bytes.NewReader(audio)); err == nil {
+		t.Errorf("WriteFLACBlocks() with empty STREAMINFO = nil, expected error")
+	}
+	if err := WriteFLACBlocks(io.Discard, blocks[0].Data, blocks, bytes.NewReader(audio)); err == nil {
+		t.Errorf("WriteFLACBlocks() with second STREAMINFO = nil, expected error")
+	}
+}
+
+func TestWriteFLACTagsNoShift(t *testing.T) {
+	opts := FLACWriteOptions{NoShift: true}
+	data := map[string]string{"TITLE": "A longer title than before"}
+
+	// Without padding the audio data must be moved.
+	orig := testFLAC(testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{"TITLE": "Title"})))
+	f := tempFile(t, orig)
+	if err := WriteFLACTagsWithOptions(f, data, opts); err != ErrWouldShift {
+		t.Errorf("WriteFLACTagsWithOptions() = %v, expected ErrWouldShift", err)
+	}
+	if !bytes.Equal(orig, readAll(t, f)) {
+		t.Errorf("WriteFLACTagsWithOptions() changed the file")
+	}
+	title := "A longer title than before"
+	if err := UpdateFLACTagsWithOptions(f, map[string]*string{"TITLE": &title}, opts); err != ErrWouldShift {
+		t.Errorf("UpdateFLACTagsWithOptions() = %v, expected ErrWouldShift", err)
+	}
+
+	// The option only applies to the call it is passed to.
+	if err := WriteFLACTags(f, data); err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+	testValue(t, data["TITLE"], testReadFLAC(t, f).Title())
+
+	// Padding absorbs the change in size.
+	f = tempFile(t, testFLAC(
+		testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, map[string]string{"TITLE": "Title"})),
+		testFLACBlock(paddingBlock, true, make([]byte, 64)),
+	))
+	if err := WriteFLACTagsWithOptions(f, data, opts); err != nil {
+		t.Fatalf("WriteFLACTagsWithOptions() = %v", err)
+	}
+	testValue(t, data["TITLE"], testReadFLAC(t, f).Title())
+
+	// Removing a picture always moves the audio data.
+	orig = testFLAC(
+		testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, nil)),
+		testFLACBlock(pictureBlock, true, testFLACPictureData("image/png", []byte{1, 2, 3})),
+	)
+	f = tempFile(t, orig)
+	if _, err := RemovePicturesWithOptions(f, opts); err != ErrWouldShift {
+		t.Errorf("RemovePicturesWithOptions() = %v, expected ErrWouldShift", err)
+	}
+	if !bytes.Equal(orig, readAll(t, f)) {
+		t.Errorf("RemovePicturesWithOptions() changed the file")
+	}
+}
+
+func TestBuildFLACPictureBlockMIME(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0 JFIF data")
+	png := append(append([]byte{}, pngHeader...), "data"...)
+
+	tests := []struct {
+		mime string
+		data []byte
+		want string
+		err  error
+	}{
+		{"image/jpeg", jpeg, "image/jpeg", nil},
+		{"image/jpg", jpeg, "image/jpg", nil},
+		{"image/png", png, "image/png", nil},
+		{"", png, "image/png", nil},                       // determined from the data
+		{"image/webp", []byte("RIFF"), "image/webp", nil}, // unrecognised data
+		{"image/png", jpeg, "", ErrMIMEMismatch},
+		{"image/jpeg", png, "", ErrMIMEMismatch},
+	}
+
+	for _, tt := range tests {
+		b, err := buildFLACPictureBlock(&Picture{MIMEType: tt.mime, Data: tt.data})
+		if err != tt.err {
+			t.Errorf("buildFLACPictureBlock(%q) = %v, expected %v", tt.mime, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		m := newMetadataVorbis()
+		if err := m.readPictureBlock(bytes.NewReader(b), false); err != nil {
+			t.Fatalf("readPictureBlock() = %v", err)
+		}
+		testValue(t, tt.want, m.Picture().MIMEType)
+	}
+}
+
+func TestFLACPaddingIsClean(t *testing.T) {
+	dirty := bytes.Repeat([]byte{0xaa}, 64)
+	comment := testVorbisComment(t, map[string]string{"TITLE": "Title"})
+	f := tempFile(t, testFLAC(
+		testFLACBlock(vorbisCommentBlock, false, comment),
+		testFLACBlock(paddingBlock, true, dirty),
+	))
+	clean, err := FLACPaddingIsClean(f)
+	if err != nil {
+		t.Fatalf("FLACPaddingIsClean() = %v", err)
+	}
+	testValue(t, false, clean)
+
+	// A comment of the same length would otherwise be written in place.
+	if err := WriteFLACTags(f, map[string]string{"TITLE": "Other"}); err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+	clean, err = FLACPaddingIsClean(f)
+	if err != nil {
+		t.Fatalf("FLACPaddingIsClean() = %v", err)
+	}
+	testValue(t, true, clean)
+	testValue(t, "Other", testReadFLAC(t, f).Title())
+}
+
+func TestWriteFLACTagsWithPadding(t *testing.T) {
+	want, err := SumFLAC(tempCopy(t, "with_tags/sample.flac"))
+	if err != nil {
+		t.Fatalf("SumFLAC() = %v", err)
+	}
+
+	for _, n := range []int{0, 10, 8192} {
+		f := tempCopy(t, "with_tags/sample.flac")
+		if err := WriteFLACTagsWithPadding(f, map[string]string{"TITLE": "Title"}, n); err != nil {
+			t.Fatalf("WriteFLACTagsWithPadding(%d) = %v", n, err)
+		}
+
+		blocks, _, err := readFLACBlocks(f)
+		if err != nil {
+			t.Fatalf("readFLACBlocks() = %v", err)
+		}
+		var padding []int
+		for _, b := range blocks {
+			if b.Type == paddingBlock {
+				padding = append(padding, len(b.Data))
+			}
+		}
+		switch {
+		case n == 0 && len(padding) != 0:
+			t.Errorf("padding = %v, expected none", padding)
+		case n > 0 && (len(padding) != 1 || padding[0] != n || blocks[len(blocks)-1].Type != paddingBlock):
+			t.Errorf("padding = %v, expected a trailing PADDING block of %d bytes", padding, n)
+		}
+		testValue(t, "Title", testReadFLAC(t, f).Title())
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := SumFLAC(f); err != nil || got != want {
+			t.Errorf("SumFLAC() = %v, %v, expected %v (audio data changed)", got, err, want)
+		}
+	}
+
+	if err := WriteFLACTagsWithPadding(tempCopy(t, "with_tags/sample.flac"), nil, -1); err == nil {
+		t.Errorf("WriteFLACTagsWithPadding(-1) = nil, expected error")
+	}
+}
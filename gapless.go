@@ -0,0 +1,48 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// GaplessInfo is the information required for gapless playback: the number of samples
+// to skip at the start and end of the decoded audio.
+type GaplessInfo struct {
+	EncoderDelay   int // Samples added by the encoder at the start of the audio.
+	EncoderPadding int // Samples added by the encoder at the end of the audio.
+}
+
+// parseITunSMPB parses the iTunSMPB value written by iTunes, which is a list of hexadecimal
+// fields: reserved, encoder delay, encoder padding, original sample count, ...
+func parseITunSMPB(s string) (GaplessInfo, bool) {
+	f := strings.Fields(s)
+	if len(f) < 3 {
+		return GaplessInfo{}, false
+	}
+
+	delay, err := strconv.ParseUint(f[1], 16, 32)
+	if err != nil {
+		return GaplessInfo{}, false
+	}
+	padding, err := strconv.ParseUint(f[2], 16, 32)
+	if err != nil {
+		return GaplessInfo{}, false
+	}
+	return GaplessInfo{EncoderDelay: int(delay), EncoderPadding: int(padding)}, true
+}
+
+// lameGapless returns the encoder delay and padding from the LAME extension of a Xing header.
+func lameGapless(lame []byte) (GaplessInfo, bool) {
+	if len(lame) < xingLAMESize {
+		return GaplessInfo{}, false
+	}
+	b := lame[21:24]
+	return GaplessInfo{
+		EncoderDelay:   int(b[0])<<4 | int(b[1])>>4,
+		EncoderPadding: int(b[1]&0x0F)<<8 | int(b[2]),
+	}, true
+}
@@ -0,0 +1,110 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+const testITunSMPB = " 00000000 00000840 000001CA 00000000001CC9F6 00000000 00000000 00000000 00000000 00000000 00000000 00000000 00000000"
+
+// testLAMEFrame returns an MPEG 1 Layer III frame containing an Info header with a LAME
+// extension with the given encoder, quality, VBR method and encoder delay/padding.
+func testLAMEFrame(encoder string, quality int, vbr byte, delay, padding int) []byte {
+	b := []byte{0xFF, 0xFB, 0x90, 0x64}
+	b = append(b, make([]byte, 32)...) // side info
+	b = append(b, "Info"...)
+	b = append(b, 0, 0, 0, xingFrames|xingBytes|xingTOC|xingQuality)
+	b = append(b, 0, 0, 0x01, 0x00) // frames
+	b = append(b, 0, 0x01, 0, 0)    // bytes
+	b = append(b, make([]byte, 100)...)
+	b = append(b, 0, 0, 0, byte(quality))
+
+	lame := make([]byte, xingLAMESize)
+	copy(lame, encoder)
+	lame[9] = vbr
+	lame[21] = byte(delay >> 4)
+	lame[22] = byte(delay&0x0F)<<4 | byte(padding>>8)
+	lame[23] = byte(padding)
+	b = append(b, lame...)
+	return append(b, make([]byte, 417-len(b))...)
+}
+
+// testOpus returns an Ogg Opus stream with the given pre-skip, containing only the header
+// packets.
+func testOpus(t *testing.T, preSkip int) []byte {
+	head := append([]byte{}, opusHeadPrefix...)
+	head = append(head, 1, 2, byte(preSkip), byte(preSkip>>8))
+	head = append(head, 0x80, 0xBB, 0, 0, 0, 0, 0) // 48kHz, no gain, mapping family 0
+	tags := append(append([]byte{}, opusTagsPrefix...), testVorbisComment(t, map[string]string{"TITLE": "Title"})...)
+
+	var b []byte
+	for i, p := range [][]byte{head, tags} {
+		for _, page := range oggPaginate([][]byte{p}, 1, uint32(i), 0) {
+			b = append(b, page.bytes()...)
+		}
+	}
+	return b
+}
+
+func TestParseITunSMPB(t *testing.T) {
+	tests := []struct {
+		input string
+		g     GaplessInfo
+		ok    bool
+	}{
+		{testITunSMPB, GaplessInfo{2112, 458}, true},
+		{"00000000 00000000 00000000", GaplessInfo{}, true},
+		{"00000000 00000840", GaplessInfo{}, false},
+		{"00000000 XYZ 000001CA", GaplessInfo{}, false},
+		{"", GaplessInfo{}, false},
+	}
+
+	for ii, tt := range tests {
+		g, ok := parseITunSMPB(tt.input)
+		if g != tt.g || ok != tt.ok {
+			t.Errorf("[%d] parseITunSMPB(%q) = %v, %v, expected: %v, %v", ii, tt.input, g, ok, tt.g, tt.ok)
+		}
+	}
+}
+
+func TestGapless(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comm := append([]byte("\x00engiTunSMPB\x00"), testITunSMPB...)
+	m4a := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, m4a, mp4FreeformItem("com.apple.iTunes", "iTunSMPB", testITunSMPB))
+
+	tests := []struct {
+		r  io.ReadSeeker
+		g  GaplessInfo
+		ok bool
+	}{
+		{bytes.NewReader(append(testID3v2Tag(id3v2RawFrame{Name: "COMM", Data: comm}), audio...)), GaplessInfo{2112, 458}, true},
+		{bytes.NewReader(append(testID3v2Tag(), append(testLAMEFrame("LAME3.100", 57, 0x24, 576, 1000), audio...)...)), GaplessInfo{576, 1000}, true},
+		{bytes.NewReader(append(testID3v2Tag(), audio...)), GaplessInfo{}, false},
+		{m4a, GaplessInfo{2112, 458}, true},
+		{bytes.NewReader(testOpus(t, 312)), GaplessInfo{EncoderDelay: 312}, true},
+		{tempCopy(t, "with_tags/sample.ogg"), GaplessInfo{}, false},
+	}
+
+	for ii, tt := range tests {
+		tt.r.Seek(0, io.SeekStart)
+		m, err := ReadFrom(tt.r)
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", ii, err)
+			continue
+		}
+		g, ok := m.Gapless()
+		if g != tt.g || ok != tt.ok {
+			t.Errorf("[%d] Gapless() = %v, %v, expected: %v, %v", ii, g, ok, tt.g, tt.ok)
+		}
+	}
+}
@@ -5,14 +5,22 @@ import (
 	"io"
 )
 
-// Identify identifies the format and file type of the data in the ReadSeeker.
-func Identify(r io.ReadSeeker) (format Format, fileType FileType, err error) {
-	b, err := readBytes(r, 11)
+// sniffPrefixSize is the number of bytes read by sniffFormat.
+const sniffPrefixSize = 11
+
+// sniffFormat identifies the file type of the data in r from its first few bytes, and restores
+// the position of r.  The format is also returned where it can be determined from these bytes
+// (the ID3v2 version, MP4 or VORBIS), otherwise it is UnknownFormat.  MP4 data with an
+// unrecognised brand has format MP4 and file type UnknownFileType, and MP3 data without an
+// ID3v2 tag (detected by MPEG frame sync) has file type MP3 and format UnknownFormat.
+// Returns UnknownFileType (and a nil error) if the data is not recognised.
+func sniffFormat(r io.ReadSeeker) (format Format, fileType FileType, err error) {
+	b, err := readBytes(r, sniffPrefixSize)
 	if err != nil {
 		return
 	}
 
-	_, err = r.Seek(-11, io.SeekCurrent)
+	_, err = r.Seek(-sniffPrefixSize, io.SeekCurrent)
 	if err != nil {
 		err = fmt.Errorf("could not seek back to original position: %v", err)
 		return
@@ -26,9 +34,7 @@ func Identify(r io.ReadSeeker) (format Format, fileType FileType, err error) {
 		return VORBIS, OGG, nil
 
 	case string(b[4:8]) == "ftyp":
-		b = b[8:11]
-		fileType = UnknownFileType
-		switch string(b) {
+		switch string(b[8:11]) {
 		case "M4A":
 			fileType = M4A
 
@@ -41,21 +47,43 @@ func Identify(r io.ReadSeeker) (format Format, fileType FileType, err error) {
 		return MP4, fileType, nil
 
 	case string(b[0:3]) == "ID3":
-		b := b[3:]
-		switch uint(b[0]) {
+		switch uint(b[3]) {
 		case 2:
 			format = ID3v2_2
 		case 3:
 			format = ID3v2_3
 		case 4:
 			format = ID3v2_4
-		case 0, 1:
-			fallthrough
 		default:
-			err = fmt.Errorf("ID3 version: %v, expected: 2, 3 or 4", uint(b[0]))
+			err = fmt.Errorf("ID3 version: %v, expected: 2, 3 or 4", uint(b[3]))
 			return
 		}
 		return format, MP3, nil
+
+	case string(b[0:4]) == "DSD ":
+		return UnknownFormat, DSF, nil
+
+	case string(b[0:4]) == "FORM" && string(b[8:11]) == "AIF":
+		return UnknownFormat, AIFF, nil
+
+	case string(b[0:4]) == "FRM8":
+		return UnknownFormat, DFF, nil
+
+	case mpegFrameHeader(b).valid():
+		return UnknownFormat, MP3, nil
+	}
+	return UnknownFormat, UnknownFileType, nil
+}
+
+// Identify identifies the format and file type of the data in the ReadSeeker.
+func Identify(r io.ReadSeeker) (format Format, fileType FileType, err error) {
+	format, fileType, err = sniffFormat(r)
+	if err != nil || format != UnknownFormat {
+		return
+	}
+	switch fileType {
+	case DSF, AIFF, DFF:
+		return
 	}
 
 	n, err := r.Seek(-128, io.SeekEnd)
@@ -9,6 +9,7 @@ import (
 	"io"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // id3v1Genres is a list of genres as given in the ID3v1 specification.
@@ -82,10 +83,10 @@ func ReadID3v1Tags(r io.ReadSeeker) (Metadata, error) {
 	var comment string
 	var track int
 	if commentBytes[28] == 0 {
-		comment = trimString(string(commentBytes[:28]))
+		comment = trimID3v1Field(string(commentBytes[:28]))
 		track = int(commentBytes[29])
 	} else {
-		comment = trimString(string(commentBytes))
+		comment = trimID3v1Field(string(commentBytes))
 	}
 
 	var genre string
@@ -98,11 +99,11 @@ func ReadID3v1Tags(r io.ReadSeeker) (Metadata, error) {
 	}
 
 	m := make(map[string]interface{})
-	m["title"] = trimString(title)
-	m["artist"] = trimString(artist)
-	m["album"] = trimString(album)
-	m["year"] = trimString(year)
-	m["comment"] = trimString(comment)
+	m["title"] = trimID3v1Field(title)
+	m["artist"] = trimID3v1Field(artist)
+	m["album"] = trimID3v1Field(album)
+	m["year"] = trimID3v1Field(year)
+	m["comment"] = comment
 	m["track"] = track
 	m["genre"] = genre
 
@@ -113,6 +114,13 @@ func trimString(x string) string {
 	return strings.TrimSpace(strings.Trim(x, "\x00"))
 }
 
+// trimID3v1Field returns the ID3v1 field x without its padding.  Fields should be padded
+// with NULs, but some taggers pad with spaces (or a mixture of both).
+func trimID3v1Field(x string) string {
+	x = strings.TrimRightFunc(x, func(r rune) bool { return r == 0 || unicode.IsSpace(r) })
+	return strings.TrimLeftFunc(x, unicode.IsSpace)
+}
+
 // metadataID3v1 is the implementation of Metadata used for ID3v1 tags.
 type metadataID3v1 map[string]interface{}
 
@@ -139,6 +147,30 @@ func (m metadataID3v1) Track() (int, int) { return m["track"].(int), 0 }
 func (m metadataID3v1) AlbumArtist() string { return "" }
 func (m metadataID3v1) Composer() string    { return "" }
 func (metadataID3v1) Disc() (int, int)      { return 0, 0 }
+func (metadataID3v1) Subtitle() string      { return "" }
+func (metadataID3v1) DiscSubtitle() string  { return "" }
 func (m metadataID3v1) Picture() *Picture   { return nil }
+func (metadataID3v1) Pictures() []*Picture  { return nil }
 func (m metadataID3v1) Lyrics() string      { return "" }
 func (m metadataID3v1) Comment() string     { return m["comment"].(string) }
+func (m metadataID3v1) MediaType() string   { return "" }
+func (metadataID3v1) Mood() string          { return "" }
+func (metadataID3v1) Rating() int           { return 0 }
+func (metadataID3v1) ContentGroup() string  { return "" }
+func (metadataID3v1) Work() string          { return "" }
+
+func (metadataID3v1) ClassicalInfo() (ClassicalInfo, bool) { return ClassicalInfo{}, false }
+
+func (metadataID3v1) Bitrate() (int, bool)         { return 0, false }
+func (metadataID3v1) Gapless() (GaplessInfo, bool) { return GaplessInfo{}, false }
+
+func (metadataID3v1) Conductor() string        { return "" }
+func (metadataID3v1) Remixer() string          { return "" }
+func (metadataID3v1) InvolvedPeople() []Credit { return nil }
+
+func (metadataID3v1) Copyright() string { return "" }
+func (metadataID3v1) Publisher() string { return "" }
+func (metadataID3v1) Owner() string     { return "" }
+
+func (metadataID3v1) PodcastInfo() (PodcastInfo, bool) { return PodcastInfo{}, false }
+func (metadataID3v1) ITunesTags() ITunesInfo           { return ITunesInfo{} }
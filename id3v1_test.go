@@ -39,3 +39,29 @@ func doTest(name string, track int, length int, t *testing.T) {
 		t.Errorf("Comment length for %s is %d where %d is expected", name, actual, length)
 	}
 }
+
+func TestReadID3v1TagsPadding(t *testing.T) {
+	field := func(s string, n int) []byte {
+		return append([]byte(s), make([]byte, n-len(s))...)
+	}
+	b := []byte("TAG")
+	b = append(b, field("The Wizard\x00\x00 \x00", 30)...)
+	b = append(b, field("Black Sabbath   ", 30)...)
+	b = append(b, field(" Black Sabbath \x00 \x00", 30)...)
+	b = append(b, field("1970", 4)...)
+	b = append(b, field("A comment \x00", 28)...)
+	b = append(b, 0, 2, 9) // track, genre
+
+	m, err := ReadID3v1Tags(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadID3v1Tags() = %v", err)
+	}
+	testValue(t, "The Wizard", m.Title())
+	testValue(t, "Black Sabbath", m.Artist())
+	testValue(t, "Black Sabbath", m.Album())
+	testValue(t, 1970, m.Year())
+	testValue(t, "A comment", m.Comment())
+	track, _ := m.Track()
+	testValue(t, 2, track)
+	testValue(t, "Metal", m.Genre())
+}
@@ -0,0 +1,108 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// id3v1Size is the size of an ID3v1 tag.
+const id3v1Size = 128
+
+// hasID3v1Tag returns true if there is an ID3v1 tag at the end of r.
+func hasID3v1Tag(r io.ReadSeeker) (bool, error) {
+	n, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, err
+	}
+	if n < id3v1Size {
+		return false, nil
+	}
+
+	if _, err := r.Seek(-id3v1Size, io.SeekEnd); err != nil {
+		return false, err
+	}
+	b, err := readBytes(r, 3)
+	if err != nil {
+		return false, err
+	}
+	return string(b) == "TAG", nil
+}
+
+// id3v1Tag returns an ID3v1.1 tag representing data, which must already be normalised.
+// Values which don't fit in the fixed-size fields are truncated, and characters which
+// can't be represented in ISO-8859-1 are replaced with '?'.
+func id3v1Tag(data map[string]string) []byte {
+	b := make([]byte, id3v1Size)
+	copy(b[0:3], "TAG")
+	copy(b[3:33], encodeISO8859(data[FieldTitle]))
+	copy(b[33:63], encodeISO8859(data[FieldArtist]))
+	copy(b[63:93], encodeISO8859(data[FieldAlbum]))
+	copy(b[93:97], fieldYear(data))
+
+	comment := encodeISO8859(data[FieldComment])
+	track, _ := parseXofN(data[FieldTrackNumber])
+	if track > 0 && track < 256 {
+		copy(b[97:125], comment)
+		b[126] = byte(track)
+	} else {
+		copy(b[97:127], comment)
+	}
+
+	b[127] = 0xFF // no genre
+	if g := strings.TrimSpace(data[FieldGenre]); g != "" {
+		for i, x := range id3v1Genres {
+			if strings.EqualFold(x, g) {
+				b[127] = byte(i)
+				break
+			}
+		}
+		if n, err := strconv.Atoi(g); err == nil && n >= 0 && n < len(id3v1Genres) {
+			b[127] = byte(n)
+		}
+	}
+	return b
+}
+
+// id3v1Fields returns the fields of m which can be stored in an ID3v1 tag (see id3v1Tag).
+func id3v1Fields(m Metadata) map[string]string {
+	data := map[string]string{
+		FieldTitle:   m.Title(),
+		FieldArtist:  m.Artist(),
+		FieldAlbum:   m.Album(),
+		FieldComment: m.Comment(),
+		FieldGenre:   m.Genre(),
+	}
+	if y := m.Year(); y > 0 {
+		data[FieldYear] = strconv.Itoa(y)
+	}
+	if x, _ := m.Track(); x > 0 {
+		data[FieldTrackNumber] = strconv.Itoa(x)
+	}
+	return data
+}
+
+// writeID3v1Tag writes an ID3v1.1 tag representing data (which must already be normalised)
+// to the end of rw, replacing any existing ID3v1 tag.
+func writeID3v1Tag(rw io.ReadWriteSeeker, data map[string]string) error {
+	ok, err := hasID3v1Tag(rw)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		_, err = rw.Seek(-id3v1Size, io.SeekEnd)
+	} else {
+		_, err = rw.Seek(0, io.SeekEnd)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = rw.Write(id3v1Tag(data))
+	return err
+}
@@ -5,7 +5,8 @@
 package tag
 
 import (
-	"errors"
+	"bytes"
+	"compress/zlib"
 	"fmt"
 	"io"
 	"regexp"
@@ -148,6 +149,9 @@ type id3v2FrameFlags struct {
 	// ID3 2.4.0 only (see http://id3.org/id3v2.4.0-structure sec 4.1)
 	Unsynchronisation   bool
 	DataLengthIndicator bool
+
+	// Undefined flags which are set.
+	Unknown bool
 }
 
 func readID3v23FrameFlags(r io.Reader) (*id3v2FrameFlags, error) {
@@ -166,6 +170,7 @@ func readID3v23FrameFlags(r io.Reader) (*id3v2FrameFlags, error) {
 		Compression:           getBit(fmt, 7),
 		Encryption:            getBit(fmt, 6),
 		GroupIdentity:         getBit(fmt, 5),
+		Unknown:               msg&0x1F != 0 || fmt&0x1F != 0,
 	}, nil
 }
 
@@ -187,6 +192,7 @@ func readID3v24FrameFlags(r io.Reader) (*id3v2FrameFlags, error) {
 		Encryption:            getBit(fmt, 2),
 		Unsynchronisation:     getBit(fmt, 1),
 		DataLengthIndicator:   getBit(fmt, 0),
+		Unknown:               msg&0x8F != 0 || fmt&0xB0 != 0,
 	}, nil
 
 }
@@ -230,11 +236,19 @@ func readID3v2_4FrameHeader(r io.Reader) (name string, size uint, headerSize uin
 	return
 }
 
-// readID3v2Frames reads ID3v2 frames from the given reader using the ID3v2Header.
-func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]interface{}, error) {
+// readID3v2Frames reads ID3v2 frames from the given reader using the ID3v2Header, adding
+// any non-fatal problems to w.
+func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header, w *warnings, opts ReadOptions) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 
-	for offset < h.Size {
+	// offset includes the 10 byte tag header, which is not included in the tag size.
+	end := h.Size + 10
+	frameHeaderSize := uint(10)
+	if h.Version == ID3v2_2 {
+		frameHeaderSize = 6
+	}
+
+	for offset+frameHeaderSize <= end {
 		var err error
 		var name string
 		var size, headerSize uint
@@ -272,57 +286,102 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 			break
 		}
 
-		offset += headerSize + size
-
-		// Avoid corrupted padding (see http://id3.org/Compliance%20Issues).
-		if !validID3Frame(h.Version, name) && offset > h.Size {
+		// Stop at the first invalid frame rather than reading past the end of the tag, which
+		// would otherwise treat audio data as frames.
+		if !validID3FrameName(name) {
+			w.add(h.Version, "invalid frame name %q at offset %d, ignoring rest of tag", name, offset)
+			break
+		}
+		if size > end-offset-headerSize {
+			if validID3Frame(h.Version, name) {
+				w.add(h.Version, "frame %q size %d exceeds tag size, ignoring rest of tag", name, size)
+			}
 			break
 		}
 
+		offset += headerSize + size
+
 		if flags != nil {
-			if flags.Compression {
-				switch h.Version {
-				case ID3v2_3:
-					// No data length indicator defined.
-					if _, err := read7BitChunkedUint(r, 4); err != nil { // read 4
-						return nil, err
-					}
-					size -= 4
+			if flags.Unknown {
+				w.add(h.Version, "frame %q has unknown flags set", name)
+			}
 
-				case ID3v2_4:
-					// Must have a data length indicator (to give the size) if compression is enabled.
-					if !flags.DataLengthIndicator {
-						return nil, errors.New("compression without data length indicator")
-					}
+			// Data added to the frame header by the flags, in the order given by the
+			// specification of each version.
+			var extra []uint
+			switch h.Version {
+			case ID3v2_3:
+				if flags.Compression {
+					extra = append(extra, 4) // decompressed size
+				}
+				if flags.Encryption {
+					extra = append(extra, 1) // encryption method
+				}
+				if flags.GroupIdentity {
+					extra = append(extra, 1) // group identifier
+				}
 
-				default:
-					return nil, fmt.Errorf("unsupported compression flag used in %v", h.Version)
+			case ID3v2_4:
+				if flags.GroupIdentity {
+					extra = append(extra, 1) // group identifier
+				}
+				if flags.Encryption {
+					extra = append(extra, 1) // encryption method
+				}
+				if flags.DataLengthIndicator {
+					extra = append(extra, 4) // data length indicator
 				}
 			}
 
-			if flags.DataLengthIndicator {
-				if h.Version == ID3v2_3 {
-					return nil, fmt.Errorf("data length indicator set but not defined for %v", ID3v2_3)
+			for _, n := range extra {
+				if size < n {
+					return nil, fmt.Errorf("invalid %q frame: size %d too small for flags", name, size)
 				}
-
-				size, err = read7BitChunkedUint(r, 4)
-				if err != nil { // read 4
+				if _, err := readBytes(r, n); err != nil {
 					return nil, err
 				}
+				size -= n
 			}
+		}
 
-			if flags.Encryption {
-				_, err = readBytes(r, 1) // read 1 byte of encryption method
-				if err != nil {
-					return nil, err
-				}
-				size--
+		if (name == "APIC" || name == "PIC") && pictureTooLarge(int64(size)) {
+			if _, err := io.CopyN(io.Discard, r, int64(size)); err != nil {
+				return nil, err
 			}
+			w.add(h.Version, "skipped %q frame: %v", name, errPictureTooLarge)
+			continue
 		}
 
-		b, err := readBytes(r, size)
-		if err != nil {
-			return nil, err
+		// The picture data is read on demand if possible (see ReadOptions).
+		var pic *Picture
+		if rs, ok := r.(io.ReadSeeker); ok && opts.LazyPictures && (name == "APIC" || name == "PIC") &&
+			(flags == nil || !flags.Compression && !flags.Encryption && !flags.Unsynchronisation) {
+			if pic, err = readLazyPictureFrame(rs, name, size); err != nil {
+				return nil, err
+			}
+		}
+
+		var b []byte
+		if pic == nil {
+			if b, err = readBytes(r, size); err != nil {
+				return nil, err
+			}
+		}
+
+		encrypted := flags != nil && flags.Encryption
+		if encrypted {
+			w.add(h.Version, "frame %q is encrypted, storing raw data", name)
+		} else if flags != nil && flags.Compression {
+			b, err = inflateFrame(b)
+			if err != nil {
+				w.add(h.Version, "skipped compressed frame %q: %v", name, err)
+				continue
+			}
+		}
+
+		// Frames which are decoded as text start with the text encoding.
+		if !encrypted && len(b) > 0 && b[0] > encodingUTF8 && hasID3v2TextEncoding(name) {
+			w.add(h.Version, "frame %q has unknown text encoding: %d", name, b[0])
 		}
 
 		// There can be multiple tag with the same name. Append a number to the
@@ -336,6 +395,19 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 		}
 
 		switch {
+		case pic != nil:
+			result[rawName] = pic
+
+		case encrypted:
+			result[rawName] = b
+
+		case name == "IPLS" || name == "IPL" || name == "TIPL":
+			c, err := readCreditsFrame(b)
+			if err != nil {
+				return nil, err
+			}
+			result[rawName] = c
+
 		case name == "TXXX" || name == "TXX":
 			t, err := readTextWithDescrFrame(b, false, true) // no lang, but enc
 			if err != nil {
@@ -343,13 +415,21 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 			}
 			result[rawName] = t
 
-		case name[0] == 'T':
+		case name[0] == 'T', name == "GRP1" || name == "GP1", // iTunes grouping and movement are text frames
+			name == "MVNM" || name == "MVN", name == "MVIN" || name == "MVI":
 			txt, err := readTFrame(b)
 			if err != nil {
 				return nil, err
 			}
 			result[rawName] = txt
 
+		case name == "POPM" || name == "POP":
+			p, err := readPOPMFrame(b)
+			if err != nil {
+				return nil, err
+			}
+			result[rawName] = p
+
 		case name == "UFID" || name == "UFI":
 			t, err := readUFID(b)
 			if err != nil {
@@ -399,11 +479,31 @@ func readID3v2Frames(r io.Reader, offset uint, h *id3v2Header) (map[string]inter
 	return result, nil
 }
 
+// hasID3v2TextEncoding returns true if frames with the given name begin with a text
+// encoding byte.
+func hasID3v2TextEncoding(name string) bool {
+	switch name {
+	case "COMM", "COM", "USLT", "ULT", "APIC", "PIC", "GRP1", "GP1":
+		return true
+	}
+	return name[0] == 'T'
+}
+
 type unsynchroniser struct {
 	io.Reader
 	ff bool
 }
 
+// inflateFrame returns the decompressed content of a compressed (zlib) frame.
+func inflateFrame(b []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
 // filter io.Reader which skip the Unsynchronisation bytes
 func (r *unsynchroniser) Read(p []byte) (int, error) {
 	b := make([]byte, 1)
@@ -423,24 +523,72 @@ func (r *unsynchroniser) Read(p []byte) (int, error) {
 	return i, nil
 }
 
+// removeUnsynchronisation returns b with the unsynchronisation scheme reversed.
+func removeUnsynchronisation(b []byte) []byte {
+	out, _ := io.ReadAll(&unsynchroniser{Reader: bytes.NewReader(b)})
+	return out
+}
+
 // ReadID3v2Tags parses ID3v2.{2,3,4} tags from the io.ReadSeeker into a Metadata, returning
 // non-nil error on failure.
 func ReadID3v2Tags(r io.ReadSeeker) (Metadata, error) {
+	return readID3v2Tags(r, nil, ReadOptions{})
+}
+
+// readID3v2Tags implements ReadID3v2Tags, adding any non-fatal problems to w.
+func readID3v2Tags(r io.ReadSeeker, w *warnings, opts ReadOptions) (Metadata, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+
 	h, offset, err := readID3v2Header(r)
 	if err != nil {
 		return nil, err
 	}
 
+	// The tag size must not exceed the data available, otherwise frames would be read
+	// from the audio data.
+	pos, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(pos, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if avail := end - start - 10; int64(h.Size) > avail {
+		w.add(h.Version, "tag size %d exceeds remaining data size %d", h.Size, avail)
+		h.Size = uint(avail)
+	}
+
 	var ur io.Reader = r
 	if h.Unsynchronisation {
 		ur = &unsynchroniser{Reader: r}
 	}
 
-	f, err := readID3v2Frames(ur, offset, h)
+	f, err := readID3v2Frames(ur, offset, h, w, opts)
 	if err != nil {
 		return nil, err
 	}
-	return metadataID3v2{header: h, frames: f}, nil
+
+	m := metadataID3v2{header: h, frames: f}
+
+	// The first audio frame may contain a Xing header (with encoder information), this
+	// is optional so errors are ignored.
+	audio := start + 10 + int64(h.Size)
+	if _, err := r.Seek(audio, io.SeekStart); err == nil {
+		m.xing, _ = readXingHeader(r)
+	}
+	if _, err := r.Seek(audio, io.SeekStart); err == nil {
+		if b, err := readBytes(r, 4); err == nil && mpegFrameHeader(b).valid() {
+			m.mpeg = b
+		}
+	}
+	return m, nil
 }
 
 var id3v2genreRe = regexp.MustCompile(`(.*[^(]|.* |^)\(([0-9]+)\) *(.*)$`)
@@ -6,8 +6,14 @@ package tag
 
 import (
 	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"image/png"
 	"reflect"
 	"testing"
+	"unicode/utf16"
 )
 
 func TestUnsynchroniser(t *testing.T) {
@@ -153,3 +159,297 @@ func TestGenreExpension(t *testing.T) {
 		}
 	}
 }
+
+func TestReadID3v2TagsBogusFrameSize(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xFF, 0xFB, 0x90, 0x00}, 64)
+
+	// A frame declaring a size which runs past the end of the tag into the audio data.
+	b := testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")},
+		id3v2RawFrame{Name: "TPE1", Data: []byte("\x00Artist")},
+	)
+	copy(b[10+10+6+4:], []byte{0x7F, 0xFF, 0xFF, 0xFF}) // TPE1 size
+	b = append(b, audio...)
+
+	m, warnings, err := ReadFromStrict(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFromStrict() = %v", err)
+	}
+	testValue(t, "Title", m.Title())
+	testValue(t, "", m.Artist())
+	if len(warnings) == 0 {
+		t.Errorf("expected warning for bogus frame size")
+	}
+
+	// A tag size which exceeds the size of the data.
+	b = testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")})
+	copy(b[6:10], format7BitChunkedUint(1<<27, 4))
+
+	m, warnings, err = ReadFromStrict(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFromStrict() = %v", err)
+	}
+	testValue(t, "Title", m.Title())
+	if len(warnings) == 0 {
+		t.Errorf("expected warning for bogus tag size")
+	}
+}
+
+func FuzzReadID3v2Tags(f *testing.F) {
+	f.Add(testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")}))
+	f.Add((&id3v2RawTag{Version: 2, Frames: []id3v2RawFrame{{Name: "TT2", Data: []byte("\x00Title")}}}).bytes(0))
+	f.Add((&id3v2RawTag{Version: 4, Frames: []id3v2RawFrame{{Name: "TIT2", Data: []byte("\x03Title")}}}).bytes(8))
+	f.Add([]byte("ID3\x03\x00\x00\x7F\x7F\x7F\x7FTIT2\xFF\xFF\xFF\xFF\x00\x00"))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		ReadID3v2Tags(bytes.NewReader(b))
+	})
+}
+
+func TestPictureExtension(t *testing.T) {
+	tests := []struct {
+		p    Picture
+		want string
+	}{
+		{Picture{MIMEType: "image/jpeg"}, ".jpg"},
+		{Picture{MIMEType: "image/PNG"}, ".png"},
+		{Picture{Ext: "GIF"}, ".gif"},
+		{Picture{Data: []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")}, ".jpg"},
+		{Picture{Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")}, ".png"},
+		{Picture{Data: []byte("GIF89a")}, ".gif"},
+		{Picture{MIMEType: "-->", Data: []byte("\x89PNG\r\n\x1a\n")}, ".png"},
+		{Picture{Data: []byte{1, 2, 3}}, ""},
+	}
+
+	for ii, tt := range tests {
+		if got := tt.p.Extension(); got != tt.want {
+			t.Errorf("[%d] Extension() = %q, expected %q", ii, got, tt.want)
+		}
+	}
+}
+
+func testImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "png":
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPictureDimensions(t *testing.T) {
+	for _, format := range []string{"jpeg", "png"} {
+		data := testImage(t, format, 33, 17)
+
+		b := append([]byte("\x00image/"+format+"\x00\x03cover\x00"), data...)
+		p, err := readAPICFrame(b)
+		if err != nil {
+			t.Fatalf("%v: readAPICFrame() = %v", format, err)
+		}
+		if p.Width != 33 || p.Height != 17 {
+			t.Errorf("%v: dimensions = %dx%d, expected 33x17", format, p.Width, p.Height)
+		}
+	}
+
+	if w, h := imageSize([]byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00}); w != 0 || h != 0 {
+		t.Errorf("imageSize() = %dx%d for truncated JPEG, expected 0x0", w, h)
+	}
+}
+
+func testZlib(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadID3v2CompressedFrames(t *testing.T) {
+	talb := []byte("\x00Compressed Album")
+	z := testZlib(t, talb)
+
+	v23 := append([]byte{0, 0, 0, byte(len(talb))}, z...) // decompressed size
+	v24 := append(format7BitChunkedUint(uint(len(talb)), 4), z...)
+
+	tests := []*id3v2RawTag{
+		{Version: 3, Frames: []id3v2RawFrame{
+			{Name: "TALB", Flags: [2]byte{0, 0x80}, Data: v23},
+			{Name: "TIT2", Data: []byte("\x00Title")},
+		}},
+		{Version: 4, Frames: []id3v2RawFrame{
+			{Name: "TALB", Flags: [2]byte{0, 0x09}, Data: v24}, // compression, data length indicator
+			{Name: "TIT2", Data: []byte("\x00Title")},
+		}},
+	}
+
+	for _, tt := range tests {
+		m, warnings, err := ReadFromStrict(bytes.NewReader(tt.bytes(0)))
+		if err != nil {
+			t.Fatalf("v2.%d: ReadFromStrict() = %v", tt.Version, err)
+		}
+		testValue(t, "Compressed Album", m.Album())
+		testValue(t, "Title", m.Title())
+		if len(warnings) != 0 {
+			t.Errorf("v2.%d: warnings = %v, expected none", tt.Version, warnings)
+		}
+	}
+}
+
+func TestReadID3v2EncryptedFrame(t *testing.T) {
+	tag := &id3v2RawTag{Version: 3, Frames: []id3v2RawFrame{
+		{Name: "TALB", Flags: [2]byte{0, 0x40}, Data: []byte{0x80, 1, 2, 3}}, // encryption method, data
+		{Name: "TIT2", Data: []byte("\x00Title")},
+	}}
+
+	m, warnings, err := ReadFromStrict(bytes.NewReader(tag.bytes(0)))
+	if err != nil {
+		t.Fatalf("ReadFromStrict() = %v", err)
+	}
+	testValue(t, "Title", m.Title())
+	testValue(t, "", m.Album())
+	if got, ok := m.Raw()["TALB"].([]byte); !ok || !bytes.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("Raw()[\"TALB\"] = %v, expected raw encrypted data", m.Raw()["TALB"])
+	}
+	if len(warnings) != 1 {
+		t.Errorf("warnings = %v, expected warning for encrypted frame", warnings)
+	}
+}
+
+func TestReadID3v2UTF16Comm(t *testing.T) {
+	utf16Bytes := func(s string, order binary.AppendByteOrder) []byte {
+		var b []byte
+		for _, x := range utf16.Encode([]rune(s)) {
+			b = order.AppendUint16(b, x)
+		}
+		return b
+	}
+	bom := []byte{0xFF, 0xFE}
+
+	// Descriptions where a character with a zero byte precedes the terminator (which must
+	// not be found at an odd offset).
+	comm := []byte{encodingUTF16WithBOM, 'e', 'n', 'g'}
+	comm = append(comm, bom...)
+	comm = append(comm, utf16Bytes("AĀ", binary.LittleEndian)...)
+	comm = append(comm, 0, 0)
+	comm = append(comm, bom...)
+	comm = append(comm, utf16Bytes("Ā comment", binary.LittleEndian)...)
+
+	uslt := []byte{encodingUTF16, 'd', 'e', 'u'}
+	uslt = append(uslt, utf16Bytes("ĀA", binary.BigEndian)...)
+	uslt = append(uslt, 0, 0)
+	uslt = append(uslt, utf16Bytes("Über\nZeilen", binary.BigEndian)...)
+	uslt = append(uslt, 0, 0)
+
+	tag := &id3v2RawTag{Version: 4, Frames: []id3v2RawFrame{
+		{Name: "COMM", Data: comm},
+		{Name: "USLT", Data: uslt},
+	}}
+	m, err := ReadID3v2Tags(bytes.NewReader(tag.bytes(0)))
+	if err != nil {
+		t.Fatalf("ReadID3v2Tags() = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		want Comm
+	}{
+		{"COMM", Comm{Language: "eng", Description: "AĀ", Text: "Ā comment"}},
+		{"USLT", Comm{Language: "deu", Description: "ĀA", Text: "Über\nZeilen"}},
+	}
+	for _, tt := range tests {
+		got, ok := m.Raw()[tt.name].(*Comm)
+		if !ok {
+			t.Errorf("%v frame not read", tt.name)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%v = %+v, expected %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestReadID3v2Rating(t *testing.T) {
+	popm := func(email string, rating byte) id3v2RawFrame {
+		return id3v2RawFrame{Name: "POPM", Data: append([]byte(email+"\x00"), rating, 0, 0, 0, 7)}
+	}
+
+	tests := []struct {
+		frames []id3v2RawFrame
+		stars  int
+	}{
+		{nil, 0},
+		{[]id3v2RawFrame{popm(popmWMPEmail, 1)}, 1},
+		{[]id3v2RawFrame{popm(popmWMPEmail, 64)}, 2},
+		{[]id3v2RawFrame{popm(popmWMPEmail, 128)}, 3},
+		{[]id3v2RawFrame{popm(popmWMPEmail, 196)}, 4},
+		{[]id3v2RawFrame{popm(popmWMPEmail, 255)}, 5},
+		{[]id3v2RawFrame{popm("rating@winamp.com", 128)}, 3},
+
+		// The rating written by Windows is preferred.
+		{[]id3v2RawFrame{popm("rating@winamp.com", 255), popm(popmWMPEmail, 64)}, 2},
+		{[]id3v2RawFrame{popm("rating@winamp.com", 0), popm("other@example.com", 196)}, 4},
+	}
+
+	for ii, tt := range tests {
+		m, err := ReadFrom(bytes.NewReader(testID3v2Tag(tt.frames...)))
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", ii, err)
+			continue
+		}
+		if got := m.Rating(); got != tt.stars {
+			t.Errorf("[%d] Rating() = %d, expected %d", ii, got, tt.stars)
+		}
+	}
+
+	m, err := ReadFrom(bytes.NewReader(testID3v2Tag(popm(popmWMPEmail, 196))))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	want := &Popularimeter{Email: popmWMPEmail, Rating: 196, Counter: 7}
+	if got := m.Raw()["POPM"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Raw()[\"POPM\"] = %v, expected %v", got, want)
+	}
+}
+
+func TestPictureAspectRatio(t *testing.T) {
+	tests := []struct {
+		width, height int
+		ratio         float64
+		square        bool
+	}{
+		{600, 600, 1, true},
+		{1000, 500, 2, false},
+	}
+
+	for _, tt := range tests {
+		data := testImage(t, "png", tt.width, tt.height)
+		p, err := readAPICFrame(append([]byte("\x00image/png\x00\x03\x00"), data...))
+		if err != nil {
+			t.Fatalf("readAPICFrame() = %v", err)
+		}
+		if got := p.AspectRatio(); got != tt.ratio {
+			t.Errorf("%dx%d: AspectRatio() = %v, expected %v", tt.width, tt.height, got, tt.ratio)
+		}
+		if got := p.IsSquare(); got != tt.square {
+			t.Errorf("%dx%d: IsSquare() = %v, expected %v", tt.width, tt.height, got, tt.square)
+		}
+	}
+
+	// The dimensions are not known.
+	p := Picture{}
+	testValue(t, 0.0, p.AspectRatio())
+	testValue(t, false, p.IsSquare())
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"unicode/utf16"
 )
@@ -295,12 +296,27 @@ func validID3Frame(version Format, name string) bool {
 	return ok
 }
 
+// validID3FrameName returns true if name consists of the characters allowed in a frame
+// identifier (A-Z and 0-9).
+func validID3FrameName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
 func readWFrame(b []byte) (string, error) {
 	// Frame text is always encoded in ISO-8859-1
 	b = append([]byte{0}, b...)
 	return readTFrame(b)
 }
 
+// readTFrame returns the text of a text frame.  ID3v2.4 text frames can contain more than one
+// value separated by null characters, which are kept between the values (see
+// metadataID3v2.values).  Empty values (i.e. a terminating null character) are removed.
 func readTFrame(b []byte) (string, error) {
 	if len(b) == 0 {
 		return "", nil
@@ -310,7 +326,13 @@ func readTFrame(b []byte) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return strings.Join(strings.Split(txt, string(singleZero)), ""), nil
+	var values []string
+	for _, v := range strings.Split(txt, string(singleZero)) {
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return strings.Join(values, string(singleZero)), nil
 }
 
 const (
@@ -349,18 +371,22 @@ func decodeText(enc byte, b []byte) (string, error) {
 	}
 }
 
-var (
-	singleZero = []byte{0}
-	doubleZero = []byte{0, 0}
-)
+var singleZero = []byte{0}
 
+// dataSplit splits b at the first null terminator for the text encoding enc.  In UTF-16 text
+// the terminator is a two byte null character, which must start at an even offset (otherwise
+// the zero high (or low) byte of a character followed by a terminator would be split early).
 func dataSplit(b []byte, enc byte) [][]byte {
-	delim := singleZero
 	if enc == encodingUTF16 || enc == encodingUTF16WithBOM {
-		delim = doubleZero
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return [][]byte{b[:i], b[i+2:]}
+			}
+		}
+		return [][]byte{b}
 	}
 
-	result := bytes.SplitN(b, delim, 2)
+	result := bytes.SplitN(b, singleZero, 2)
 	if len(result) != 2 {
 		return result
 	}
@@ -385,6 +411,19 @@ func decodeISO8859(b []byte) string {
 	return string(r)
 }
 
+// encodeISO8859 encodes s as ISO-8859-1, replacing characters which cannot be
+// represented with '?'.
+func encodeISO8859(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+	return b
+}
+
 func decodeUTF16WithBOM(b []byte) (string, error) {
 	if len(b) < 2 {
 		return "", errors.New("invalid encoding: expected at least 2 bytes for UTF-16 byte order mark")
@@ -489,7 +528,7 @@ func readTextWithDescrFrame(b []byte, hasLang bool, encoded bool) (*Comm, error)
 	if err != nil {
 		return nil, fmt.Errorf("error decoding tag text: %v", err)
 	}
-	c.Text = text
+	c.Text = strings.TrimSuffix(text, "\x00") // optional terminator
 
 	return c, nil
 }
@@ -517,6 +556,78 @@ func readUFID(b []byte) (*UFID, error) {
 	}, nil
 }
 
+// Popularimeter is a popularimeter (POPM) frame, which stores a rating and play counter
+// for the user identified by Email.
+type Popularimeter struct {
+	Email   string
+	Rating  byte // 1 (worst) to 255 (best), or 0 if unknown
+	Counter uint64
+}
+
+func (p Popularimeter) String() string {
+	return fmt.Sprintf("%v: rating %d, played %d times", p.Email, p.Rating, p.Counter)
+}
+
+// readPOPMFrame reads a POPM (or POP) frame:
+// Email to user   <text string> $00
+// Rating          $xx
+// Counter         $xx xx xx xx (xx ...)
+func readPOPMFrame(b []byte) (*Popularimeter, error) {
+	i := bytes.IndexByte(b, 0)
+	if i < 0 || i+1 >= len(b) {
+		return nil, errors.New("expected POPM email and rating")
+	}
+	p := &Popularimeter{
+		Email:  string(b[:i]),
+		Rating: b[i+1],
+	}
+	// The counter may be omitted, and is at least 4 bytes otherwise.
+	for _, c := range b[i+2:] {
+		p.Counter = p.Counter<<8 | uint64(c)
+	}
+	return p, nil
+}
+
+// popmWMPEmail is the email used by Windows (Media Player and Explorer) in POPM frames.
+const popmWMPEmail = "Windows Media Player 9 Series"
+
+// popmStars returns the star rating (1 to 5, or 0 if unrated) of the POPM rating r.  Windows
+// writes 1, 64, 128, 196 and 255 for 1 to 5 stars, other taggers use similar values so the
+// same ranges are used for all ratings.
+func popmStars(r byte) int {
+	switch {
+	case r == 0:
+		return 0
+	case r < 32:
+		return 1
+	case r < 96:
+		return 2
+	case r < 160:
+		return 3
+	case r < 224:
+		return 4
+	}
+	return 5
+}
+
+// popmRating returns the POPM rating (0 to 255) for the rating r (0 to 100, where 20 is one
+// star), interpolating between the values written by Windows for 1 to 5 stars.
+func popmRating(r int) byte {
+	stars := []int{0, 1, 64, 128, 196, 255}
+	if r >= 100 {
+		return 255
+	}
+	if r <= 0 {
+		return 0
+	}
+	i := r / 20
+	v := stars[i] + (stars[i+1]-stars[i])*(r%20)/20
+	if v == 0 {
+		v = 1 // rated
+	}
+	return byte(v)
+}
+
 var pictureTypes = map[byte]string{
 	0x00: "Other",
 	0x01: "32x32 pixels 'file icon' (PNG only)",
@@ -547,13 +658,188 @@ type Picture struct {
 	MIMEType    string // MIMEType of the picture.
 	Type        string // Type of the picture (see pictureTypes).
 	Description string // Description.
-	Data        []byte // Raw picture data.
+	Data        []byte // Raw picture data, nil if not read (see ReadOptions and Read).
+	Width       int    // Width in pixels, zero if not known.
+	Height      int    // Height in pixels, zero if not known.
+
+	// Position of the picture data in the original reader, if Data was not read.
+	r      io.ReaderAt
+	offset int64
+	size   int64
+}
+
+// pictureFrontCover is the Type of front cover pictures.
+const pictureFrontCover = "Cover (front)"
+
+// frontCover returns the first front cover in pics, or the first picture if there is no front
+// cover (or nil if there are no pictures).
+func frontCover(pics []*Picture) *Picture {
+	for _, p := range pics {
+		if p.Type == pictureFrontCover {
+			return p
+		}
+	}
+	if len(pics) > 0 {
+		return pics[0]
+	}
+	return nil
 }
 
 // String returns a string representation of the underlying Picture instance.
 func (p Picture) String() string {
+	size := int64(len(p.Data))
+	if p.Data == nil {
+		size = p.size
+	}
 	return fmt.Sprintf("Picture{Ext: %v, MIMEType: %v, Type: %v, Description: %v, Data.Size: %v}",
-		p.Ext, p.MIMEType, p.Type, p.Description, len(p.Data))
+		p.Ext, p.MIMEType, p.Type, p.Description, size)
+}
+
+// Read returns the picture data.  If the data was not read with the metadata (see ReadOptions)
+// then it is read from the original reader, which must not have been closed or modified.
+func (p Picture) Read() ([]byte, error) {
+	if p.Data != nil || p.r == nil {
+		return p.Data, nil
+	}
+	b := make([]byte, p.size)
+	if _, err := p.r.ReadAt(b, p.offset); err != nil {
+		return nil, fmt.Errorf("error reading picture data: %v", err)
+	}
+	return b, nil
+}
+
+// Extension returns the recommended file extension (including the leading dot) for the
+// picture, determined from the MIME type, the ID3v2.2 image format or by sniffing the picture
+// data (in that order).  Returns an empty string if the format is not recognised.
+func (p Picture) Extension() string {
+	switch strings.ToLower(p.MIMEType) {
+	case "image/jpeg", "image/jpg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+
+	switch strings.ToLower(p.Ext) {
+	case "jpg", "jpeg":
+		return ".jpg"
+	case "png":
+		return ".png"
+	case "gif":
+		return ".gif"
+	}
+
+	switch sniffImageMIME(p.Data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	}
+	return ""
+}
+
+// AspectRatio returns the ratio of the width to the height of the picture (i.e. 2 for a
+// 1000x500 picture), or zero if the dimensions are not known.
+func (p Picture) AspectRatio() float64 {
+	if p.Width <= 0 || p.Height <= 0 {
+		return 0
+	}
+	return float64(p.Width) / float64(p.Height)
+}
+
+// IsSquare returns true if the picture is known to be square.
+func (p Picture) IsSquare() bool {
+	return p.Width > 0 && p.Width == p.Height
+}
+
+// ErrMIMEMismatch is the error returned when writing a picture whose MIME type doesn't match
+// the format of the picture data.
+var ErrMIMEMismatch = errors.New("picture MIME type does not match picture data")
+
+// sniffImageMIME returns the MIME type of the JPEG, PNG or GIF image in b, determined from the
+// signature at the start of the data, or an empty string if the format is not recognised.
+func sniffImageMIME(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, []byte("\xff\xd8\xff")):
+		return "image/jpeg"
+	case bytes.HasPrefix(b, pngHeader):
+		return "image/png"
+	case bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		return "image/gif"
+	}
+	return ""
+}
+
+// pictureMIMEType returns the MIME type to write for p.  If p has no MIME type then it is
+// determined from the picture data, otherwise ErrMIMEMismatch is returned if the data is in
+// a different (recognised) format.  Data in other formats is written with the given MIME type.
+func pictureMIMEType(p *Picture) (string, error) {
+	sniffed := sniffImageMIME(p.Data)
+	mime := strings.ToLower(p.MIMEType)
+	if mime == "image/jpg" {
+		mime = "image/jpeg" // common misspelling
+	}
+
+	switch {
+	case p.MIMEType == "":
+		return sniffed, nil
+	case sniffed != "" && mime != sniffed:
+		return "", ErrMIMEMismatch
+	}
+	return p.MIMEType, nil
+}
+
+// imageSize returns the dimensions of the PNG, JPEG or GIF image in b, read from the image
+// header (the PNG IHDR chunk, JPEG SOF marker or GIF logical screen descriptor) without
+// decoding the image.  Returns zero values if the dimensions cannot be determined.
+func imageSize(b []byte) (width, height int) {
+	switch {
+	case bytes.HasPrefix(b, pngHeader):
+		// Signature (8), IHDR length (4) and type (4), width (4), height (4).
+		if len(b) < 24 || string(b[12:16]) != "IHDR" {
+			return 0, 0
+		}
+		return getInt(b[16:20]), getInt(b[20:24])
+
+	case bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		if len(b) < 10 {
+			return 0, 0
+		}
+		return int(b[6]) | int(b[7])<<8, int(b[8]) | int(b[9])<<8
+
+	case bytes.HasPrefix(b, []byte{0xFF, 0xD8}):
+		for i := 2; i+4 <= len(b); {
+			if b[i] != 0xFF {
+				return 0, 0
+			}
+			marker := b[i+1]
+			switch {
+			case marker == 0xFF: // fill byte
+				i++
+				continue
+			case marker == 0x01 || marker >= 0xD0 && marker <= 0xD8: // no length
+				i += 2
+				continue
+			case marker == 0xD9 || marker == 0xDA: // end of image, start of scan
+				return 0, 0
+			}
+
+			n := getInt(b[i+2 : i+4])
+			// SOF markers, excluding DHT (C4), JPG (C8) and DAC (CC).
+			if marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC {
+				// Length (2), precision (1), height (2), width (2).
+				if i+9 > len(b) {
+					return 0, 0
+				}
+				return getInt(b[i+7 : i+9]), getInt(b[i+5 : i+7])
+			}
+			i += 2 + n
+		}
+	}
+	return 0, 0
 }
 
 // IDv2.2
@@ -592,15 +878,52 @@ func readPICFrame(b []byte) (*Picture, error) {
 		mimeType = "image/png"
 	}
 
+	width, height := imageSize(descDataSplit[1])
 	return &Picture{
 		Ext:         ext,
 		MIMEType:    mimeType,
 		Type:        pictureTypes[picType],
 		Description: desc,
 		Data:        descDataSplit[1],
+		Width:       width,
+		Height:      height,
 	}, nil
 }
 
+// lazyPictureHeaderSize is the number of bytes read from an APIC or PIC frame when the picture
+// data is read on demand, which must include the fields preceding the data.
+const lazyPictureHeaderSize = 1024
+
+// readLazyPictureFrame reads the APIC or PIC frame (of the given size) at the current position
+// of r without reading the picture data, which is read on demand by Picture.Read.  If the fields
+// preceding the data are not within the first lazyPictureHeaderSize bytes then the position of
+// r is restored and nil is returned, so that the whole frame can be read instead.
+func readLazyPictureFrame(r io.ReadSeeker, name string, size uint) (*Picture, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readBytes(r, uint(minInt(int(size), lazyPictureHeaderSize)))
+	if err != nil {
+		return nil, err
+	}
+
+	read := readAPICFrame
+	if name == "PIC" {
+		read = readPICFrame
+	}
+	p, err := read(b)
+	if err != nil {
+		_, err = r.Seek(start, io.SeekStart)
+		return nil, err
+	}
+
+	n := int64(len(b) - len(p.Data)) // size of the fields preceding the data
+	p.Data, p.r, p.offset, p.size = nil, readerAt{r}, start+n, int64(size)-n
+	_, err = r.Seek(start+int64(size), io.SeekStart)
+	return p, err
+}
+
 // IDv2.{3,4}
 // -- Header
 // <Header for 'Attached picture', ID: "APIC">
@@ -645,11 +968,14 @@ func readAPICFrame(b []byte) (*Picture, error) {
 		ext = "png"
 	}
 
+	width, height := imageSize(descDataSplit[1])
 	return &Picture{
 		Ext:         ext,
 		MIMEType:    mimeType,
 		Type:        pictureTypes[picType],
 		Description: desc,
 		Data:        descDataSplit[1],
+		Width:       width,
+		Height:      height,
 	}, nil
 }
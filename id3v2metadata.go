@@ -7,7 +7,6 @@ package tag
 import (
 	"strconv"
 	"strings"
-	"time"
 )
 
 type frameNames map[string][2]string
@@ -24,8 +23,11 @@ func (f frameNames) Name(s string, fm Format) string {
 	case ID3v2_3:
 		return l[1]
 	case ID3v2_4:
-		if s == "year" {
+		switch s {
+		case "year":
 			return "TDRC"
+		case "credits":
+			return "TIPL"
 		}
 		return l[1]
 	}
@@ -43,22 +45,98 @@ var frames = frameNames(map[string][2]string{
 	"disc":         [2]string{"TPA", "TPOS"},
 	"genre":        [2]string{"TCO", "TCON"},
 	"picture":      [2]string{"PIC", "APIC"},
-	"lyrics":       [2]string{"", "USLT"},
+	"lyrics":       [2]string{"ULT", "USLT"},
 	"comment":      [2]string{"COM", "COMM"},
+	"media_type":   [2]string{"TMT", "TMED"},
+	"subtitle":     [2]string{"TT3", "TIT3"},
+	"set_subtitle": [2]string{"", "TSST"}, // ID3v2.4, but also written in ID3v2.3 tags
+	"conductor":    [2]string{"TP3", "TPE3"},
+	"remixer":      [2]string{"TP4", "TPE4"},
+	"credits":      [2]string{"IPL", "IPLS"},
+	"copyright":    [2]string{"TCR", "TCOP"},
+	"publisher":    [2]string{"TPB", "TPUB"},
+	"owner":        [2]string{"", "TOWN"},
+	"mood":         [2]string{"", "TMOO"}, // ID3v2.4, but also written in ID3v2.3 tags
+	"rating":       [2]string{"POP", "POPM"},
+	"grouping":     [2]string{"TT1", "TIT1"},
+
+	// Podcast frames written by iTunes (not part of the ID3v2 specification).
+	"podcast":             [2]string{"", "PCST"},
+	"podcast_url":         [2]string{"", "WFED"},
+	"podcast_guid":        [2]string{"", "TGID"},
+	"podcast_keywords":    [2]string{"", "TKWD"},
+	"podcast_description": [2]string{"", "TDES"},
+
+	// Other frames written by iTunes (not part of the ID3v2 specification).
+	"album_artist_sort": [2]string{"TS2", "TSO2"},
+	"compilation":       [2]string{"TCP", "TCMP"},
+	"itunes_grouping":   [2]string{"GP1", "GRP1"}, // iTunes 12.5.2 and later, see "grouping"
+	"movement":          [2]string{"MVN", "MVNM"},
+	"movement_number":   [2]string{"MVI", "MVIN"}, // number and count, i.e. "2/4"
 })
 
 // metadataID3v2 is the implementation of Metadata used for ID3v2 tags.
 type metadataID3v2 struct {
 	header *id3v2Header
 	frames map[string]interface{}
+	xing   *xingHeader
+	mpeg   mpegFrameHeader // header of the first audio frame, nil if there isn't one
+}
+
+// all returns the values of the frames named k, which are stored as k, k_0, k_1, ... when
+// there is more than one.
+func (m metadataID3v2) all(k string) []interface{} {
+	var res []interface{}
+	for i, name := 0, k; ; i, name = i+1, k+"_"+strconv.Itoa(i) {
+		v, ok := m.frames[name]
+		if !ok {
+			return res
+		}
+		res = append(res, v)
+	}
 }
 
+// id3v2ValueSeparator separates the values of text frames with more than one value in the
+// strings returned by the accessors, as for MP4 items with more than one data atom.
+const id3v2ValueSeparator = ";"
+
 func (m metadataID3v2) getString(k string) string {
-	v, ok := m.frames[k]
-	if !ok {
-		return ""
+	// Values which are not strings (i.e. encrypted frames) are ignored.
+	s, _ := m.frames[k].(string)
+	return strings.ReplaceAll(s, "\x00", id3v2ValueSeparator)
+}
+
+// values returns the values of the text frame k (see readTFrame).
+func (m metadataID3v2) values(k string) []string {
+	s, _ := m.frames[k].(string)
+	if s == "" {
+		return nil
 	}
-	return v.(string)
+	return strings.Split(s, "\x00")
+}
+
+// MultiValueMetadata is implemented by the Metadata of ID3v2 tags (as returned by
+// ReadID3v2Tags), which can store more than one value in a text frame: ID3v2.4 separates the
+// values with null characters (see WriteID3v2Values).  Accessors such as Genre return the
+// values joined with ";".
+type MultiValueMetadata interface {
+	// Values returns the values of the field (i.e. FieldGenre), or nil if the field is not
+	// set or can't have more than one value (see WriteID3v2Values).
+	Values(field string) []string
+}
+
+func (m metadataID3v2) Values(field string) []string {
+	name, ok := id3v24TextFrames[strings.ToUpper(field)]
+	if !ok || m.Format() == ID3v2_2 {
+		return nil
+	}
+	values := m.values(name)
+	if name == "TCON" {
+		for i, v := range values {
+			values[i] = id3v2genre(v)
+		}
+	}
+	return values
 }
 
 func (m metadataID3v2) Format() Format              { return m.header.Version }
@@ -86,7 +164,11 @@ func (m metadataID3v2) Composer() string {
 }
 
 func (m metadataID3v2) Genre() string {
-	return id3v2genre(m.getString(frames.Name("genre", m.Format())))
+	values := m.values(frames.Name("genre", m.Format()))
+	for i, v := range values {
+		values[i] = id3v2genre(v)
+	}
+	return strings.Join(values, id3v2ValueSeparator)
 }
 
 func (m metadataID3v2) Year() int {
@@ -95,13 +177,35 @@ func (m metadataID3v2) Year() int {
 	if year, err := strconv.Atoi(stringYear); err == nil {
 		return year
 	}
+	if year := parseYear(stringYear); year != 0 {
+		return year
+	}
 
-	date, err := time.Parse(time.DateOnly, stringYear)
-	if err != nil {
-		return 0
+	// Some taggers write the frame of the other version.
+	switch m.Format() {
+	case ID3v2_3:
+		return parseYear(m.getString("TDRC"))
+	case ID3v2_4:
+		return parseYear(m.getString("TYER"))
 	}
+	return 0
+}
 
-	return date.Year()
+// parseYear returns the year at the start of the date s (i.e. "1996", "1996-04" or
+// "1996-04-12T10:00"), or zero if s does not start with a year.
+func parseYear(s string) int {
+	s = strings.TrimSpace(s)
+	if len(s) < 4 || len(s) > 4 && s[4] >= '0' && s[4] <= '9' {
+		return 0
+	}
+	year := 0
+	for _, c := range s[:4] {
+		if c < '0' || c > '9' {
+			return 0
+		}
+		year = year*10 + int(c-'0')
+	}
+	return year
 }
 
 func parseXofN(s string) (x, n int) {
@@ -143,10 +247,156 @@ func (m metadataID3v2) Comment() string {
 	return trimString(t.(*Comm).Description)
 }
 
+func (m metadataID3v2) MediaType() string {
+	return m.getString(frames.Name("media_type", m.Format()))
+}
+
+func (m metadataID3v2) Mood() string {
+	return m.getString(frames.Name("mood", m.Format()))
+}
+
+func (m metadataID3v2) Rating() int {
+	// There can be a POPM frame for each user, prefer the rating written by Windows,
+	// otherwise use the first rated frame.
+	rating := 0
+	for _, v := range m.all(frames.Name("rating", m.Format())) {
+		p, ok := v.(*Popularimeter)
+		if !ok {
+			continue
+		}
+		if p.Email == popmWMPEmail {
+			return popmStars(p.Rating)
+		}
+		if rating == 0 {
+			rating = popmStars(p.Rating)
+		}
+	}
+	return rating
+}
+
+func (m metadataID3v2) ContentGroup() string {
+	if g := m.getString(frames.Name("itunes_grouping", m.Format())); g != "" {
+		return g
+	}
+	return m.getString(frames.Name("grouping", m.Format()))
+}
+
+func (m metadataID3v2) Work() string {
+	// iTunes 12.5.2 and later write the grouping to GRP1 and the work to TIT1, earlier
+	// versions (and other software) use TIT1 for the grouping.
+	if m.getString(frames.Name("itunes_grouping", m.Format())) == "" {
+		return ""
+	}
+	return m.getString(frames.Name("grouping", m.Format()))
+}
+
+func (m metadataID3v2) ClassicalInfo() (ClassicalInfo, bool) {
+	x, n := parseXofN(m.getString(frames.Name("movement_number", m.Format())))
+	return classicalInfo(ClassicalInfo{
+		Work:           m.Work(),
+		MovementName:   m.getString(frames.Name("movement", m.Format())),
+		MovementNumber: x,
+		MovementCount:  n,
+		Composer:       m.Composer(),
+	})
+}
+
+func (m metadataID3v2) Gapless() (GaplessInfo, bool) {
+	// iTunes stores gapless information in a COMM frame, other taggers use TXXX.
+	for k, v := range m.frames {
+		if !strings.HasPrefix(k, "COM") && !strings.HasPrefix(k, "TXX") {
+			continue
+		}
+		if c, ok := v.(*Comm); ok && c.Description == "iTunSMPB" {
+			if g, ok := parseITunSMPB(c.Text); ok {
+				return g, true
+			}
+		}
+	}
+
+	if m.xing != nil {
+		return lameGapless(m.xing.LAME)
+	}
+	return GaplessInfo{}, false
+}
+
+func (m metadataID3v2) Bitrate() (int, bool) {
+	if m.mpeg == nil {
+		return 0, false
+	}
+	// The Xing header gives the average bitrate of VBR files.
+	if x := m.xing; x != nil && x.Frames > 0 && x.Bytes > 0 && m.mpeg.sampleRate() > 0 {
+		samples := int64(x.Frames) * int64(m.mpeg.samplesPerFrame())
+		return int(int64(x.Bytes) * 8 * int64(m.mpeg.sampleRate()) / samples), true
+	}
+	b := m.mpeg.bitrate()
+	return b, b > 0
+}
+
+func (m metadataID3v2) Subtitle() string {
+	return m.getString(frames.Name("subtitle", m.Format()))
+}
+
+func (m metadataID3v2) DiscSubtitle() string {
+	return m.getString(frames.Name("set_subtitle", m.Format()))
+}
+
+func (m metadataID3v2) Conductor() string {
+	return m.getString(frames.Name("conductor", m.Format()))
+}
+
+func (m metadataID3v2) Remixer() string {
+	return m.getString(frames.Name("remixer", m.Format()))
+}
+
+func (m metadataID3v2) InvolvedPeople() []Credit {
+	c, _ := m.frames[frames.Name("credits", m.Format())].([]Credit)
+	return c
+}
+
+func (m metadataID3v2) Copyright() string {
+	return m.getString(frames.Name("copyright", m.Format()))
+}
+
+func (m metadataID3v2) Publisher() string {
+	return m.getString(frames.Name("publisher", m.Format()))
+}
+
+func (m metadataID3v2) Owner() string {
+	return m.getString(frames.Name("owner", m.Format()))
+}
+
+func (m metadataID3v2) PodcastInfo() (PodcastInfo, bool) {
+	p := PodcastInfo{
+		FeedURL:     m.getString(frames.Name("podcast_url", m.Format())),
+		EpisodeGUID: m.getString(frames.Name("podcast_guid", m.Format())),
+		Keywords:    m.getString(frames.Name("podcast_keywords", m.Format())),
+		Description: m.getString(frames.Name("podcast_description", m.Format())),
+	}
+	_, ok := m.frames[frames.Name("podcast", m.Format())]
+	return p, ok || p != PodcastInfo{}
+}
+
+func (m metadataID3v2) ITunesTags() ITunesInfo {
+	return ITunesInfo{
+		AlbumArtist:     m.AlbumArtist(),
+		AlbumArtistSort: m.getString(frames.Name("album_artist_sort", m.Format())),
+		Compilation:     parseCompilation(m.getString(frames.Name("compilation", m.Format()))),
+		Grouping:        m.ContentGroup(),
+	}
+}
+
 func (m metadataID3v2) Picture() *Picture {
-	v, ok := m.frames[frames.Name("picture", m.Format())]
-	if !ok {
-		return nil
+	return frontCover(m.Pictures())
+}
+
+func (m metadataID3v2) Pictures() []*Picture {
+	var pics []*Picture
+	for _, v := range m.all(frames.Name("picture", m.Format())) {
+		// Values which are not pictures (i.e. encrypted frames) are ignored.
+		if p, ok := v.(*Picture); ok {
+			pics = append(pics, p)
+		}
 	}
-	return v.(*Picture)
+	return pics
 }
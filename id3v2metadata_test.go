@@ -4,7 +4,10 @@
 
 package tag
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func TestParseXofN(t *testing.T) {
 	table := []struct {
@@ -27,3 +30,43 @@ func TestParseXofN(t *testing.T) {
 		}
 	}
 }
+
+func TestID3v2Year(t *testing.T) {
+	tests := []struct {
+		tag  *id3v2RawTag
+		want int
+	}{
+		{&id3v2RawTag{Version: 4, Frames: []id3v2RawFrame{{Name: "TDRC", Data: []byte("\x031996-04")}}}, 1996},
+		{&id3v2RawTag{Version: 3, Frames: []id3v2RawFrame{{Name: "TYER", Data: []byte("\x001996")}}}, 1996},
+		{&id3v2RawTag{Version: 3, Frames: []id3v2RawFrame{{Name: "TDRC", Data: []byte("\x001996-04-12")}}}, 1996},
+		{&id3v2RawTag{Version: 4, Frames: []id3v2RawFrame{{Name: "TYER", Data: []byte("\x031996")}}}, 1996},
+	}
+
+	for ii, tt := range tests {
+		m, err := ReadID3v2Tags(bytes.NewReader(tt.tag.bytes(0)))
+		if err != nil {
+			t.Fatalf("[%d] ReadID3v2Tags() = %v", ii, err)
+		}
+		if got := m.Year(); got != tt.want {
+			t.Errorf("[%d] Year() = %d, expected %d", ii, got, tt.want)
+		}
+	}
+}
+
+func TestID3v22Frames(t *testing.T) {
+	tag := &id3v2RawTag{Version: 2, Frames: []id3v2RawFrame{
+		{Name: "TT2", Data: []byte("\x00Title")},
+		{Name: "TP1", Data: []byte("\x00Artist")},
+		{Name: "TAL", Data: []byte("\x00Album")},
+		{Name: "ULT", Data: []byte("\x00eng\x00Lyrics")},
+	}}
+	m, err := ReadID3v2Tags(bytes.NewReader(tag.bytes(0)))
+	if err != nil {
+		t.Fatalf("ReadID3v2Tags() = %v", err)
+	}
+	testValue(t, ID3v2_2, m.Format())
+	testValue(t, "Title", m.Title())
+	testValue(t, "Artist", m.Artist())
+	testValue(t, "Album", m.Album())
+	testValue(t, "Lyrics", m.Lyrics())
+}
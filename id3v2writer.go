@@ -0,0 +1,987 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3v2Padding is the number of bytes of padding added when a new ID3v2 tag does
+// not fit in the space used by the existing tag, so that later edits can be made
+// without moving the audio data.
+const id3v2Padding = 1024
+
+// id3v2MaxSize is the largest tag size which can be encoded in an ID3v2 header.
+const id3v2MaxSize = 1<<28 - 1
+
+// id3v24TextFrames maps field names to the ID3v2.4 text frames used to store them.
+var id3v24TextFrames = map[string]string{
+	FieldTitle:           "TIT2",
+	FieldArtist:          "TPE1",
+	FieldAlbum:           "TALB",
+	FieldAlbumArtist:     "TPE2",
+	FieldAlbumArtistSort: "TSO2",
+	FieldArtistSort:      "TSOP",
+	FieldComposer:        "TCOM",
+	FieldGenre:           "TCON",
+	FieldCopyright:       "TCOP",
+	FieldPublisher:       "TPUB",
+	FieldOwner:           "TOWN",
+	FieldMood:            "TMOO",
+	FieldInitialKey:      "TKEY",
+	FieldLanguage:        "TLAN",
+	FieldBPM:             "TBPM",
+	FieldPodcastGUID:     "TGID",
+}
+
+// id3v2TagSize returns the number of bytes used by the ID3v2 tag at the start of r
+// (including the header and footer), or zero if there is no tag.
+func id3v2TagSize(r io.ReadSeeker) (int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	b := make([]byte, 10)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	if string(b[0:3]) != "ID3" || b[3] < 2 || b[3] > 4 {
+		return 0, nil
+	}
+
+	size := int64(get7BitChunkedInt(b[6:10])) + 10
+	if b[3] == 4 && getBit(b[5], 4) {
+		size += 10 // footer
+	}
+	return size, nil
+}
+
+// id3v24Frame returns an ID3v2.4 frame with the given name and content.
+func id3v24Frame(name string, b []byte) []byte {
+	f := make([]byte, 0, 10+len(b))
+	f = append(f, name...)
+	f = append(f, format7BitChunkedUint(uint(len(b)), 4)...)
+	f = append(f, 0, 0) // flags
+	return append(f, b...)
+}
+
+// encodeText encodes s using the text encoding enc, which must be encodingUTF8 or
+// encodingUTF16WithBOM (written little-endian, as by Windows).
+func encodeText(enc byte, s string) []byte {
+	if enc != encodingUTF16WithBOM {
+		return []byte(s)
+	}
+	b := make([]byte, 0, 2+2*len(s))
+	b = append(b, 0xFF, 0xFE)
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(c), byte(c>>8))
+	}
+	return b
+}
+
+// id3v24TextFrame returns the content of a text frame using the text encoding enc.
+func id3v24TextFrame(enc byte, text string) []byte {
+	return append([]byte{enc}, encodeText(enc, text)...)
+}
+
+// id3v24TextWithDescrFrame returns the content of a COMM, USLT (when lang is non-empty) or
+// TXXX frame using the text encoding enc.
+func id3v24TextWithDescrFrame(enc byte, lang, desc, text string) []byte {
+	b := []byte{enc}
+	b = append(b, lang...)
+	b = append(b, encodeText(enc, desc)...)
+	b = append(b, 0)
+	if enc == encodingUTF16WithBOM {
+		b = append(b, 0)
+	}
+	return append(b, encodeText(enc, text)...)
+}
+
+// formatXofN is the inverse of parseXofN.
+func formatXofN(x, n string) string {
+	if n == "" {
+		return x
+	}
+	if x == "" {
+		x = "0"
+	}
+	return x + "/" + n
+}
+
+// id3v2WriteStyle is the style of an existing ID3v2 tag, which is kept when the tag is
+// rewritten.
+type id3v2WriteStyle struct {
+	lyricsLang string // Language code of the lyrics (USLT) frame.
+	encoding   byte   // Text encoding: encodingUTF8 or encodingUTF16WithBOM.
+}
+
+// defaultID3v2WriteStyle is used when there is no existing tag.
+var defaultID3v2WriteStyle = id3v2WriteStyle{lyricsLang: "eng", encoding: encodingUTF8}
+
+// readID3v2WriteStyle returns the style of the ID3v2 tag at the start of r.  Tags with
+// UTF-16 text frames are rewritten using UTF-16 (so that players which don't support UTF-8
+// still display the text), otherwise UTF-8 is used (never ISO-8859-1, which can't represent
+// all characters).
+func readID3v2WriteStyle(r io.ReadSeeker) id3v2WriteStyle {
+	s := defaultID3v2WriteStyle
+	t, err := readID3v2RawTag(r)
+	if err != nil || t == nil {
+		return s
+	}
+	for _, f := range t.Frames {
+		// Frames with format flags (i.e. compression) are not decoded.
+		if len(f.Data) == 0 || f.Flags[1] != 0 || !hasID3v2TextEncoding(f.Name) {
+			continue
+		}
+		if enc := f.Data[0]; enc == encodingUTF16 || enc == encodingUTF16WithBOM {
+			s.encoding = encodingUTF16WithBOM
+		}
+		if (f.Name == "USLT" || f.Name == "ULT") && len(f.Data) >= 4 {
+			s.lyricsLang = string(f.Data[1:4])
+		}
+	}
+	return s
+}
+
+// buildID3v24Frames returns the ID3v2.4 frames representing data, which must already be
+// normalised.  Fields without a corresponding ID3v2.4 frame are written as TXXX frames.
+// Text is written using the encoding of s, and lyrics in a USLT frame with its language code.
+func buildID3v24Frames(data map[string]string, s id3v2WriteStyle) []byte {
+	var b []byte
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := data[k]
+		if name, ok := id3v24TextFrames[k]; ok {
+			b = append(b, id3v24Frame(name, id3v24TextFrame(s.encoding, v))...)
+			continue
+		}
+
+		switch k {
+		case FieldDate, FieldYear:
+			if k == FieldYear && data[FieldDate] != "" {
+				continue
+			}
+			b = append(b, id3v24Frame("TDRC", id3v24TextFrame(s.encoding, v))...)
+
+		case FieldTrackNumber:
+			b = append(b, id3v24Frame("TRCK", id3v24TextFrame(s.encoding, formatXofN(v, data[FieldTrackTotal])))...)
+
+		case FieldDiscNumber:
+			b = append(b, id3v24Frame("TPOS", id3v24TextFrame(s.encoding, formatXofN(v, data[FieldDiscTotal])))...)
+
+		case FieldTrackTotal, FieldDiscTotal:
+			// Written with the corresponding number.
+
+		case FieldPodcastURL:
+			// iTunes writes WFED as an ISO-8859-1 text frame (which is read as a URL frame).
+			b = append(b, id3v24Frame("WFED", append([]byte{encodingISO8859}, encodeISO8859(v)...))...)
+
+		case FieldComment:
+			b = append(b, id3v24Frame("COMM", id3v24TextWithDescrFrame(s.encoding, "eng", "", v))...)
+
+		case FieldLyrics:
+			b = append(b, id3v24Frame("USLT", id3v24TextWithDescrFrame(s.encoding, s.lyricsLang, "", v))...)
+
+		default:
+			b = append(b, id3v24Frame("TXXX", id3v24TextWithDescrFrame(s.encoding, "", k, v))...)
+		}
+	}
+	return b
+}
+
+// id3v22FrameNames maps ID3v2.2 frame names to the equivalent ID3v2.3 frame names, which are
+// used when the frames of an ID3v2.2 tag are kept in a rewritten tag.  The content of these
+// frames is the same in both versions, except PIC (see id3v22APICFrame).  CRM (encrypted meta
+// frame) and LNK (which refers to other frames by their ID3v2.2 names) can't be converted.
+var id3v22FrameNames = map[string]string{
+	"BUF": "RBUF",
+	"CNT": "PCNT",
+	"COM": "COMM",
+	"CRA": "AENC",
+	"EQU": "EQUA",
+	"ETC": "ETCO",
+	"GEO": "GEOB",
+	"GP1": "GRP1",
+	"IPL": "IPLS",
+	"MCI": "MCDI",
+	"MLL": "MLLT",
+	"MVI": "MVIN",
+	"MVN": "MVNM",
+	"PIC": "APIC",
+	"POP": "POPM",
+	"REV": "RVRB",
+	"RVA": "RVAD",
+	"SLT": "SYLT",
+	"STC": "SYTC",
+	"TAL": "TALB",
+	"TBP": "TBPM",
+	"TCM": "TCOM",
+	"TCO": "TCON",
+	"TCP": "TCMP",
+	"TCR": "TCOP",
+	"TDA": "TDAT",
+	"TDY": "TDLY",
+	"TEN": "TENC",
+	"TFT": "TFLT",
+	"TIM": "TIME",
+	"TKE": "TKEY",
+	"TLA": "TLAN",
+	"TLE": "TLEN",
+	"TMT": "TMED",
+	"TOA": "TOPE",
+	"TOF": "TOFN",
+	"TOL": "TOLY",
+	"TOR": "TORY",
+	"TOT": "TOAL",
+	"TP1": "TPE1",
+	"TP2": "TPE2",
+	"TP3": "TPE3",
+	"TP4": "TPE4",
+	"TPA": "TPOS",
+	"TPB": "TPUB",
+	"TRC": "TSRC",
+	"TRD": "TRDA",
+	"TRK": "TRCK",
+	"TS2": "TSO2",
+	"TSA": "TSOA",
+	"TSC": "TSOC",
+	"TSI": "TSIZ",
+	"TSP": "TSOP",
+	"TSS": "TSSE",
+	"TST": "TSOT",
+	"TT1": "TIT1",
+	"TT2": "TIT2",
+	"TT3": "TIT3",
+	"TXT": "TEXT",
+	"TXX": "TXXX",
+	"TYE": "TYER",
+	"UFI": "UFID",
+	"ULT": "USLT",
+	"WAF": "WOAF",
+	"WAR": "WOAR",
+	"WAS": "WOAS",
+	"WCM": "WCOM",
+	"WCP": "WCOP",
+	"WPB": "WPUB",
+	"WXX": "WXXX",
+}
+
+// id3v22APICFrame converts the content of an ID3v2.2 PIC frame, which has a three character
+// image format (i.e. "JPG"), to that of an APIC frame, which has a MIME type.
+func id3v22APICFrame(b []byte) ([]byte, error) {
+	if len(b) < 5 {
+		return nil, errors.New("invalid PIC frame")
+	}
+
+	var mimeType string
+	switch format := strings.ToLower(string(b[1:4])); format {
+	case "jpg":
+		mimeType = "image/jpeg"
+	case "-->":
+		mimeType = format // the picture data is a URL
+	default:
+		mimeType = "image/" + strings.TrimRight(format, "\x00 ")
+	}
+
+	f := make([]byte, 0, len(b)+len(mimeType)-2)
+	f = append(f, b[0])
+	f = append(f, mimeType...)
+	f = append(f, 0)
+	return append(f, b[4:]...), nil
+}
+
+// id3v23FrameV24 converts the flags and content of an ID3v2.3 frame to the ID3v2.4 layout.
+// The status flags are one bit lower in ID3v2.4, and the data added by the compression,
+// encryption and grouping format flags is in a different order (with the decompressed size
+// of a compressed frame given by a synchsafe data length indicator).
+func id3v23FrameV24(f id3v2RawFrame) ([2]byte, []byte, error) {
+	flags := [2]byte{f.Flags[0] >> 1 & 0x70, 0}
+	b := f.Data
+
+	var size, method, group []byte
+	if getBit(f.Flags[1], 7) {
+		if len(b) < 4 {
+			return flags, nil, fmt.Errorf("invalid compressed ID3v2 frame %q", f.Name)
+		}
+		size, b = format7BitChunkedUint(uint(getInt(b[0:4])), 4), b[4:]
+		flags[1] |= 0x08 | 0x01 // compression and data length indicator
+	}
+	if getBit(f.Flags[1], 6) {
+		if len(b) < 1 {
+			return flags, nil, fmt.Errorf("invalid encrypted ID3v2 frame %q", f.Name)
+		}
+		method, b = b[0:1], b[1:]
+		flags[1] |= 0x04
+	}
+	if getBit(f.Flags[1], 5) {
+		if len(b) < 1 {
+			return flags, nil, fmt.Errorf("invalid grouped ID3v2 frame %q", f.Name)
+		}
+		group, b = b[0:1], b[1:]
+		flags[1] |= 0x40
+	}
+	if flags[1] == 0 {
+		return flags, f.Data, nil
+	}
+
+	data := make([]byte, 0, len(f.Data))
+	data = append(data, group...)
+	data = append(data, method...)
+	data = append(data, size...)
+	return flags, append(data, b...), nil
+}
+
+// id3v2ReplacedFrames returns the names of the frames written by buildID3v24Frames for data
+// (which must already be normalised), and the (upper case) descriptions of its TXXX frames.
+// The date fields replace the ID3v2.3 date frames as well as TDRC.
+func id3v2ReplacedFrames(data map[string]string) (names, txxx map[string]bool) {
+	names, txxx = make(map[string]bool), make(map[string]bool)
+	for k := range data {
+		if name, ok := id3v24TextFrames[k]; ok {
+			names[name] = true
+			continue
+		}
+
+		switch k {
+		case FieldDate, FieldYear:
+			names["TDRC"], names["TYER"], names["TDAT"], names["TIME"], names["TRDA"] = true, true, true, true, true
+		case FieldTrackNumber:
+			names["TRCK"] = true
+		case FieldDiscNumber:
+			names["TPOS"] = true
+		case FieldTrackTotal, FieldDiscTotal:
+			// Written with the corresponding number.
+		case FieldPodcastURL:
+			names["WFED"] = true
+		case FieldComment:
+			names["COMM"] = true
+		case FieldLyrics:
+			names["USLT"] = true
+		default:
+			txxx[k] = true
+		}
+	}
+	return names, txxx
+}
+
+// id3v2ReplacedFrame returns true if the ID3v2.3 or ID3v2.4 frame f (with ID3v2.4 flags) is
+// replaced by a frame written from data (see id3v2ReplacedFrames).  Only the COMM and USLT
+// frames without a description are replaced, and TXXX frames are matched by description.
+// Compressed and encrypted frames can't be decoded, so are never replaced.
+func id3v2ReplacedFrame(f id3v2RawFrame, names, txxx map[string]bool) bool {
+	switch f.Name {
+	case "TXXX", "COMM", "USLT":
+		if f.Flags[1]&(0x08|0x04) != 0 || (f.Name != "TXXX" && !names[f.Name]) {
+			return false
+		}
+		b := f.Data
+		if getBit(f.Flags[1], 6) && len(b) > 0 {
+			b = b[1:] // group identifier
+		}
+		if getBit(f.Flags[1], 0) && len(b) >= 4 {
+			b = b[4:] // data length indicator
+		}
+		c, err := readTextWithDescrFrame(b, f.Name != "TXXX", true)
+		if err != nil {
+			return false
+		}
+		if f.Name == "TXXX" {
+			return txxx[strings.ToUpper(c.Description)]
+		}
+		return c.Description == ""
+	}
+	return names[f.Name]
+}
+
+// keptID3v2Frames returns the frames of the ID3v2 tag at the start of r which are kept when
+// it is rewritten with the fields in data (which must already be normalised), encoded as
+// ID3v2.4 frames.  Frames which are replaced by those written from data are dropped (see
+// id3v2ReplacedFrame), and all others are kept with their content unchanged: ID3v2.2 frames
+// are renamed (see id3v22FrameNames), and ID3v2.3 flags converted (see id3v23FrameV24).  An
+// error is returned if a kept frame can't be converted.
+func keptID3v2Frames(r io.ReadSeeker, data map[string]string) ([]byte, error) {
+	t, err := readID3v2RawTag(r)
+	if err != nil || t == nil {
+		return nil, err
+	}
+
+	names, txxx := id3v2ReplacedFrames(data)
+	var b []byte
+	for _, f := range t.Frames {
+		switch t.Version {
+		case 2:
+			name, ok := id3v22FrameNames[f.Name]
+			if !ok {
+				return nil, fmt.Errorf("ID3v2.2 frame %q can't be converted to ID3v2.4", f.Name)
+			}
+			f.Name = name
+		case 3:
+			if f.Flags, f.Data, err = id3v23FrameV24(f); err != nil {
+				return nil, err
+			}
+		}
+		if id3v2ReplacedFrame(f, names, txxx) {
+			continue
+		}
+		if t.Version == 2 && f.Name == "APIC" {
+			if f.Data, err = id3v22APICFrame(f.Data); err != nil {
+				return nil, err
+			}
+		}
+		b = append(b, f.Name...)
+		b = append(b, format7BitChunkedUint(uint(len(f.Data)), 4)...)
+		b = append(b, f.Flags[:]...)
+		b = append(b, f.Data...)
+	}
+	return b, nil
+}
+
+// writeID3v24Tag replaces any ID3v2 tag at the start of rw with an ID3v2.4 tag containing
+// the given frames.  The existing tag space is reused (and padded) if the new tag fits,
+// otherwise the audio data is moved to make room.
+func writeID3v24Tag(rw io.ReadWriteSeeker, frames []byte) error {
+	old, err := id3v2TagSize(rw)
+	if err != nil {
+		return err
+	}
+
+	size := int64(len(frames)) + 10
+	if size-10 > id3v2MaxSize {
+		return errors.New("ID3v2 tag too large")
+	}
+
+	if size <= old {
+		size = old
+	} else {
+		size += id3v2Padding
+		if err := shiftFileRight(rw, old, size-old); err != nil {
+			return fmt.Errorf("error making space for ID3v2 tag: %v", err)
+		}
+	}
+
+	b := make([]byte, size)
+	copy(b, "ID3")
+	b[3] = 4 // version 2.4.0
+	copy(b[6:10], format7BitChunkedUint(uint(size-10), 4))
+	copy(b[10:], frames)
+
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = rw.Write(b)
+	return err
+}
+
+// WriteID3v2Tags writes the fields in data to rw as an ID3v2.4 tag at the start of the file,
+// replacing the frames of any existing ID3v2 tag which store the same fields (TXXX frames are
+// matched by description).  Other frames (i.e. APIC, GEOB, PRIV and other TXXX frames) are kept
+// unchanged, except that ID3v2.2 frames are converted to their ID3v2.3 names (and PIC frames to
+// APIC).  An error is returned if a kept frame can't be converted.  The language of any existing
+// lyrics (USLT) frame is kept, as is the UTF-16 text encoding of an existing tag (otherwise text
+// is written as UTF-8).
+func WriteID3v2Tags(rw io.ReadWriteSeeker, data map[string]string) error {
+	data = normaliseFields(data)
+	kept, err := keptID3v2Frames(rw, data)
+	if err != nil {
+		return err
+	}
+	frames := buildID3v24Frames(data, readID3v2WriteStyle(rw))
+	return writeID3v24Tag(rw, append(frames, kept...))
+}
+
+// WriteID3v2Values is like WriteID3v2Tags, but fields can have more than one value (i.e. a list
+// of genres), which are written to the text frame separated by null characters as specified by
+// ID3v2.4 (and written by MusicBrainz Picard).  Only fields which are stored in text frames
+// (see MultiValueMetadata) can have more than one value.
+func WriteID3v2Values(rw io.ReadWriteSeeker, data map[string][]string) error {
+	fields := make(map[string]string, len(data))
+	for k, v := range data {
+		if _, ok := id3v24TextFrames[strings.ToUpper(k)]; !ok && len(v) > 1 {
+			return fmt.Errorf("field %q cannot have more than one value", k)
+		}
+		fields[k] = strings.Join(v, "\x00")
+	}
+	return WriteID3v2Tags(rw, fields)
+}
+
+// WriteID3Both writes the fields in data to rw as an ID3v2.4 tag at the start of the file (see
+// WriteID3v2Tags) and a matching ID3v1.1 tag at the end, replacing any existing ID3v1 tag.
+// Values which don't fit in the fixed-size ID3v1 fields are truncated.
+func WriteID3Both(rw io.ReadWriteSeeker, data map[string]string) error {
+	if err := WriteID3v2Tags(rw, data); err != nil {
+		return err
+	}
+	return writeID3v1Tag(rw, normaliseFields(data))
+}
+
+// id3v2RawFrame is an undecoded ID3v2 frame.
+type id3v2RawFrame struct {
+	Name  string
+	Flags [2]byte // Always zero for ID3v2.2.
+	Data  []byte
+}
+
+// id3v2RawTag is an undecoded ID3v2 tag.
+type id3v2RawTag struct {
+	Version byte // Major version: 2, 3 or 4.
+	Flags   byte
+	Frames  []id3v2RawFrame
+	Padding int   // Bytes of padding following the frames.
+	Size    int64 // Size of the tag in the file, including the header and footer.
+}
+
+// readID3v2RawTag reads the ID3v2 tag at the start of r, returning nil if there isn't one.
+// Tag-level unsynchronisation (ID3v2.2 and ID3v2.3) is removed and the extended header is
+// dropped, so that the frames can be rewritten without them.
+func readID3v2RawTag(r io.ReadSeeker) (*id3v2RawTag, error) {
+	size, err := id3v2TagSize(r)
+	if err != nil || size == 0 {
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	h, err := readBytes(r, 10)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readBytes(r, uint(get7BitChunkedInt(h[6:10])))
+	if err != nil {
+		return nil, fmt.Errorf("error reading ID3v2 tag: %v", err)
+	}
+
+	t := &id3v2RawTag{
+		Version: h[3],
+		Flags:   h[5] &^ (1<<6 | 1<<4), // extended header and footer are dropped
+		Size:    size,
+	}
+
+	if getBit(h[5], 7) && t.Version < 4 {
+		b = removeUnsynchronisation(b)
+		t.Flags &^= 1 << 7
+	}
+
+	if getBit(h[5], 6) && t.Version > 2 {
+		if len(b) < 4 {
+			return nil, errors.New("invalid ID3v2 extended header")
+		}
+		n := getInt(b[0:4]) + 4 // ID3v2.3 size excludes the size bytes
+		if t.Version == 4 {
+			n = get7BitChunkedInt(b[0:4])
+		}
+		if n > len(b) {
+			return nil, errors.New("invalid ID3v2 extended header size")
+		}
+		b = b[n:]
+	}
+
+	for len(b) > 0 && b[0] != 0 {
+		var f id3v2RawFrame
+		var n, headerSize int
+		switch t.Version {
+		case 2:
+			headerSize = 6
+			if len(b) < headerSize {
+				return nil, errors.New("invalid ID3v2 frame header")
+			}
+			f.Name, n = string(b[0:3]), getInt(b[3:6])
+		case 3, 4:
+			headerSize = 10
+			if len(b) < headerSize {
+				return nil, errors.New("invalid ID3v2 frame header")
+			}
+			f.Name, n = string(b[0:4]), getInt(b[4:8])
+			if t.Version == 4 {
+				n = get7BitChunkedInt(b[4:8])
+			}
+			copy(f.Flags[:], b[8:10])
+		}
+
+		if n > len(b)-headerSize {
+			return nil, fmt.Errorf("ID3v2 frame %q exceeds tag size", f.Name)
+		}
+		f.Data = b[headerSize : headerSize+n]
+		t.Frames = append(t.Frames, f)
+		b = b[headerSize+n:]
+	}
+	t.Padding = len(b)
+	return t, nil
+}
+
+// framesSize returns the number of bytes used by the encoded frames of the tag.
+func (t *id3v2RawTag) framesSize() int {
+	headerSize := 10
+	if t.Version == 2 {
+		headerSize = 6
+	}
+
+	n := 0
+	for _, f := range t.Frames {
+		n += headerSize + len(f.Data)
+	}
+	return n
+}
+
+// bytes returns the encoded tag, followed by the given number of bytes of padding.
+func (t *id3v2RawTag) bytes(padding int) []byte {
+	size := t.framesSize() + padding
+
+	b := make([]byte, 0, 10+size)
+	b = append(b, 'I', 'D', '3', t.Version, 0, t.Flags)
+	b = append(b, format7BitChunkedUint(uint(size), 4)...)
+	for _, f := range t.Frames {
+		n := uint(len(f.Data))
+		switch t.Version {
+		case 2:
+			b = append(b, f.Name...)
+			b = append(b, formatUintBigEndian(n, 3)...)
+		case 3:
+			b = append(b, f.Name...)
+			b = append(b, formatUintBigEndian(n, 4)...)
+			b = append(b, f.Flags[:]...)
+		case 4:
+			b = append(b, f.Name...)
+			b = append(b, format7BitChunkedUint(n, 4)...)
+			b = append(b, f.Flags[:]...)
+		}
+		b = append(b, f.Data...)
+	}
+	return append(b, make([]byte, padding)...)
+}
+
+// writeID3v2RawTag replaces the ID3v2 tag at the start of rw with t (followed by
+// the given number of bytes of padding).
+func writeID3v2RawTag(rw io.ReadWriteSeeker, t *id3v2RawTag, padding int) error {
+	if t.framesSize()+padding > id3v2MaxSize {
+		return errors.New("ID3v2 tag too large")
+	}
+	return replaceRegion(rw, 0, t.Size, t.bytes(padding))
+}
+
+// removeID3v2Frames removes all frames with the given names from the ID3v2 tag at the start
+// of rw, returning the number of bytes removed.  The existing padding is kept.
+func removeID3v2Frames(rw io.ReadWriteSeeker, names ...string) (int64, error) {
+	t, err := readID3v2RawTag(rw)
+	if err != nil || t == nil {
+		return 0, err
+	}
+
+	remove := make(map[string]bool, len(names))
+	for _, n := range names {
+		remove[n] = true
+	}
+
+	kept := t.Frames[:0]
+	for _, f := range t.Frames {
+		if !remove[f.Name] {
+			kept = append(kept, f)
+		}
+	}
+	if len(kept) == len(t.Frames) {
+		return 0, nil
+	}
+	t.Frames = kept
+
+	if err := writeID3v2RawTag(rw, t, t.Padding); err != nil {
+		return 0, err
+	}
+	return t.Size - int64(10+t.framesSize()+t.Padding), nil
+}
+
+// buildAPICFrame returns the content of an APIC frame containing pic.  The description is
+// written as ISO-8859-1 if possible (otherwise UTF-16), which is valid in ID3v2.3 and ID3v2.4.
+func buildAPICFrame(pic *Picture) ([]byte, error) {
+	mime, err := pictureMIMEType(pic)
+	if err != nil {
+		return nil, err
+	}
+
+	enc, desc, term := byte(encodingISO8859), encodeISO8859(pic.Description), []byte{0}
+	for _, r := range pic.Description {
+		if r > 0xFF {
+			enc, desc, term = encodingUTF16WithBOM, encodeText(encodingUTF16WithBOM, pic.Description), []byte{0, 0}
+			break
+		}
+	}
+
+	b := make([]byte, 0, 4+len(mime)+len(desc)+len(pic.Data))
+	b = append(b, enc)
+	b = append(b, mime...)
+	b = append(b, 0, pictureTypeCode(pic.Type))
+	b = append(b, desc...)
+	b = append(b, term...)
+	return append(b, pic.Data...), nil
+}
+
+// apicPictureType returns the picture type of the APIC frame content b, or -1 if it is invalid.
+func apicPictureType(b []byte) int {
+	if len(b) < 1 {
+		return -1
+	}
+	i := bytes.IndexByte(b[1:], 0) // end of the MIME type
+	if i < 0 || 1+i+1 >= len(b) {
+		return -1
+	}
+	return int(b[1+i+1])
+}
+
+// setID3v2Picture adds an APIC frame containing pic to the ID3v2 tag at the start of rw (or a
+// new ID3v2.4 tag if there isn't one), replacing any APIC frames with the same picture type.
+// The tag keeps its size if the frame fits in the padding.
+func setID3v2Picture(rw io.ReadWriteSeeker, pic *Picture) error {
+	frame, err := buildAPICFrame(pic)
+	if err != nil {
+		return err
+	}
+	t, err := readID3v2RawTag(rw)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		t = &id3v2RawTag{Version: 4}
+	}
+	if t.Version == 2 {
+		return errors.New("writing pictures to ID3v2.2 tags is not supported")
+	}
+
+	code := int(pictureTypeCode(pic.Type))
+	kept := t.Frames[:0]
+	for _, f := range t.Frames {
+		// Frames with format flags (i.e. compression) are not decoded.
+		if f.Name == "APIC" && f.Flags[1] == 0 && apicPictureType(f.Data) == code {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	t.Frames = append(kept, id3v2RawFrame{Name: "APIC", Data: frame})
+	return writeID3v2RawTagInPlace(rw, t)
+}
+
+// setID3v2Rating sets the rating of the POPM frame with the given email in the ID3v2 tag at the
+// start of rw (adding a frame, or a new ID3v2.4 tag, if there isn't one), keeping its counter.
+func setID3v2Rating(rw io.ReadWriteSeeker, rating byte, email string) error {
+	t, err := readID3v2RawTag(rw)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		t = &id3v2RawTag{Version: 4}
+	}
+	name := "POPM"
+	if t.Version == 2 {
+		name = "POP"
+	}
+
+	for i, f := range t.Frames {
+		if f.Name != name || f.Flags[1] != 0 {
+			continue
+		}
+		p, err := readPOPMFrame(f.Data)
+		if err != nil || p.Email != email {
+			continue
+		}
+		b := append([]byte(nil), f.Data...)
+		b[len(email)+1] = rating
+		t.Frames[i].Data = b
+		return writeID3v2RawTagInPlace(rw, t)
+	}
+
+	b := append([]byte(email), 0, rating) // the counter is omitted
+	t.Frames = append(t.Frames, id3v2RawFrame{Name: name, Data: b})
+	return writeID3v2RawTagInPlace(rw, t)
+}
+
+// writeID3v2RawTagInPlace replaces the ID3v2 tag at the start of rw with t, keeping the size of
+// the existing tag if the frames fit (otherwise id3v2Padding bytes of padding are added).
+func writeID3v2RawTagInPlace(rw io.ReadWriteSeeker, t *id3v2RawTag) error {
+	padding := int(t.Size) - 10 - t.framesSize()
+	if padding < 0 {
+		padding = id3v2Padding
+	}
+	return writeID3v2RawTag(rw, t, padding)
+}
+
+// RemoveUnsync rewrites the ID3v2 tag at the start of rw without unsynchronisation (the scheme
+// which inserts a zero byte after any 0xFF byte which could be mistaken for an MPEG frame sync),
+// clearing the header flag (and in ID3v2.4, the frame flags).  The tag keeps its size (the space
+// saved is added to the padding) so the audio data is not moved.  Returns nil without writing
+// anything if the tag doesn't use unsynchronisation.
+func RemoveUnsync(rw io.ReadWriteSeeker) error {
+	unsync, err := id3v2Unsynchronised(rw)
+	if err != nil || !unsync {
+		return err
+	}
+
+	t, err := readID3v2RawTag(rw)
+	if err != nil {
+		return err
+	}
+	t.Flags &^= 1 << 7
+	if t.Version == 4 {
+		for i, f := range t.Frames {
+			if getBit(f.Flags[1], 1) {
+				t.Frames[i].Data = removeUnsynchronisation(f.Data)
+				t.Frames[i].Flags[1] &^= 1 << 1
+			}
+		}
+	}
+
+	padding := int(t.Size) - 10 - t.framesSize()
+	if padding < 0 {
+		padding = t.Padding
+	}
+	return writeID3v2RawTag(rw, t, padding)
+}
+
+// id3v2Unsynchronised returns true if the ID3v2 tag at the start of r uses unsynchronisation,
+// either for the whole tag or (in ID3v2.4) for any frame.
+func id3v2Unsynchronised(r io.ReadSeeker) (bool, error) {
+	size, err := id3v2TagSize(r)
+	if err != nil || size == 0 {
+		return false, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	h, err := readBytes(r, 10)
+	if err != nil {
+		return false, err
+	}
+	if getBit(h[5], 7) {
+		return true, nil
+	}
+	if h[3] != 4 {
+		return false, nil
+	}
+
+	t, err := readID3v2RawTag(r)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range t.Frames {
+		if getBit(f.Flags[1], 1) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RepairID3v2Size corrects the size in the header of the ID3v2 tag at the start of rw when it
+// doesn't match the data: a size which is too large hides the start of the audio, and one which
+// is too small leaves the end of the frames to be read as audio.  The frames are walked from the
+// start of the tag (ignoring the declared size), and the tag is taken to end at the first MPEG
+// frame sync which follows them (so any padding is kept).  Tags with unsynchronisation or a
+// footer are not supported.  Returns nil without writing anything if the size is correct.
+func RepairID3v2Size(rw io.ReadWriteSeeker) error {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	h, err := readBytes(rw, 10)
+	if err != nil {
+		return err
+	}
+	if string(h[0:3]) != "ID3" {
+		return errors.New("no ID3v2 tag found")
+	}
+	if getBit(h[5], 7) || getBit(h[5], 4) {
+		return errors.New("ID3v2 tags with unsynchronisation or a footer are not supported")
+	}
+
+	headerSize, nameSize := int64(10), 4
+	if h[3] == 2 {
+		headerSize, nameSize = 6, 3
+	}
+
+	// Walk the frames until padding (or anything which isn't a frame header).
+	end := int64(10)
+	for {
+		b, err := readBytes(rw, uint(headerSize))
+		if err != nil || !validID3v2FrameName(b[:nameSize]) {
+			break
+		}
+		var n int64
+		switch h[3] {
+		case 2:
+			n = int64(getInt(b[3:6]))
+		case 3:
+			n = int64(getInt(b[4:8]))
+		default:
+			n = int64(get7BitChunkedInt(b[4:8]))
+		}
+		end += headerSize + n
+		if _, err := rw.Seek(end, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	sync, err := findMPEGSync(rw, end)
+	if err != nil {
+		return err
+	}
+
+	size := sync - 10
+	if size == int64(get7BitChunkedInt(h[6:10])) {
+		return nil
+	}
+	if size > id3v2MaxSize {
+		return errors.New("ID3v2 tag too large")
+	}
+	if _, err := rw.Seek(6, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = rw.Write(format7BitChunkedUint(uint(size), 4))
+	return err
+}
+
+// validID3v2FrameName returns true if b is a valid frame name (upper case letters and digits).
+func validID3v2FrameName(b []byte) bool {
+	for _, c := range b {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// findMPEGSync returns the offset of the first valid MPEG audio frame header in r at or after
+// offset.
+func findMPEGSync(r io.ReadSeeker, offset int64) (int64, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	br := bufio.NewReader(r)
+	for {
+		h, err := br.Peek(4)
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("no MPEG frame found")
+			}
+			return 0, err
+		}
+		if mpegFrameHeader(h).valid() {
+			return offset, nil
+		}
+		br.Discard(1)
+		offset++
+	}
+}
@@ -0,0 +1,480 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteID3Both(t *testing.T) {
+	for _, path := range []string{
+		"without_tags/sample.mp3",
+		"with_tags/sample.id3v23.mp3",
+		"with_tags/sample.id3v11.mp3",
+	} {
+		f := tempCopy(t, path)
+
+		data := map[string]string{
+			"title":       "A Title Which Is Much Too Long For An ID3v1 Tag",
+			"artist":      "Test Artist",
+			"album":       "Test Album",
+			"date":        "1996-04-01",
+			"genre":       "Jazz",
+			"tracknumber": "5",
+			"tracktotal":  "12",
+			"comment":     "Test Comment",
+		}
+		if err := WriteID3Both(f, data); err != nil {
+			t.Fatalf("%v: WriteID3Both() = %v", path, err)
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		v2, err := ReadID3v2Tags(f)
+		if err != nil {
+			t.Fatalf("%v: ReadID3v2Tags() = %v", path, err)
+		}
+		v1, err := ReadID3v1Tags(f)
+		if err != nil {
+			t.Fatalf("%v: ReadID3v1Tags() = %v", path, err)
+		}
+
+		testValue(t, ID3v2_4, v2.Format())
+		testValue(t, data["title"], v2.Title())
+		testValue(t, data["title"][:30], v1.Title())
+		if !strings.HasPrefix(v2.Title(), v1.Title()) {
+			t.Errorf("%v: ID3v1 title %q is not a prefix of ID3v2 title %q", path, v1.Title(), v2.Title())
+		}
+
+		for _, m := range []Metadata{v1, v2} {
+			testValue(t, "Test Artist", m.Artist())
+			testValue(t, "Test Album", m.Album())
+			testValue(t, "Jazz", m.Genre())
+			testValue(t, "Test Comment", m.Comment())
+			track, _ := m.Track()
+			testValue(t, 5, track)
+		}
+		_, total := v2.Track()
+		testValue(t, 12, total)
+		testValue(t, 1996, v1.Year())
+	}
+}
+
+func TestID3v1TagTruncation(t *testing.T) {
+	b := id3v1Tag(normaliseFields(map[string]string{
+		"title":   "Ωmega " + strings.Repeat("x", 40),
+		"comment": strings.Repeat("c", 40),
+	}))
+
+	if len(b) != id3v1Size {
+		t.Fatalf("got %d bytes, expected %d", len(b), id3v1Size)
+	}
+	testValue(t, "?mega "+strings.Repeat("x", 24), string(b[3:33]))
+	testValue(t, strings.Repeat("c", 30), string(b[97:127]))
+	testValue(t, byte(0xFF), b[127])
+}
+
+// testID3v2Tag returns an ID3v2.3 tag containing the given frames.
+func testID3v2Tag(frames ...id3v2RawFrame) []byte {
+	t := &id3v2RawTag{Version: 3, Frames: frames}
+	return t.bytes(16)
+}
+
+func TestID3v2RawTagRoundTrip(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.id3v22.mp3",
+		"with_tags/sample.id3v23.mp3",
+		"with_tags/sample.id3v24.mp3",
+	} {
+		f := tempCopy(t, path)
+		orig := readAll(t, f)
+
+		rt, err := readID3v2RawTag(f)
+		if err != nil {
+			t.Fatalf("%v: readID3v2RawTag() = %v", path, err)
+		}
+		if err := writeID3v2RawTag(f, rt, rt.Padding); err != nil {
+			t.Fatalf("%v: writeID3v2RawTag() = %v", path, err)
+		}
+		if !bytes.Equal(orig, readAll(t, f)) {
+			t.Errorf("%v: content changed after round trip", path)
+		}
+	}
+}
+
+func TestWriteLyrics(t *testing.T) {
+	lyrics := "First line\nSecond line\n\nThird verse"
+
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3 := tempFile(t, append(testID3v2Tag(
+		id3v2RawFrame{Name: "USLT", Data: []byte("\x00deu\x00Alter Text")},
+	), audio...))
+	if err := WriteID3Both(mp3, map[string]string{"Lyrics": lyrics}); err != nil {
+		t.Fatalf("WriteID3Both() = %v", err)
+	}
+	if _, err := mp3.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadID3v2Tags(mp3)
+	if err != nil {
+		t.Fatalf("ReadID3v2Tags() = %v", err)
+	}
+	testValue(t, lyrics, m.Lyrics())
+	if c, ok := m.Raw()["USLT"].(*Comm); !ok || c.Language != "deu" {
+		t.Errorf("USLT = %v, expected language to be kept", m.Raw()["USLT"])
+	}
+
+	flac := tempCopy(t, "without_tags/sample.flac")
+	if err := WriteFLACTags(flac, map[string]string{"Lyrics": lyrics}); err != nil {
+		t.Fatalf("WriteFLACTags() = %v", err)
+	}
+	if _, err := flac.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadFLACTags(flac)
+	if err != nil {
+		t.Fatalf("ReadFLACTags() = %v", err)
+	}
+	testValue(t, lyrics, m.Lyrics())
+}
+
+func TestWriteID3v2KeepsEncoding(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	title := "Кино — Группа крови"
+
+	tests := []struct {
+		tag      []byte
+		encoding byte
+	}{
+		// UTF-16 ID3v2.3 tag, as written by Windows.
+		{testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: append([]byte{encodingUTF16WithBOM}, testUTF16WithBOM("Old")...)}), encodingUTF16WithBOM},
+		// ISO-8859-1 is upgraded to UTF-8 rather than losing characters.
+		{testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Old")}), encodingUTF8},
+		{nil, encodingUTF8},
+	}
+
+	for ii, tt := range tests {
+		mp3 := tempFile(t, append(tt.tag, audio...))
+		data := map[string]string{FieldTitle: title, FieldComment: title, "CUSTOM": title}
+		if err := WriteID3Both(mp3, data); err != nil {
+			t.Fatalf("[%d] WriteID3Both() = %v", ii, err)
+		}
+
+		mp3.Seek(0, io.SeekStart)
+		m, err := ReadFrom(mp3)
+		if err != nil {
+			t.Fatalf("[%d] ReadFrom() = %v", ii, err)
+		}
+		testValue(t, title, m.Title())
+		testValue(t, title, m.Comment())
+		if c, ok := m.Raw()["TXXX"].(*Comm); !ok || c.Description != "CUSTOM" || c.Text != title {
+			t.Errorf("[%d] TXXX = %v, expected CUSTOM: %v", ii, m.Raw()["TXXX"], title)
+		}
+
+		raw, err := readID3v2RawTag(mp3)
+		if err != nil {
+			t.Fatalf("[%d] readID3v2RawTag() = %v", ii, err)
+		}
+		for _, f := range raw.Frames {
+			if f.Data[0] != tt.encoding {
+				t.Errorf("[%d] %v encoding = %d, expected %d", ii, f.Name, f.Data[0], tt.encoding)
+			}
+		}
+	}
+}
+
+func TestWriteID3v2TagsKeepsFrames(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv := []byte("com.example.owner\x00\xff\x00private\x01")
+	mbid := []byte("\x00MusicBrainz Album Id\x00a7e4e12c-0000-4000-8000-000000000000")
+	isrc := []byte("\x00GBAYE6700149")
+
+	for _, version := range []byte{3, 4} {
+		// The tag alter preservation flag, which is one bit lower in ID3v2.4.
+		flags := [2]byte{0x40, 0}
+		if version == 3 {
+			flags[0] = 0x80
+		}
+		tag := &id3v2RawTag{Version: version, Frames: []id3v2RawFrame{
+			{Name: "TIT2", Data: []byte("\x00Old Title")},
+			{Name: "PRIV", Flags: flags, Data: priv},
+			{Name: "TXXX", Data: []byte("\x00Custom\x00Old Value")},
+			{Name: "TALB", Data: []byte("\x00Old Album")},
+			{Name: "TXXX", Data: mbid},
+			{Name: "TSRC", Data: isrc},
+		}}
+		mp3 := tempFile(t, append(tag.bytes(16), audio...))
+		err := WriteID3v2Tags(mp3, map[string]string{FieldTitle: "New Title", "custom": "New Value"})
+		if err != nil {
+			t.Fatalf("v2.%d: WriteID3v2Tags() = %v", version, err)
+		}
+
+		raw, err := readID3v2RawTag(mp3)
+		if err != nil {
+			t.Fatalf("v2.%d: readID3v2RawTag() = %v", version, err)
+		}
+		var names []string
+		for _, f := range raw.Frames {
+			names = append(names, f.Name)
+			switch f.Name {
+			case "PRIV":
+				if !bytes.Equal(f.Data, priv) {
+					t.Errorf("v2.%d: PRIV = %q, expected %q", version, f.Data, priv)
+				}
+				testValue(t, [2]byte{0x40, 0}, f.Flags)
+			case "TSRC":
+				if !bytes.Equal(f.Data, isrc) {
+					t.Errorf("v2.%d: TSRC = %q, expected %q", version, f.Data, isrc)
+				}
+			}
+		}
+		testValue(t, "TXXX TIT2 PRIV TALB TXXX TSRC", strings.Join(names, " "))
+		if !bytes.Equal(raw.Frames[4].Data, mbid) {
+			t.Errorf("v2.%d: TXXX = %q, expected %q", version, raw.Frames[4].Data, mbid)
+		}
+
+		mp3.Seek(0, io.SeekStart)
+		m, err := ReadFrom(mp3)
+		if err != nil {
+			t.Fatalf("v2.%d: ReadFrom() = %v", version, err)
+		}
+		testValue(t, "New Title", m.Title())
+		testValue(t, "Old Album", m.Album())
+	}
+}
+
+func TestWriteID3v2TagsConvertsV23Flags(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A compressed, encrypted and grouped ID3v2.3 frame: the decompressed size, encryption
+	// method and group identifier follow the header.
+	tag := &id3v2RawTag{Version: 3, Frames: []id3v2RawFrame{
+		{Name: "PRIV", Flags: [2]byte{0, 0xE0}, Data: []byte("\x00\x00\x01\x00\x80\x81data")},
+	}}
+	mp3 := tempFile(t, append(tag.bytes(16), audio...))
+	if err := WriteID3v2Tags(mp3, map[string]string{FieldTitle: "Title"}); err != nil {
+		t.Fatalf("WriteID3v2Tags() = %v", err)
+	}
+
+	raw, err := readID3v2RawTag(mp3)
+	if err != nil {
+		t.Fatalf("readID3v2RawTag() = %v", err)
+	}
+	if len(raw.Frames) != 2 {
+		t.Fatalf("len(raw.Frames) = %d, expected 2", len(raw.Frames))
+	}
+	f := raw.Frames[1]
+	testValue(t, "PRIV", f.Name)
+	testValue(t, [2]byte{0, 0x4D}, f.Flags)
+	testValue(t, "\x81\x80\x00\x00\x02\x00data", string(f.Data))
+}
+
+func TestWriteID3v2TagsConvertsV22Frames(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := testImage(t, "png", 2, 2)
+
+	tag := &id3v2RawTag{Version: 2, Frames: []id3v2RawFrame{
+		{Name: "TT2", Data: []byte("\x00Old Title")},
+		{Name: "TAL", Data: []byte("\x00Album")},
+		{Name: "PIC", Data: append([]byte("\x00PNG\x03Cover\x00"), img...)},
+	}}
+	mp3 := tempFile(t, append(tag.bytes(16), audio...))
+	if err := WriteID3v2Tags(mp3, map[string]string{FieldTitle: "New Title"}); err != nil {
+		t.Fatalf("WriteID3v2Tags() = %v", err)
+	}
+
+	mp3.Seek(0, io.SeekStart)
+	m, err := ReadFrom(mp3)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, ID3v2_4, m.Format())
+	testValue(t, "New Title", m.Title())
+	testValue(t, "Album", m.Album())
+
+	p := m.Picture()
+	if p == nil {
+		t.Fatal("Picture() = nil")
+	}
+	testValue(t, "image/png", p.MIMEType)
+	testValue(t, "Cover", p.Description)
+	testValue(t, "Cover (front)", p.Type)
+	if !bytes.Equal(p.Data, img) {
+		t.Errorf("Picture().Data = %q, expected %q", p.Data, img)
+	}
+
+	// Frames which can't be converted are an error, rather than being dropped.
+	tag.Frames = append(tag.Frames, id3v2RawFrame{Name: "CRM", Data: []byte("owner\x00\x00data")})
+	mp3 = tempFile(t, append(tag.bytes(16), audio...))
+	if err := WriteID3v2Tags(mp3, map[string]string{FieldTitle: "New Title"}); err == nil {
+		t.Error("WriteID3v2Tags() = nil, expected error for CRM frame")
+	}
+}
+
+func TestRepairID3v2Size(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Data: id3v24TextFrame(encodingUTF8, "Title")},
+		id3v2RawFrame{Name: "TPE1", Data: id3v24TextFrame(encodingUTF8, "Artist")},
+	)
+	size := len(tag) - 10
+
+	for _, declared := range []int{size, size + 100, size - 20} {
+		b := append(append([]byte{}, tag...), audio...)
+		copy(b[6:10], format7BitChunkedUint(uint(declared), 4))
+		f := tempFile(t, b)
+
+		if err := RepairID3v2Size(f); err != nil {
+			t.Errorf("[%d] RepairID3v2Size() = %v", declared, err)
+			continue
+		}
+		got := readAll(t, f)
+		if !bytes.Equal(got[:len(tag)], tag) || !bytes.Equal(got[len(tag):], audio) {
+			t.Errorf("[%d] RepairID3v2Size() did not restore the tag size", declared)
+			continue
+		}
+
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", declared, err)
+			continue
+		}
+		testValue(t, "Title", m.Title())
+		testValue(t, "Artist", m.Artist())
+	}
+}
+
+// testUnsync returns b with the unsynchronisation scheme applied.
+func testUnsync(b []byte) []byte {
+	var out []byte
+	for i, c := range b {
+		out = append(out, c)
+		if c == 0xFF && (i+1 == len(b) || b[i+1] == 0 || b[i+1]&0xE0 == 0xE0) {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func TestRemoveUnsync(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const title = "Title ÿà" // 0xFF 0xE0 in ISO-8859-1
+	tit2 := append([]byte{encodingISO8859}, "Title \xff\xe0"...)
+
+	// ID3v2.3 with tag-level unsynchronisation.
+	v3 := testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: tit2})
+	frames := testUnsync(v3[10 : len(v3)-16])
+	v3 = append(append([]byte("ID3\x03\x00\x80"), format7BitChunkedUint(uint(len(frames)+16), 4)...), frames...)
+	v3 = append(v3, make([]byte, 16)...)
+
+	// ID3v2.4 with frame-level unsynchronisation.
+	v4 := (&id3v2RawTag{Version: 4, Flags: 0x80, Frames: []id3v2RawFrame{
+		{Name: "TIT2", Flags: [2]byte{0, 0x02}, Data: testUnsync(tit2)},
+	}}).bytes(16)
+
+	for ii, tag := range [][]byte{v3, v4} {
+		f := tempFile(t, append(append([]byte{}, tag...), audio...))
+		if err := RemoveUnsync(f); err != nil {
+			t.Errorf("[%d] RemoveUnsync() = %v", ii, err)
+			continue
+		}
+
+		b := readAll(t, f)
+		if len(b) != len(tag)+len(audio) || !bytes.Equal(b[len(tag):], audio) {
+			t.Errorf("[%d] RemoveUnsync() moved the audio data", ii)
+		}
+		if unsync, err := id3v2Unsynchronised(f); err != nil || unsync {
+			t.Errorf("[%d] id3v2Unsynchronised() = %v, %v, expected: false, nil", ii, unsync, err)
+		}
+
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", ii, err)
+			continue
+		}
+		testValue(t, title, m.Title())
+	}
+
+	// A tag without unsynchronisation is unchanged.
+	orig := append(testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: tit2}), audio...)
+	f := tempFile(t, orig)
+	if err := RemoveUnsync(f); err != nil {
+		t.Fatalf("RemoveUnsync() = %v", err)
+	}
+	if !bytes.Equal(readAll(t, f), orig) {
+		t.Errorf("RemoveUnsync() changed a tag without unsynchronisation")
+	}
+}
+
+func TestWriteID3v2Values(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3 := tempFile(t, audio)
+	genres := []string{"Rock", "Jazz", "Pop"}
+	if err := WriteID3v2Values(mp3, map[string][]string{FieldGenre: genres, FieldTitle: {"Title"}}); err != nil {
+		t.Fatalf("WriteID3v2Values() = %v", err)
+	}
+
+	raw, err := readID3v2RawTag(mp3)
+	if err != nil {
+		t.Fatalf("readID3v2RawTag() = %v", err)
+	}
+	for _, f := range raw.Frames {
+		if f.Name == "TCON" {
+			testValue(t, "\x03Rock\x00Jazz\x00Pop", string(f.Data))
+		}
+	}
+
+	mp3.Seek(0, io.SeekStart)
+	m, err := ReadFrom(mp3)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	mv, ok := m.(MultiValueMetadata)
+	if !ok {
+		t.Fatalf("%T does not implement MultiValueMetadata", m)
+	}
+	if got := mv.Values(FieldGenre); !reflect.DeepEqual(got, genres) {
+		t.Errorf("Values(FieldGenre) = %q, expected %q", got, genres)
+	}
+	testValue(t, "Title", strings.Join(mv.Values(FieldTitle), "|"))
+	testValue(t, "Rock;Jazz;Pop", m.Genre())
+	testValue(t, "Title", m.Title())
+
+	if err := WriteID3v2Values(mp3, map[string][]string{FieldComment: {"a", "b"}}); err == nil {
+		t.Errorf("WriteID3v2Values() with two comments = nil, expected error")
+	}
+}
@@ -0,0 +1,64 @@
+package tag
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSniffFormat(t *testing.T) {
+	tests := []struct {
+		b        []byte
+		format   Format
+		fileType FileType
+	}{
+		{[]byte("fLaC\x00\x00\x00\x22\x00\x00\x00\x00"), VORBIS, FLAC},
+		{[]byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00"), VORBIS, OGG},
+		{[]byte("\x00\x00\x00\x20ftypM4A \x00"), MP4, M4A},
+		{[]byte("\x00\x00\x00\x20ftypM4B \x00"), MP4, M4B},
+		{[]byte("\x00\x00\x00\x20ftypM4P \x00"), MP4, M4P},
+		{[]byte("\x00\x00\x00\x20ftypmp42\x00"), MP4, UnknownFileType},
+		{[]byte("ID3\x02\x00\x00\x00\x00\x00\x00\x00\x00"), ID3v2_2, MP3},
+		{[]byte("ID3\x03\x00\x00\x00\x00\x00\x00\x00\x00"), ID3v2_3, MP3},
+		{[]byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"), ID3v2_4, MP3},
+		{[]byte("\xFF\xFB\x90\x64\x00\x00\x00\x00\x00\x00\x00\x00"), UnknownFormat, MP3},
+		{[]byte("DSD \x1c\x00\x00\x00\x00\x00\x00\x00"), UnknownFormat, DSF},
+		{[]byte("FORM\x00\x00\x10\x00AIFF"), UnknownFormat, AIFF},
+		{[]byte("FORM\x00\x00\x10\x00AIFC"), UnknownFormat, AIFF},
+		{[]byte("FRM8\x00\x00\x00\x00\x00\x00\x10\x00DSD "), UnknownFormat, DFF},
+		{[]byte("RIFF\x00\x00\x10\x00WAVE"), UnknownFormat, UnknownFileType},
+	}
+
+	for _, tt := range tests {
+		r := bytes.NewReader(append([]byte("prefix"), tt.b...))
+		r.Seek(6, io.SeekStart)
+		format, fileType, err := sniffFormat(r)
+		if err != nil {
+			t.Errorf("sniffFormat(%q) = %v", tt.b, err)
+			continue
+		}
+		if format != tt.format || fileType != tt.fileType {
+			t.Errorf("sniffFormat(%q) = %v, %v, expected %v, %v", tt.b, format, fileType, tt.format, tt.fileType)
+		}
+		if pos, _ := r.Seek(0, io.SeekCurrent); pos != 6 {
+			t.Errorf("sniffFormat(%q) left position %d, expected 6", tt.b, pos)
+		}
+	}
+
+	if _, _, err := sniffFormat(bytes.NewReader([]byte("ID3\x01\x00\x00\x00\x00\x00\x00\x00"))); err == nil {
+		t.Errorf("sniffFormat() = nil, expected error for ID3 version 1")
+	}
+	if _, _, err := sniffFormat(bytes.NewReader([]byte("fLaC"))); err == nil {
+		t.Errorf("sniffFormat() = nil, expected error for short data")
+	}
+}
+
+func BenchmarkSniffFormat(b *testing.B) {
+	r := bytes.NewReader([]byte("FORM\x00\x00\x10\x00AIFF\x00\x00\x00\x00"))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := sniffFormat(r); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
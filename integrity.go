@@ -0,0 +1,155 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// IssueKind is the kind of problem found by CheckIntegrity.
+type IssueKind string
+
+// Kinds of Issue.
+const (
+	IssueOverlap       IssueKind = "overlap"        // Two metadata regions overlap.
+	IssuePastEOF       IssueKind = "past EOF"       // A declared size extends past the end of the data.
+	IssueNoLastBlock   IssueKind = "no last block"  // No FLAC metadata block is flagged as the last.
+	IssueDuplicateTags IssueKind = "duplicate tags" // More than one tag system, i.e. ID3v2 in a FLAC file.
+	IssueUnreadable    IssueKind = "unreadable"     // The data could not be read.
+)
+
+// Issue is a structural problem found by CheckIntegrity.
+type Issue struct {
+	Kind    IssueKind
+	Offset  int64 // Offset of the problem (i.e. of a tag or block header) in the data.
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (i Issue) String() string {
+	return fmt.Sprintf("%v at offset %d: %v", i.Kind, i.Offset, i.Message)
+}
+
+// metadataRegion is the position of a tag (or of the FLAC metadata blocks) in the data.
+type metadataRegion struct {
+	name       string
+	start, end int64
+}
+
+// CheckIntegrity checks the structure of the metadata in r, returning the problems found in
+// order of offset (or nil if there are none): metadata regions which overlap (i.e. an ID3v2 tag
+// whose size includes a trailing ID3v1 tag), tag and block sizes which extend past the end of
+// the data, FLAC metadata without a block flagged as the last, and more than one tag system
+// (ID3v2, ID3v1 or APEv2 tags in a FLAC file, or an APEv2 tag as well as ID3 tags in an MP3
+// file).  Only the ID3v2 tag at the start of the data, the FLAC metadata blocks and the APEv2
+// and ID3v1 tags at the end of the data are checked.
+func CheckIntegrity(r io.ReadSeeker) []Issue {
+	var issues []Issue
+	add := func(k IssueKind, offset int64, format string, args ...interface{}) {
+		issues = append(issues, Issue{Kind: k, Offset: offset, Message: fmt.Sprintf(format, args...)})
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return []Issue{{Kind: IssueUnreadable, Message: err.Error()}}
+	}
+	ra := readerAt{r}
+	readAt := func(offset int64, n int) []byte {
+		if offset < 0 || offset+int64(n) > size {
+			return nil
+		}
+		b := make([]byte, n)
+		if _, err := ra.ReadAt(b, offset); err != nil {
+			add(IssueUnreadable, offset, "%v", err)
+			return nil
+		}
+		return b
+	}
+
+	var regions []metadataRegion
+	var offset int64
+	id3, err := id3v2TagSize(r)
+	if err != nil {
+		add(IssueUnreadable, 0, "%v", err)
+	}
+	if id3 > 0 {
+		regions = append(regions, metadataRegion{"ID3v2 tag", 0, id3})
+		if id3 > size {
+			add(IssuePastEOF, 0, "ID3v2 tag size %d extends past the end of the data (%d bytes)", id3, size)
+		}
+		offset = id3
+	}
+
+	flac := string(readAt(offset, 4)) == "fLaC"
+	if flac {
+		if id3 > 0 {
+			add(IssueDuplicateTags, 0, "ID3v2 tag before FLAC data")
+		}
+		pos, prev := offset+4, int64(-1)
+		for {
+			h := readAt(pos, 4)
+			if h == nil {
+				if prev >= 0 && pos == size {
+					add(IssueNoLastBlock, prev, "FLAC metadata block at the end of the data is not flagged as the last")
+				}
+				break
+			}
+			if prev >= 0 && h[0] == 0xFF && h[1]&0xFE == 0xF8 {
+				add(IssueNoLastBlock, prev, "FLAC metadata block followed by an audio frame is not flagged as the last")
+				break
+			}
+
+			prev, pos = pos, pos+4+int64(getInt(h[1:4]))
+			if pos > size {
+				add(IssuePastEOF, prev, "FLAC %v block size %d extends past the end of the data (%d bytes)", blockType(h[0]&0x7F), pos-prev-4, size)
+				pos = size
+				break
+			}
+			if h[0]&0x80 != 0 {
+				break
+			}
+		}
+		regions = append(regions, metadataRegion{"FLAC metadata", offset, pos})
+	}
+
+	end := size
+	if b := readAt(size-id3v1Size, 3); string(b) == "TAG" {
+		end -= id3v1Size
+		regions = append(regions, metadataRegion{"ID3v1 tag", end, size})
+		if flac {
+			add(IssueDuplicateTags, end, "ID3v1 tag after FLAC data")
+		}
+	}
+	if b := readAt(end-apeFooterSize, apeFooterSize); string(b[:minInt(len(b), 8)]) == "APETAGEX" {
+		n := int64(binary.LittleEndian.Uint32(b[12:16]))
+		if flags := binary.LittleEndian.Uint32(b[20:24]); flags&(1<<31) != 0 {
+			n += apeFooterSize
+		}
+		start := end - n
+		if start < 0 {
+			start = 0
+		}
+		regions = append(regions, metadataRegion{"APEv2 tag", start, end})
+		switch {
+		case flac:
+			add(IssueDuplicateTags, start, "APEv2 tag after FLAC data")
+		case id3 > 0 || end != size:
+			add(IssueDuplicateTags, start, "APEv2 tag as well as ID3 tags")
+		}
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].start < regions[j].start })
+	for i := 1; i < len(regions); i++ {
+		if p := regions[i-1]; regions[i].start < p.end {
+			add(IssueOverlap, regions[i].start, "%v overlaps %v (which ends at offset %d)", regions[i].name, p.name, p.end)
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Offset < issues[j].Offset })
+	return issues
+}
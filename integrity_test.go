@@ -0,0 +1,79 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	flac, err := os.ReadFile("testdata/with_tags/sample.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id3v2 := testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")})
+	id3v1 := append([]byte("TAG"), make([]byte, id3v1Size-3)...)
+
+	// Offset of the last FLAC metadata block header.
+	last := 4
+	for flac[last]&0x80 == 0 {
+		last += 4 + getInt(flac[last+1:last+4])
+	}
+
+	cat := func(bs ...[]byte) []byte {
+		var res []byte
+		for _, b := range bs {
+			res = append(res, b...)
+		}
+		return res
+	}
+
+	// An ID3v2 tag whose size includes the ID3v1 tag at the end of the data.
+	overlap := cat(id3v2, mp3, id3v1)
+	copy(overlap[6:10], format7BitChunkedUint(uint(len(overlap)-10), 4))
+
+	// An ID3v2 tag with a size past the end of the data.
+	pastEOF := cat(id3v2)
+	copy(pastEOF[6:10], format7BitChunkedUint(1<<20, 4))
+
+	noLast := cat(flac)
+	noLast[last] &^= 0x80
+
+	type issue struct {
+		Kind   IssueKind
+		Offset int64
+	}
+	tests := []struct {
+		name string
+		b    []byte
+		want []issue
+	}{
+		{"FLAC", flac, nil},
+		{"MP3", mp3, nil},
+		{"ID3v2 and ID3v1", cat(id3v2, mp3, id3v1), nil},
+		{"overlap", overlap, []issue{{IssueOverlap, int64(len(overlap) - id3v1Size)}}},
+		{"ID3v2 past EOF", pastEOF, []issue{{IssuePastEOF, 0}}},
+		{"FLAC block past EOF", flac[:4+4+20], []issue{{IssuePastEOF, 4}}},
+		{"FLAC without last block", noLast, []issue{{IssueNoLastBlock, int64(last)}}},
+		{"ID3v2 in FLAC", cat(id3v2, flac), []issue{{IssueDuplicateTags, 0}}},
+		{"ID3v1 in FLAC", cat(flac, id3v1), []issue{{IssueDuplicateTags, int64(len(flac))}}},
+	}
+	for _, tt := range tests {
+		var got []issue
+		for _, i := range CheckIntegrity(bytes.NewReader(tt.b)) {
+			got = append(got, issue{i.Kind, i.Offset})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: CheckIntegrity() = %v, expected %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ITunesInfo is the set of library fields used by iTunes (and media servers such as Plex
+// which follow its behaviour) to group tracks into albums.
+type ITunesInfo struct {
+	AlbumArtist     string
+	AlbumArtistSort string // Sort order of the album artist.
+	Compilation     bool   // The track is part of a compilation.
+	Grouping        string
+}
+
+// parseCompilation returns true if s is a true compilation flag value ("1").
+func parseCompilation(s string) bool {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n != 0
+}
+
+// PurchaseDateMetadata is implemented by the Metadata returned for MP4 files.
+type PurchaseDateMetadata interface {
+	// PurchaseDate returns the date the track was bought from the iTunes Store (in UTC), the
+	// boolean is false if there is no purd atom or the date is not recognised.
+	PurchaseDate() (time.Time, bool)
+}
+
+func (m metadataMP4) PurchaseDate() (time.Time, bool) {
+	return parsePurchaseDate(m.getString(atoms.Name("purchase_date")))
+}
+
+// purchaseDateLayouts are the layouts of the purchase dates written by iTunes (i.e.
+// "2009-07-21 13:08:16") and other software.
+var purchaseDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parsePurchaseDate returns the date s in one of purchaseDateLayouts.
+func parsePurchaseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, l := range purchaseDateLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,177 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestITunesTags(t *testing.T) {
+	want := ITunesInfo{
+		AlbumArtist:     "Various Artists",
+		AlbumArtistSort: "Various",
+		Compilation:     true,
+		Grouping:        "Summer",
+	}
+
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Frames as written by iTunes 12 (ID3v2.3) for a track in a compilation.
+	id3 := append(testID3v2Tag(
+		id3v2RawFrame{Name: "TPE2", Data: []byte("\x00Various Artists")},
+		id3v2RawFrame{Name: "TSO2", Data: []byte("\x00Various")},
+		id3v2RawFrame{Name: "TCMP", Data: []byte("\x001")},
+		id3v2RawFrame{Name: "TIT1", Data: []byte("\x00Old Grouping")},
+		id3v2RawFrame{Name: "GRP1", Data: []byte("\x00Summer")},
+	), audio...)
+
+	mp4 := tempCopy(t, "without_tags/sample.m4a")
+	addTestMP4Items(t, mp4,
+		mp4Item("aART", 1, []byte("Various Artists")),
+		mp4Item("soaa", 1, []byte("Various")),
+		mp4Item("cpil", 21, []byte{1}),
+		mp4Item("\xa9grp", 1, []byte("Summer")),
+	)
+
+	flac := tempCopy(t, "without_tags/sample.flac")
+	if err := WriteFLACTags(flac, map[string]string{
+		"ALBUMARTIST":     "Various Artists",
+		"ALBUMARTISTSORT": "Various",
+		"COMPILATION":     "1",
+		"GROUPING":        "Summer",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		r    io.ReadSeeker
+	}{
+		{"ID3v2", bytes.NewReader(id3)},
+		{"MP4", mp4},
+		{"FLAC", flac},
+	}
+	for _, tt := range tests {
+		if _, err := tt.r.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(tt.r)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", tt.name, err)
+		}
+		if got := m.ITunesTags(); got != want {
+			t.Errorf("%v: ITunesTags() = %+v, expected %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestITunesTagsNotCompilation(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.id3v24.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := m.ITunesTags()
+	testValue(t, false, got.Compilation)
+	testValue(t, m.AlbumArtist(), got.AlbumArtist)
+}
+
+func TestContentGroupAndWork(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Frames as written by Apple Music (iTunes 12.5.2 and later) for a classical track, and
+	// by earlier versions which only have TIT1.
+	modern := append(testID3v2Tag(
+		id3v2RawFrame{Name: "TIT1", Data: []byte("\x00Symphony No. 5")},
+		id3v2RawFrame{Name: "GRP1", Data: []byte("\x00Beethoven")},
+	), audio...)
+	old := append(testID3v2Tag(
+		id3v2RawFrame{Name: "TIT1", Data: []byte("\x00Beethoven")},
+	), audio...)
+
+	mp4 := tempCopy(t, "without_tags/sample.m4a")
+	addTestMP4Items(t, mp4,
+		mp4Item("\xa9grp", 1, []byte("Beethoven")),
+		mp4Item("\xa9wrk", 1, []byte("Symphony No. 5")),
+	)
+
+	tests := []struct {
+		name  string
+		r     io.ReadSeeker
+		group string
+		work  string
+	}{
+		{"ID3v2", bytes.NewReader(modern), "Beethoven", "Symphony No. 5"},
+		{"ID3v2 (old)", bytes.NewReader(old), "Beethoven", ""},
+		{"MP4", mp4, "Beethoven", "Symphony No. 5"},
+	}
+	for _, tt := range tests {
+		if _, err := tt.r.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(tt.r)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", tt.name, err)
+		}
+		if got := m.ContentGroup(); got != tt.group {
+			t.Errorf("%v: ContentGroup() = %q, expected %q", tt.name, got, tt.group)
+		}
+		if got := m.Work(); got != tt.work {
+			t.Errorf("%v: Work() = %q, expected %q", tt.name, got, tt.work)
+		}
+	}
+}
+
+func TestPurchaseDate(t *testing.T) {
+	// The purd atom of a track bought from the iTunes Store.
+	f := tempCopy(t, "without_tags/sample.m4a")
+	addTestMP4Items(t, f, mp4Item("purd", 1, []byte("2009-07-21 13:08:16")))
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	p, ok := m.(PurchaseDateMetadata)
+	if !ok {
+		t.Fatalf("%T does not implement PurchaseDateMetadata", m)
+	}
+	got, ok := p.PurchaseDate()
+	if want := time.Date(2009, 7, 21, 13, 8, 16, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Errorf("PurchaseDate() = %v, %v, expected %v, true", got, ok, want)
+	}
+
+	tests := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"2015-01-02T03:04:05Z", time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC), true},
+		{"2015-01-02T03:04:05+01:00", time.Date(2015, 1, 2, 2, 4, 5, 0, time.UTC), true},
+		{"2015-01-02", time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC), true},
+		{"", time.Time{}, false},
+		{"yesterday", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parsePurchaseDate(tt.in)
+		if ok != tt.ok || !got.Equal(tt.want) {
+			t.Errorf("parsePurchaseDate(%q) = %v, %v, expected %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import "sort"
+
+// Keys returns the sorted keys of the native tags in m (the keys of Raw), i.e. the ID3v2
+// frame IDs, MP4 atom names or lower case Vorbis comment field names (including "vendor").
+func Keys(m Metadata) []string {
+	raw := m.Raw()
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
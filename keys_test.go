@@ -0,0 +1,21 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	m := testReadFLAC(t, tempCopy(t, "with_tags/sample.flac"))
+	want := []string{
+		"album", "albumartist", "artist", "composer", "date", "description", "discnumber",
+		"genre", "title", "tracknumber", "tracktotal", "vendor",
+	}
+	if got := Keys(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %q, expected: %q", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import "strings"
+
+// LAMEInfo is the encoder information stored by LAME (and compatible encoders) in the
+// Xing/Info header of an MP3 file (see http://gabriel.mp3-tech.org/mp3infotag.html).
+type LAMEInfo struct {
+	Version   string // Encoder version, i.e. "LAME3.100".
+	VBRMethod string // Bitrate mode (see lameVBRMethods), or an empty string if unknown.
+
+	// Quality is the quality indicator from the Xing header, from 0 to 100 (best), or -1 if
+	// unavailable.  LAME writes 100 - 10*V - q, for VBR quality V (-V) and algorithm
+	// quality q (-q).
+	Quality int
+}
+
+// lameVBRMethods are the names of the VBR method values in the LAME extension.
+var lameVBRMethods = map[byte]string{
+	1: "CBR",
+	2: "ABR",
+	3: "VBR (rh)",
+	4: "VBR (mtrh)",
+	5: "VBR (mt)",
+	8: "CBR (2 pass)",
+	9: "ABR (2 pass)",
+}
+
+// LAMEMetadata is implemented by the Metadata returned for MP3 files, which describe the
+// encoder settings of LAME encoded audio.
+type LAMEMetadata interface {
+	// LAME returns the encoder information, the boolean is false if the first audio frame
+	// doesn't have a LAME extension.
+	LAME() (LAMEInfo, bool)
+}
+
+// readLAMEInfo returns the encoder information from the Xing header x.
+func readLAMEInfo(x *xingHeader) (LAMEInfo, bool) {
+	if x == nil || len(x.LAME) < xingLAMESize {
+		return LAMEInfo{}, false
+	}
+	return LAMEInfo{
+		Version:   strings.TrimRight(string(x.LAME[0:9]), "\x00 "),
+		VBRMethod: lameVBRMethods[x.LAME[9]&0x0F],
+		Quality:   x.Quality,
+	}, true
+}
+
+func (m metadataID3v2) LAME() (LAMEInfo, bool) {
+	return readLAMEInfo(m.xing)
+}
@@ -0,0 +1,47 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestLAME(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		frame []byte
+		info  LAMEInfo
+		ok    bool
+	}{
+		{testLAMEFrame("LAME3.100", 57, 0x04, 576, 1000), LAMEInfo{"LAME3.100", "VBR (mtrh)", 57}, true},
+		{testLAMEFrame("LAME3.99r", 78, 0x11, 576, 1000), LAMEInfo{"LAME3.99r", "CBR", 78}, true},
+		{testLAMEFrame("LAME3.98 ", 100, 0x02, 576, 1000), LAMEInfo{"LAME3.98", "ABR", 100}, true},
+		{testLAMEFrame("Lavc58.91", 0, 0x00, 0, 0), LAMEInfo{"Lavc58.91", "", 0}, true},
+		{nil, LAMEInfo{}, false},
+	}
+
+	for ii, tt := range tests {
+		b := append(testID3v2Tag(), append(tt.frame, audio...)...)
+		m, err := ReadFrom(bytes.NewReader(b))
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", ii, err)
+			continue
+		}
+		lm, ok := m.(LAMEMetadata)
+		if !ok {
+			t.Fatalf("[%d] %T does not implement LAMEMetadata", ii, m)
+		}
+		info, ok := lm.LAME()
+		if info != tt.info || ok != tt.ok {
+			t.Errorf("[%d] LAME() = %+v, %v, expected %+v, %v", ii, info, ok, tt.info, tt.ok)
+		}
+	}
+}
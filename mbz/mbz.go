@@ -18,6 +18,8 @@ const (
 	Disc              = "musicbrainz_discid"
 	Recording         = "musicbrainz_recordingid"
 	ReleaseGroup      = "musicbrainz_releasegroupid"
+	ReleaseStatus     = "releasestatus" // i.e. "official" or "promotion"
+	ReleaseType       = "releasetype"   // i.e. "album", "single", "ep" or "live"
 	Track             = "musicbrainz_trackid"
 	TRM               = "musicbrainz_trmid"
 )
@@ -35,10 +37,19 @@ var tags = map[string]string{
 	Disc:              "MusicBrainz Disc Id",
 	Recording:         "MusicBrainz Track Id",
 	ReleaseGroup:      "MusicBrainz Release Group Id",
+	ReleaseStatus:     "MusicBrainz Album Status",
+	ReleaseType:       "MusicBrainz Album Type",
 	Track:             "MusicBrainz Release Track Id",
 	TRM:               "MusicBrainz TRM Id",
 }
 
+// Names used by older versions of Picard (in Vorbis comments), which are only used if the
+// current name isn't set.
+var legacyTags = map[string]string{
+	"musicbrainz_albumstatus": ReleaseStatus,
+	"musicbrainz_albumtype":   ReleaseType,
+}
+
 // Info is a structure which contains MusicBrainz identifier information.
 type Info map[string]string
 
@@ -47,19 +58,34 @@ func (i Info) Get(tag string) string {
 	return i[tag]
 }
 
-// set the MusicBrainz tag to the given value.
-func (i Info) set(t, v string) {
-	if _, ok := tags[t]; ok {
-		i[t] = v
-		return
-	}
+// ReleaseType returns the MusicBrainz release type (the ReleaseType tag).
+func (i Info) ReleaseType() string {
+	return i[ReleaseType]
+}
+
+// ReleaseStatus returns the MusicBrainz release status (the ReleaseStatus tag).
+func (i Info) ReleaseStatus() string {
+	return i[ReleaseStatus]
+}
 
+// set the MusicBrainz tag to the given value.  Tag names are matched case-insensitively, as
+// some taggers write them in upper case.
+func (i Info) set(t, v string) {
 	for k, tt := range tags {
-		if tt == t {
+		if strings.EqualFold(k, t) || strings.EqualFold(tt, t) {
 			i[k] = v
 			return
 		}
 	}
+
+	for lt, k := range legacyTags {
+		if strings.EqualFold(lt, t) {
+			if _, ok := i[k]; !ok {
+				i[k] = v
+			}
+			return
+		}
+	}
 }
 
 // extractID3 attempts to extract MusicBrainz Picard tags from m.Raw(), where m.Format
@@ -0,0 +1,84 @@
+package mbz
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+// tempCopy returns a copy of the test file at path (relative to the testdata directory).
+func tempCopy(t *testing.T, path string) *os.File {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "testdata", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.CreateTemp(t.TempDir(), "mbz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestReleaseTypeAndStatus(t *testing.T) {
+	tests := []struct {
+		path  string
+		write func(io.ReadWriteSeeker, map[string]string) error
+		data  map[string]string
+	}{
+		// TXXX frames, as written by Picard.
+		{"without_tags/sample.mp3", tag.WriteID3v2Tags, map[string]string{
+			"MusicBrainz Album Type":   "live",
+			"MusicBrainz Album Status": "official",
+		}},
+		// "----" items, as written by Picard.
+		{"without_tags/sample.m4a", tag.WriteMP4Tags, map[string]string{
+			"MusicBrainz Album Type":   "live",
+			"MusicBrainz Album Status": "official",
+		}},
+		{"without_tags/sample.flac", tag.WriteFLACTags, map[string]string{
+			"RELEASETYPE":   "live",
+			"RELEASESTATUS": "official",
+		}},
+		// Older versions of Picard.
+		{"without_tags/sample.flac", tag.WriteFLACTags, map[string]string{
+			"MUSICBRAINZ_ALBUMTYPE":   "live",
+			"MUSICBRAINZ_ALBUMSTATUS": "official",
+		}},
+		// The current names are preferred.
+		{"without_tags/sample.flac", tag.WriteFLACTags, map[string]string{
+			"MUSICBRAINZ_ALBUMTYPE": "album",
+			"RELEASETYPE":           "live",
+			"RELEASESTATUS":         "official",
+		}},
+	}
+
+	for _, tt := range tests {
+		f := tempCopy(t, tt.path)
+		if err := tt.write(f, tt.data); err != nil {
+			t.Fatalf("%v: write = %v", tt.path, err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		m, err := tag.ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", tt.path, err)
+		}
+
+		i := Extract(m)
+		if got := i.ReleaseType(); got != "live" {
+			t.Errorf("%v: ReleaseType() = %q, expected %q", tt.path, got, "live")
+		}
+		if got := i.ReleaseStatus(); got != "official" {
+			t.Errorf("%v: ReleaseStatus() = %q, expected %q", tt.path, got, "official")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MediaKind is the kind of media in an MP4 file (the stik atom), used by Apple apps to decide
+// where the file appears in the library.
+type MediaKind byte
+
+// Media kinds written by iTunes.
+const (
+	MediaKindMovieLegacy MediaKind = 0 // Movie (before iTunes 9).
+	MediaKindMusic       MediaKind = 1
+	MediaKindAudiobook   MediaKind = 2
+	MediaKindMusicVideo  MediaKind = 6
+	MediaKindMovie       MediaKind = 9
+	MediaKindTVShow      MediaKind = 10
+	MediaKindBooklet     MediaKind = 11
+	MediaKindRingtone    MediaKind = 14
+	MediaKindPodcast     MediaKind = 21
+	MediaKindITunesU     MediaKind = 23
+)
+
+var mediaKindNames = map[MediaKind]string{
+	MediaKindMovieLegacy: "Movie (legacy)",
+	MediaKindMusic:       "Music",
+	MediaKindAudiobook:   "Audiobook",
+	MediaKindMusicVideo:  "Music Video",
+	MediaKindMovie:       "Movie",
+	MediaKindTVShow:      "TV Show",
+	MediaKindBooklet:     "Booklet",
+	MediaKindRingtone:    "Ringtone",
+	MediaKindPodcast:     "Podcast",
+	MediaKindITunesU:     "iTunes U",
+}
+
+// String returns the name of the media kind, or its number if it isn't known.
+func (k MediaKind) String() string {
+	if n, ok := mediaKindNames[k]; ok {
+		return n
+	}
+	return strconv.Itoa(int(k))
+}
+
+// parseMediaKind parses a media kind given as a number or a name (as returned by String,
+// ignoring case).
+func parseMediaKind(s string) (MediaKind, bool) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return MediaKind(n), true
+	}
+	for k, n := range mediaKindNames {
+		if strings.EqualFold(n, s) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// MediaKindMetadata is implemented by the Metadata returned for MP4 files.
+type MediaKindMetadata interface {
+	// MediaKind returns the kind of media, the boolean is false if there is no stik atom.
+	MediaKind() (MediaKind, bool)
+}
+
+func (m metadataMP4) MediaKind() (MediaKind, bool) {
+	k, ok := m.data["stik"].(int)
+	return MediaKind(k), ok
+}
@@ -0,0 +1,71 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// testM4B returns a copy of the sample M4A file with the M4B brand and a stik item.
+func testM4B(t *testing.T, stik byte) *os.File {
+	t.Helper()
+	f := tempCopy(t, "with_tags/sample.m4a")
+	if _, err := f.WriteAt([]byte("ftypM4B "), 4); err != nil {
+		t.Fatal(err)
+	}
+	addTestMP4Items(t, f, mp4Item("stik", mp4ClassInt, []byte{stik}))
+	f.Seek(0, io.SeekStart)
+	return f
+}
+
+func TestMediaKind(t *testing.T) {
+	f := testM4B(t, 2)
+	_, fileType, err := Identify(f)
+	if err != nil {
+		t.Fatalf("Identify() = %v", err)
+	}
+	testValue(t, M4B, fileType)
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	k, ok := m.(MediaKindMetadata).MediaKind()
+	testValue(t, true, ok)
+	testValue(t, MediaKindAudiobook, k)
+	testValue(t, "Audiobook", k.String())
+
+	m, err = ReadFrom(tempCopy(t, "with_tags/sample.m4a"))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if _, ok := m.(MediaKindMetadata).MediaKind(); ok {
+		t.Errorf("MediaKind() ok = true for a file without a stik item")
+	}
+}
+
+func TestWriteMediaKind(t *testing.T) {
+	for _, v := range []string{"2", "audiobook"} {
+		f := tempCopy(t, "without_tags/sample.m4a")
+		if err := WriteMP4Tags(f, map[string]string{FieldMediaKind: v}); err != nil {
+			t.Fatalf("WriteMP4Tags(%q) = %v", v, err)
+		}
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("ReadFrom() = %v", err)
+		}
+		k, ok := m.(MediaKindMetadata).MediaKind()
+		testValue(t, true, ok)
+		testValue(t, MediaKindAudiobook, k)
+	}
+
+	f := tempCopy(t, "without_tags/sample.m4a")
+	if err := WriteMP4Tags(f, map[string]string{FieldMediaKind: "vinyl"}); err == nil {
+		t.Errorf("WriteMP4Tags() = nil, expected error for an invalid media kind")
+	}
+}
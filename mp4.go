@@ -28,6 +28,7 @@ var atoms = atomNames(map[string]string{
 	"\xa9art": "artist",
 	"\xa9ART": "artist",
 	"aART":    "album_artist",
+	"soaa":    "album_artist_sort",
 	"\xa9day": "year",
 	"\xa9nam": "title",
 	"\xa9gen": "genre",
@@ -35,14 +36,27 @@ var atoms = atomNames(map[string]string{
 	"\xa9wrt": "composer",
 	"\xa9too": "encoder",
 	"cprt":    "copyright",
+	"\xa9pub": "publisher",
+	"ownr":    "owner",
 	"covr":    "picture",
 	"\xa9grp": "grouping",
+	"\xa9wrk": "work",
+	"\xa9mvn": "movement",
+	"\xa9mvi": "movement_number",
+	"\xa9mvc": "movement_count",
 	"keyw":    "keyword",
 	"\xa9lyr": "lyrics",
 	"\xa9cmt": "comment",
 	"tmpo":    "tempo",
 	"cpil":    "compilation",
 	"disk":    "disc",
+	"pcst":    "podcast",
+	"stik":    "media_kind",
+	"rate":    "rating",
+	"purd":    "purchase_date",
+	"purl":    "podcast_url",
+	"egid":    "podcast_guid",
+	"desc":    "description",
 })
 
 var means = map[string]bool{
@@ -70,20 +84,49 @@ func (f atomNames) Name(n string) []string {
 type metadataMP4 struct {
 	fileType FileType
 	data     map[string]interface{}
+	freeform map[string]string // all "----" atoms, keyed by "mean:name"
+	tracks   *[]*mp4Track      // tracks in the order of the trak atoms
+	lazy     bool              // covr data is not read (see ReadOptions)
+}
+
+// mp4Track is the information about a track read from a trak atom, used for the bitrate.
+type mp4Track struct {
+	sound     bool   // The handler type is "soun".
+	timescale uint32 // Units per second of the duration.
+	duration  uint64
+	bitrate   int    // Average bitrate from the sample description (esds or alac), zero if unset.
+	size      uint64 // Total size of the samples.
+}
+
+// FreeformMetadata is implemented by the Metadata returned for MP4 files, giving access to
+// all freeform ("----") atoms, including those from applications which are not otherwise
+// recognised.
+type FreeformMetadata interface {
+	// Freeform returns the values of the freeform atoms keyed by "mean:name" (for example
+	// "com.apple.iTunes:MusicBrainz Track Id").  Multiple values are joined with ";".
+	Freeform() map[string]string
 }
 
 // ReadAtoms reads MP4 metadata atoms from the io.ReadSeeker into a Metadata, returning
 // non-nil error if there was a problem.
 func ReadAtoms(r io.ReadSeeker) (Metadata, error) {
+	return readAtoms(r, nil, ReadOptions{})
+}
+
+// readAtoms implements ReadAtoms, adding any non-fatal problems to w.
+func readAtoms(r io.ReadSeeker, w *warnings, opts ReadOptions) (Metadata, error) {
 	m := metadataMP4{
 		data:     make(map[string]interface{}),
+		freeform: make(map[string]string),
 		fileType: UnknownFileType,
+		tracks:   new([]*mp4Track),
+		lazy:     opts.LazyPictures,
 	}
-	err := m.readAtoms(r)
+	err := m.readAtoms(r, w)
 	return m, err
 }
 
-func (m metadataMP4) readAtoms(r io.ReadSeeker) error {
+func (m metadataMP4) readAtoms(r io.ReadSeeker, w *warnings) error {
 	for {
 		name, size, err := readAtomHeader(r)
 		if err != nil {
@@ -102,22 +145,48 @@ func (m metadataMP4) readAtoms(r io.ReadSeeker) error {
 			}
 			fallthrough
 
-		case "moov", "udta", "ilst":
-			return m.readAtoms(r)
+		case "trak":
+			*m.tracks = append(*m.tracks, &mp4Track{})
+			return m.readAtoms(r, w)
+
+		case "moov", "udta", "ilst", "mdia", "minf", "stbl":
+			return m.readAtoms(r, w)
+
+		case "mdhd", "hdlr", "stsd", "stsz":
+			if len(*m.tracks) == 0 {
+				break
+			}
+			b, err := readBytes(r, uint(size-8))
+			if err != nil {
+				return err
+			}
+			(*m.tracks)[len(*m.tracks)-1].read(name, b)
+			continue
 		}
 
 		_, ok := atoms[name]
 		var data []string
 		if name == "----" {
-			name, data, err = readCustomAtom(r, size)
+			var mean string
+			mean, name, data, err = readCustomAtom(r, size)
 			if err != nil {
 				return err
 			}
+			if name == "" || len(data) == 0 {
+				continue
+			}
+			m.freeform[mean+":"+name] = strings.Join(data, ";")
 
-			if name != "----" {
-				ok = true
-				size = 0 // already read data
+			if !means[mean] {
+				continue // already read data
 			}
+			ok = true
+			size = 0
+		}
+
+		if ok && name == "covr" && pictureTooLarge(int64(size)) {
+			w.add(MP4, "skipped %q atom: %v", name, errPictureTooLarge)
+			ok = false
 		}
 
 		if !ok {
@@ -135,7 +204,193 @@ func (m metadataMP4) readAtoms(r io.ReadSeeker) error {
 	}
 }
 
+// read reads the content b of the atom with the given name (mdhd, hdlr, stsd or stsz) into t.
+// Invalid atoms are ignored.
+func (t *mp4Track) read(name string, b []byte) {
+	switch name {
+	case "mdhd":
+		if len(b) >= 20 && b[0] == 0 {
+			t.timescale = binary.BigEndian.Uint32(b[12:16])
+			t.duration = uint64(binary.BigEndian.Uint32(b[16:20]))
+		} else if len(b) >= 32 && b[0] == 1 {
+			t.timescale = binary.BigEndian.Uint32(b[20:24])
+			t.duration = binary.BigEndian.Uint64(b[24:32])
+		}
+
+	case "hdlr":
+		// The meta atom also has a hdlr atom (following the trak atoms), so only the
+		// sound handler type is recorded.
+		if len(b) >= 12 && string(b[8:12]) == "soun" {
+			t.sound = true
+		}
+
+	case "stsd":
+		if len(b) >= 8 {
+			t.bitrate = mp4SampleEntryBitrate(b[8:])
+		}
+
+	case "stsz":
+		if len(b) < 12 {
+			return
+		}
+		size, n := binary.BigEndian.Uint32(b[4:8]), binary.BigEndian.Uint32(b[8:12])
+		if size != 0 {
+			t.size = uint64(size) * uint64(n)
+			return
+		}
+		for b = b[12:]; len(b) >= 4 && n > 0; b, n = b[4:], n-1 {
+			t.size += uint64(binary.BigEndian.Uint32(b[0:4]))
+		}
+	}
+}
+
+// mp4SampleEntryBitrate returns the average bitrate given in the first audio sample entry b
+// of an stsd atom (the DecoderConfigDescriptor in the esds atom of an mp4a entry, or the
+// ALACSpecificConfig of an alac entry), or zero if there isn't one.
+func mp4SampleEntryBitrate(b []byte) int {
+	if len(b) < 8 {
+		return 0
+	}
+	if n := int(binary.BigEndian.Uint32(b[0:4])); n <= len(b) {
+		b = b[:n]
+	}
+	typ := string(b[4:8])
+
+	// Sample entry header (16 bytes) and audio sample entry fields (20 bytes), QuickTime
+	// sound sample description versions 1 and 2 have extra fields.
+	if len(b) < 36 {
+		return 0
+	}
+	children := b[36:]
+	switch binary.BigEndian.Uint16(b[16:18]) {
+	case 1:
+		children = b[minInt(52, len(b)):]
+	case 2:
+		children = b[minInt(72, len(b)):]
+	}
+
+	for len(children) >= 8 {
+		n := int(binary.BigEndian.Uint32(children[0:4]))
+		if n < 8 || n > len(children) {
+			return 0
+		}
+		c, name := children[8:n], string(children[4:8])
+		children = children[n:]
+
+		switch {
+		case typ == "mp4a" && name == "esds":
+			return esdsBitrate(c)
+		case typ == "alac" && name == "alac":
+			// Version and flags (4 bytes), then the ALACSpecificConfig.
+			if len(c) >= 4+24 {
+				return int(binary.BigEndian.Uint32(c[4+20 : 4+24]))
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// esdsBitrate returns the average bitrate in the DecoderConfigDescriptor of the content b of
+// an esds atom, or zero if there isn't one.  See ISO/IEC 14496-1 section 7.2.6.
+func esdsBitrate(b []byte) int {
+	if len(b) < 4 {
+		return 0
+	}
+	b = b[4:] // version and flags
+
+	// descriptor returns the tag and content of the descriptor at the start of b, and the
+	// data following it.
+	descriptor := func(b []byte) (tag byte, content, rest []byte, ok bool) {
+		if len(b) < 2 {
+			return 0, nil, nil, false
+		}
+		tag, b = b[0], b[1:]
+		n := 0
+		for i := 0; i < 4 && len(b) > 0; i++ {
+			c := b[0]
+			b = b[1:]
+			n = n<<7 | int(c&0x7F)
+			if c&0x80 == 0 {
+				break
+			}
+		}
+		if n > len(b) {
+			return 0, nil, nil, false
+		}
+		return tag, b[:n], b[n:], true
+	}
+
+	tag, es, _, ok := descriptor(b)
+	if !ok || tag != 0x03 || len(es) < 3 {
+		return 0
+	}
+	flags := es[2]
+	es = es[3:] // ES_ID and flags
+	if flags&0x80 != 0 {
+		es = es[minInt(2, len(es)):] // dependsOn_ES_ID
+	}
+	if flags&0x40 != 0 && len(es) > 0 {
+		es = es[minInt(1+int(es[0]), len(es)):] // URL
+	}
+	if flags&0x20 != 0 {
+		es = es[minInt(2, len(es)):] // OCR_ES_Id
+	}
+
+	tag, dc, _, ok := descriptor(es)
+	if !ok || tag != 0x04 || len(dc) < 13 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(dc[9:13]))
+}
+
+// readLazyPicture reads the header of the data atom of a covr atom (of the given size), and if it
+// contains a JPEG or PNG picture then adds the picture without reading the data (see
+// ReadOptions) and returns true.  Otherwise the position of r is restored and false is returned.
+func (m metadataMP4) readLazyPicture(r io.ReadSeeker, size uint32) (bool, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil || size < 16 {
+		return false, err
+	}
+
+	// "data" + size (4 bytes each), version (1 byte), class (3 bytes), locale (4 bytes)
+	b, err := readBytes(r, 16)
+	if err != nil {
+		return false, err
+	}
+	n := int64(size) - 16
+	contentType := atomTypes[getInt(b[9:12])]
+	if contentType == "implicit" && n >= int64(len(pngHeader)) {
+		if b, err = readBytes(r, uint(len(pngHeader))); err != nil {
+			return false, err
+		}
+		if bytes.Equal(b, pngHeader) {
+			contentType = "png"
+		}
+	}
+	if contentType != "jpeg" && contentType != "png" {
+		_, err := r.Seek(start, io.SeekStart)
+		return false, err
+	}
+
+	m.data["covr"] = &Picture{
+		Ext:      contentType,
+		MIMEType: "image/" + contentType,
+		r:        readerAt{r},
+		offset:   start + 16,
+		size:     n,
+	}
+	_, err = r.Seek(start+int64(size), io.SeekStart)
+	return true, err
+}
+
 func (m metadataMP4) readAtomData(r io.ReadSeeker, name string, size uint32, processedData []string) error {
+	if name == "covr" && m.lazy && len(processedData) == 0 {
+		if ok, err := m.readLazyPicture(r, size); ok || err != nil {
+			return err
+		}
+	}
+
 	var b []byte
 	var err error
 	var contentType string
@@ -174,12 +429,16 @@ func (m metadataMP4) readAtomData(r io.ReadSeeker, name string, size uint32, pro
 	}
 
 	if name == "trkn" || name == "disk" {
-		if len(b) < 6 {
-			return fmt.Errorf("invalid encoding: expected at least %d bytes, for track and disk numbers, got %d", 6, len(b))
+		// Reserved (2 bytes), then the number and total as 16-bit integers.  Some writers
+		// omit the total.
+		if len(b) < 4 {
+			return fmt.Errorf("invalid encoding: expected at least %d bytes, for track and disk numbers, got %d", 4, len(b))
 		}
 
-		m.data[name] = int(b[3])
-		m.data[name+"_count"] = int(b[5])
+		m.data[name] = getInt(b[2:4])
+		if len(b) >= 6 {
+			m.data[name+"_count"] = getInt(b[4:6])
+		}
 		return nil
 	}
 
@@ -190,6 +449,10 @@ func (m metadataMP4) readAtomData(r io.ReadSeeker, name string, size uint32, pro
 			}
 			// TODO(dhowden): Detect JPEG formats too (harder).
 		}
+		if name == "purl" || name == "egid" {
+			// iTunes writes the podcast URL and GUID as implicit.
+			contentType = "text"
+		}
 	}
 
 	var data interface{}
@@ -207,13 +470,21 @@ func (m metadataMP4) readAtomData(r io.ReadSeeker, name string, size uint32, pro
 		if len(b) < 1 {
 			return fmt.Errorf("invalid encoding: expected at least %d bytes, for integer tag data, got %d", 1, len(b))
 		}
-		data = getInt(b[:1])
+		// Integers are big-endian, and 1 (i.e. cpil), 2 (i.e. tmpo and \xa9mvi), 4 or 8
+		// bytes long.
+		if len(b) > 8 {
+			b = b[:1]
+		}
+		data = getInt(b)
 
 	case "jpeg", "png":
+		width, height := imageSize(b)
 		data = &Picture{
 			Ext:      contentType,
 			MIMEType: "image/" + contentType,
 			Data:     b,
+			Width:    width,
+			Height:   height,
 		}
 	}
 	m.data[name] = data
@@ -232,53 +503,50 @@ func readAtomHeader(r io.ReadSeeker) (name string, size uint32, err error) {
 
 // Generic atom.
 // Should have 3 sub atoms : mean, name and data.
-// We check that mean is "com.apple.iTunes" or others and we use the subname as
-// the name, and move to the data atom.
+// We return the mean and the subname, and the values of the data atom.
 // Data atom could have multiple data values, each with a header.
-// If anything goes wrong, we jump at the end of the "----" atom.
-func readCustomAtom(r io.ReadSeeker, size uint32) (_ string, data []string, _ error) {
+func readCustomAtom(r io.ReadSeeker, size uint32) (mean, name string, data []string, _ error) {
 	subNames := make(map[string]string)
 
 	for size > 8 {
 		subName, subSize, err := readAtomHeader(r)
 		if err != nil {
-			return "", nil, err
+			return "", "", nil, err
 		}
 
 		// Remove the size of the atom from the size counter
 		if size >= subSize {
 			size -= subSize
 		} else {
-			return "", nil, errors.New("--- invalid size")
+			return "", "", nil, errors.New("--- invalid size")
 		}
 
 		b, err := readBytes(r, uint(subSize-8))
 		if err != nil {
-			return "", nil, err
+			return "", "", nil, err
 		}
 
 		if len(b) < 4 {
-			return "", nil, fmt.Errorf("invalid encoding: expected at least %d bytes, got %d", 4, len(b))
+			return "", "", nil, fmt.Errorf("invalid encoding: expected at least %d bytes, got %d", 4, len(b))
 		}
 		switch subName {
 		case "mean", "name":
 			subNames[subName] = string(b[4:])
 		case "data":
-			data = append(data, string(b[4:]))
+			// The value follows the type (4 bytes) and locale (4 bytes), as for other items.
+			if len(b) < 8 {
+				return "", "", nil, fmt.Errorf("invalid encoding: expected at least %d bytes, got %d", 8, len(b))
+			}
+			data = append(data, string(b[8:]))
 		}
 	}
 
 	// there should remain only the header size
 	if size != 8 {
 		err := errors.New("---- atom out of bounds")
-		return "", nil, err
-	}
-
-	if !means[subNames["mean"]] || subNames["name"] == "" || len(data) == 0 {
-		return "----", nil, nil
+		return "", "", nil, err
 	}
-
-	return subNames["name"], data, nil
+	return subNames["mean"], subNames["name"], data, nil
 }
 
 func (metadataMP4) Format() Format       { return MP4 }
@@ -286,6 +554,8 @@ func (m metadataMP4) FileType() FileType { return m.fileType }
 
 func (m metadataMP4) Raw() map[string]interface{} { return m.data }
 
+func (m metadataMP4) Freeform() map[string]string { return m.freeform }
+
 func (m metadataMP4) getString(n []string) string {
 	for _, k := range n {
 		if x, ok := m.data[k]; ok {
@@ -369,6 +639,128 @@ func (m metadataMP4) Comment() string {
 	return t.(string)
 }
 
+func (m metadataMP4) MediaType() string {
+	// Stored in a "----" atom (as written by MusicBrainz Picard).
+	return m.getString([]string{"MEDIA"})
+}
+
+func (m metadataMP4) Mood() string {
+	return m.getString([]string{"MOOD"})
+}
+
+func (m metadataMP4) Rating() int {
+	// The rtng atom is the content rating (i.e. explicit or clean), not a star rating, which is
+	// stored as 0 to 100 in a rate item by Mp3tag and MusicBee (see SetRating).
+	r, err := strconv.Atoi(strings.TrimSpace(m.getString(atoms.Name("rating"))))
+	if err != nil || r <= 0 {
+		return 0
+	}
+	if r > 100 {
+		r = 100
+	}
+	return (r + 19) / 20
+}
+
+func (m metadataMP4) ContentGroup() string {
+	return m.getString(atoms.Name("grouping"))
+}
+
+func (m metadataMP4) Work() string {
+	return m.getString(atoms.Name("work"))
+}
+
+func (m metadataMP4) ClassicalInfo() (ClassicalInfo, bool) {
+	return classicalInfo(ClassicalInfo{
+		Work:           m.Work(),
+		MovementName:   m.getString(atoms.Name("movement")),
+		MovementNumber: m.getInt(atoms.Name("movement_number")),
+		MovementCount:  m.getInt(atoms.Name("movement_count")),
+		Composer:       m.Composer(),
+	})
+}
+
+func (m metadataMP4) Gapless() (GaplessInfo, bool) {
+	return parseITunSMPB(m.getString([]string{"iTunSMPB"}))
+}
+
+func (m metadataMP4) Bitrate() (int, bool) {
+	for _, t := range *m.tracks {
+		if !t.sound {
+			continue
+		}
+		if t.bitrate > 0 {
+			return t.bitrate, true
+		}
+		// Variable bitrate encoders may not set the average bitrate.
+		if t.timescale > 0 && t.duration > 0 && t.size > 0 {
+			return int(t.size * 8 * uint64(t.timescale) / t.duration), true
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+func (m metadataMP4) Subtitle() string {
+	// Stored in "----" atoms (as written by MusicBrainz Picard).
+	return m.getString([]string{"SUBTITLE"})
+}
+
+func (m metadataMP4) DiscSubtitle() string {
+	return m.getString([]string{"DISCSUBTITLE"})
+}
+
+func (m metadataMP4) Conductor() string {
+	// Stored in "----" atoms (as written by MusicBrainz Picard).
+	return m.getString([]string{"CONDUCTOR"})
+}
+
+func (m metadataMP4) Remixer() string {
+	return m.getString([]string{"REMIXER"})
+}
+
+func (m metadataMP4) InvolvedPeople() []Credit {
+	var c []Credit
+	for _, r := range vorbisCreditRoles {
+		if v := m.getString([]string{strings.ToUpper(r.field)}); v != "" {
+			c = append(c, Credit{Role: r.role, Name: v})
+		}
+	}
+	return c
+}
+
+func (m metadataMP4) Copyright() string {
+	return m.getString(atoms.Name("copyright"))
+}
+
+func (m metadataMP4) Publisher() string {
+	// MusicBrainz Picard writes the record label in a "----" atom.
+	return m.getString(append(atoms.Name("publisher"), "LABEL"))
+}
+
+func (m metadataMP4) Owner() string {
+	return m.getString(atoms.Name("owner"))
+}
+
+func (m metadataMP4) PodcastInfo() (PodcastInfo, bool) {
+	p := PodcastInfo{
+		FeedURL:     m.getString([]string{"purl"}),
+		EpisodeGUID: m.getString([]string{"egid"}),
+		Keywords:    m.getString([]string{"keyw"}),
+		Description: m.getString([]string{"desc"}),
+	}
+	flag, _ := m.data["pcst"].(int)
+	return p, flag != 0 || p.FeedURL != "" || p.EpisodeGUID != ""
+}
+
+func (m metadataMP4) ITunesTags() ITunesInfo {
+	return ITunesInfo{
+		AlbumArtist:     m.AlbumArtist(),
+		AlbumArtistSort: m.getString(atoms.Name("album_artist_sort")),
+		Compilation:     m.getInt([]string{"cpil"}) != 0,
+		Grouping:        m.getString(atoms.Name("grouping")),
+	}
+}
+
 func (m metadataMP4) Picture() *Picture {
 	v, ok := m.data["covr"]
 	if !ok {
@@ -377,3 +769,11 @@ func (m metadataMP4) Picture() *Picture {
 	p, _ := v.(*Picture)
 	return p
 }
+
+func (m metadataMP4) Pictures() []*Picture {
+	// Only the first covr picture is read.
+	if p := m.Picture(); p != nil {
+		return []*Picture{p}
+	}
+	return nil
+}
@@ -0,0 +1,548 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mp4Containers maps the names of atoms which contain other atoms to the number of bytes
+// which precede the child atoms.  All children of "ilst" are also containers.
+var mp4Containers = map[string]int{
+	"moov": 0,
+	"trak": 0,
+	"mdia": 0,
+	"minf": 0,
+	"stbl": 0,
+	"dinf": 0,
+	"edts": 0,
+	"udta": 0,
+	"meta": 4, // version (1 byte) + flags (3 bytes)
+	"ilst": 0,
+}
+
+// mp4Atom is an MP4 atom held in memory for editing.
+type mp4Atom struct {
+	Name     string
+	Data     []byte     // Content of a leaf atom.
+	Children []*mp4Atom // Children of a container atom.
+
+	container bool
+	prefix    []byte // Content of a container atom which precedes the children.
+	suffix    []byte // Trailing bytes of a container atom too short to be an atom.
+}
+
+// parseMP4Atoms parses the atoms in b, which are the children of parent.
+func parseMP4Atoms(b []byte, parent string) (atoms []*mp4Atom, suffix []byte, err error) {
+	for len(b) >= 8 {
+		size := uint64(binary.BigEndian.Uint32(b))
+		name := string(b[4:8])
+		headerSize := uint64(8)
+
+		switch size {
+		case 0: // atom extends to the end of its parent
+			size = uint64(len(b))
+		case 1: // 64-bit size
+			if len(b) < 16 {
+				return nil, nil, fmt.Errorf("invalid %q atom header", name)
+			}
+			size = binary.BigEndian.Uint64(b[8:16])
+			headerSize = 16
+		}
+		if size < headerSize || size > uint64(len(b)) {
+			return nil, nil, fmt.Errorf("invalid %q atom size: %d", name, size)
+		}
+
+		a := &mp4Atom{Name: name}
+		content := b[headerSize:size]
+		if n, ok := mp4Containers[name]; ok || parent == "ilst" {
+			if len(content) < n {
+				return nil, nil, fmt.Errorf("invalid %q atom", name)
+			}
+			a.container = true
+			a.prefix = content[:n]
+			a.Children, a.suffix, err = parseMP4Atoms(content[n:], name)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			a.Data = content
+		}
+
+		atoms = append(atoms, a)
+		b = b[size:]
+	}
+	return atoms, b, nil
+}
+
+// bytes returns the encoded atom.
+func (a *mp4Atom) bytes() []byte {
+	var content []byte
+	if a.container {
+		content = append(content, a.prefix...)
+		for _, c := range a.Children {
+			content = append(content, c.bytes()...)
+		}
+		content = append(content, a.suffix...)
+	} else {
+		content = a.Data
+	}
+
+	var b []byte
+	if size := 8 + uint64(len(content)); size <= math.MaxUint32 {
+		b = make([]byte, 8, size)
+		binary.BigEndian.PutUint32(b, uint32(size))
+		copy(b[4:8], a.Name)
+	} else {
+		b = make([]byte, 16, size+8)
+		binary.BigEndian.PutUint32(b, 1)
+		copy(b[4:8], a.Name)
+		binary.BigEndian.PutUint64(b[8:16], size+8)
+	}
+	return append(b, content...)
+}
+
+// size returns the size of the encoded atom.
+func (a *mp4Atom) size() int64 {
+	return int64(len(a.bytes()))
+}
+
+// child returns the first child atom with the given name, or nil if there is none.
+func (a *mp4Atom) child(name string) *mp4Atom {
+	for _, c := range a.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// find returns the descendant atom at the given path of names, or nil if there is none.
+func (a *mp4Atom) find(path ...string) *mp4Atom {
+	for _, name := range path {
+		if a = a.child(name); a == nil {
+			return nil
+		}
+	}
+	return a
+}
+
+// removeChildren removes all child atoms with the given name, returning the number
+// of atoms removed.
+func (a *mp4Atom) removeChildren(name string) int {
+	kept := a.Children[:0]
+	for _, c := range a.Children {
+		if c.Name != name {
+			kept = append(kept, c)
+		}
+	}
+	n := len(a.Children) - len(kept)
+	a.Children = kept
+	return n
+}
+
+// adjustChunkOffsets adds delta to all chunk offsets (in stco and co64 atoms) which are
+// greater than or equal to from.
+func (a *mp4Atom) adjustChunkOffsets(from, delta int64) error {
+	switch a.Name {
+	case "stco", "co64":
+		if len(a.Data) < 8 {
+			return fmt.Errorf("invalid %q atom", a.Name)
+		}
+		entrySize := 4
+		if a.Name == "co64" {
+			entrySize = 8
+		}
+		n := int(binary.BigEndian.Uint32(a.Data[4:8]))
+		if n > (len(a.Data)-8)/entrySize {
+			return fmt.Errorf("invalid %q atom entry count: %d", a.Name, n)
+		}
+
+		for i := 0; i < n; i++ {
+			b := a.Data[8+i*entrySize:]
+			if entrySize == 4 {
+				off := int64(binary.BigEndian.Uint32(b))
+				if off >= from {
+					if off+delta > math.MaxUint32 {
+						return errors.New("chunk offset overflow in stco atom")
+					}
+					binary.BigEndian.PutUint32(b, uint32(off+delta))
+				}
+				continue
+			}
+			off := int64(binary.BigEndian.Uint64(b))
+			if off >= from {
+				binary.BigEndian.PutUint64(b, uint64(off+delta))
+			}
+		}
+	}
+
+	for _, c := range a.Children {
+		if err := c.adjustChunkOffsets(from, delta); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMP4Moov reads the moov atom of the MP4 data in r, returning the atom along with its
+// offset and size in r.
+func readMP4Moov(r io.ReadSeeker) (moov *mp4Atom, offset, size int64, err error) {
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+
+	for {
+		var name string
+		var n uint32
+		name, n, err = readAtomHeader(r)
+		if err != nil {
+			if err == io.EOF {
+				err = errors.New("no moov atom found")
+			}
+			return
+		}
+
+		size = int64(n)
+		headerSize := int64(8)
+		switch n {
+		case 0:
+			var end int64
+			if end, err = r.Seek(0, io.SeekEnd); err != nil {
+				return
+			}
+			size = end - offset
+		case 1:
+			var n64 uint64
+			if err = binary.Read(r, binary.BigEndian, &n64); err != nil {
+				return
+			}
+			size, headerSize = int64(n64), 16
+		}
+		if size < headerSize {
+			err = fmt.Errorf("invalid %q atom size: %d", name, size)
+			return
+		}
+
+		if name == "moov" {
+			if _, err = r.Seek(offset, io.SeekStart); err != nil {
+				return
+			}
+			var b []byte
+			if b, err = readBytes(r, uint(size)); err != nil {
+				return
+			}
+
+			var atoms []*mp4Atom
+			if atoms, _, err = parseMP4Atoms(b, ""); err != nil {
+				return
+			}
+			moov = atoms[0]
+			return
+		}
+
+		offset += size
+		if _, err = r.Seek(offset, io.SeekStart); err != nil {
+			return
+		}
+	}
+}
+
+// writeMP4Moov replaces the moov atom of the MP4 data in rw (at offset with the given size)
+// with moov, updating chunk offsets for any media data which is moved.
+func writeMP4Moov(rw io.ReadWriteSeeker, moov *mp4Atom, offset, size int64) error {
+	if delta := moov.size() - size; delta != 0 {
+		if err := moov.adjustChunkOffsets(offset+size, delta); err != nil {
+			return err
+		}
+	}
+	return replaceRegion(rw, offset, size, moov.bytes())
+}
+
+// removeMP4Items removes all metadata items (children of moov.udta.meta.ilst) with the given
+// name from the MP4 data in rw, returning the number of bytes removed.
+func removeMP4Items(rw io.ReadWriteSeeker, name string) (int64, error) {
+	moov, offset, size, err := readMP4Moov(rw)
+	if err != nil {
+		return 0, err
+	}
+
+	ilst := moov.find("udta", "meta", "ilst")
+	if ilst == nil || ilst.removeChildren(name) == 0 {
+		return 0, nil
+	}
+
+	removed := size - moov.size()
+	return removed, writeMP4Moov(rw, moov, offset, size)
+}
+
+// setMP4Picture replaces the covr items of the MP4 data in rw with one containing pic, which
+// must be a JPEG or PNG image.
+func setMP4Picture(rw io.ReadWriteSeeker, pic *Picture) error {
+	mime, err := pictureMIMEType(pic)
+	if err != nil {
+		return err
+	}
+	var class byte
+	switch strings.ToLower(mime) {
+	case "image/jpeg", "image/jpg":
+		class = mp4ClassJPEG
+	case "image/png":
+		class = mp4ClassPNG
+	default:
+		return fmt.Errorf("unsupported MP4 picture type: %q", mime)
+	}
+
+	moov, offset, size, err := readMP4Moov(rw)
+	if err != nil {
+		return err
+	}
+	ilst := mp4Ilst(moov)
+	ilst.removeChildren("covr")
+	ilst.Children = append(ilst.Children, mp4Item("covr", class, pic.Data))
+	return writeMP4Moov(rw, moov, offset, size)
+}
+
+// setMP4Rating replaces the rate items of the MP4 data in rw with one containing rating (0 to
+// 100), as written by Mp3tag and MusicBee.
+func setMP4Rating(rw io.ReadWriteSeeker, rating int) error {
+	moov, offset, size, err := readMP4Moov(rw)
+	if err != nil {
+		return err
+	}
+	ilst := mp4Ilst(moov)
+	ilst.removeChildren("rate")
+	ilst.Children = append(ilst.Children, mp4Item("rate", mp4ClassText, []byte(strconv.Itoa(rating))))
+	return writeMP4Moov(rw, moov, offset, size)
+}
+
+// mp4TextItems maps field names to the MP4 items used to store them.
+var mp4TextItems = map[string]string{
+	FieldTitle:           "\xa9nam",
+	FieldArtist:          "\xa9ART",
+	FieldAlbum:           "\xa9alb",
+	FieldAlbumArtist:     "aART",
+	FieldAlbumArtistSort: "soaa",
+	FieldArtistSort:      "soar",
+	FieldComposer:        "\xa9wrt",
+	FieldGenre:           "\xa9gen",
+	FieldComment:         "\xa9cmt",
+	FieldCopyright:       "cprt",
+	FieldPublisher:       "\xa9pub",
+	FieldOwner:           "ownr",
+	FieldLyrics:          "\xa9lyr",
+	FieldPodcastURL:      "purl",
+	FieldPodcastGUID:     "egid",
+}
+
+// MP4 data atom classes (see atomTypes).
+const (
+	mp4ClassImplicit = 0
+	mp4ClassText     = 1
+	mp4ClassJPEG     = 13
+	mp4ClassPNG      = 14
+	mp4ClassInt      = 21
+)
+
+// mp4Item returns a metadata item (a child of ilst) with a single data atom.
+func mp4Item(name string, class byte, value []byte) *mp4Atom {
+	data := append([]byte{0, 0, 0, class, 0, 0, 0, 0}, value...) // version, class, locale
+	return &mp4Atom{
+		Name:      name,
+		container: true,
+		Children:  []*mp4Atom{{Name: "data", Data: data}},
+	}
+}
+
+// mp4FreeformItem returns a "----" metadata item with the given mean and name.
+func mp4FreeformItem(mean, name, value string) *mp4Atom {
+	return &mp4Atom{
+		Name:      "----",
+		container: true,
+		Children: []*mp4Atom{
+			{Name: "mean", Data: append([]byte{0, 0, 0, 0}, mean...)},
+			{Name: "name", Data: append([]byte{0, 0, 0, 0}, name...)},
+			{Name: "data", Data: append([]byte{0, 0, 0, mp4ClassText, 0, 0, 0, 0}, value...)},
+		},
+	}
+}
+
+// encodeTrkn returns the content of a trkn (or disk) data atom: the index and total as 16-bit
+// big-endian integers, between two reserved 16-bit fields.
+func encodeTrkn(index, total int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint16(b[2:4], uint16(index))
+	binary.BigEndian.PutUint16(b[4:6], uint16(total))
+	return b
+}
+
+// mp4Ilst returns the moov.udta.meta.ilst atom of moov, adding any missing atoms.
+func mp4Ilst(moov *mp4Atom) *mp4Atom {
+	udta := moov.child("udta")
+	if udta == nil {
+		udta = &mp4Atom{Name: "udta", container: true}
+		moov.Children = append(moov.Children, udta)
+	}
+	meta := udta.child("meta")
+	if meta == nil {
+		hdlr := make([]byte, 25) // version and flags, pre-defined, handler type, reserved, name
+		copy(hdlr[8:], "mdirappl")
+		meta = &mp4Atom{
+			Name:      "meta",
+			container: true,
+			prefix:    make([]byte, 4),
+			Children:  []*mp4Atom{{Name: "hdlr", Data: hdlr}},
+		}
+		udta.Children = append(udta.Children, meta)
+	}
+	ilst := meta.child("ilst")
+	if ilst == nil {
+		ilst = &mp4Atom{Name: "ilst", container: true}
+		meta.Children = append(meta.Children, ilst)
+	}
+	return ilst
+}
+
+// WriteMP4Tags writes the fields in data to the MP4 data in rw, replacing all the existing
+// metadata items except cover art.  Fields without a standard MP4 item are written as "----"
+// items with the mean "com.apple.iTunes" (as done by MusicBrainz Picard).  Track and disc
+// numbers are written as binary trkn and disk items, and FieldMediaKind as a stik item.  The
+// media data is moved (and the chunk offsets updated) if the size of the moov atom changes and
+// it precedes the media data.
+func WriteMP4Tags(rw io.ReadWriteSeeker, data map[string]string) error {
+	return writeMP4Tags(rw, data, false)
+}
+
+// updateMP4Tags is like WriteMP4Tags, but only the items for the fields in data are replaced
+// and the other items are kept.  A track (or disc) number written without a total keeps the
+// existing total, and a total written without a number keeps the existing number.
+func updateMP4Tags(rw io.ReadWriteSeeker, data map[string]string) error {
+	return writeMP4Tags(rw, data, true)
+}
+
+// writeMP4Tags writes the fields in data to the MP4 data in rw (see WriteMP4Tags), keeping the
+// existing items for other fields if merge is true.
+func writeMP4Tags(rw io.ReadWriteSeeker, data map[string]string, merge bool) error {
+	moov, offset, size, err := readMP4Moov(rw)
+	if err != nil {
+		return err
+	}
+	data = normaliseFields(data)
+	ilst := mp4Ilst(moov)
+	if merge {
+		mergeMP4Numbers(ilst, data)
+	}
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var items []*mp4Atom
+	for _, k := range keys {
+		v := data[k]
+		if name, ok := mp4TextItems[k]; ok {
+			items = append(items, mp4Item(name, mp4ClassText, []byte(v)))
+			continue
+		}
+
+		switch k {
+		case FieldDate, FieldYear:
+			if k == FieldYear && data[FieldDate] != "" {
+				continue
+			}
+			items = append(items, mp4Item("\xa9day", mp4ClassText, []byte(v)))
+
+		case FieldTrackNumber, FieldDiscNumber:
+			name, total := "trkn", data[FieldTrackTotal]
+			if k == FieldDiscNumber {
+				name, total = "disk", data[FieldDiscTotal]
+			}
+			x, n := parseXofN(v)
+			if total != "" {
+				n, _ = strconv.Atoi(strings.TrimSpace(total))
+			}
+			items = append(items, mp4Item(name, mp4ClassImplicit, encodeTrkn(x, n)))
+
+		case FieldTrackTotal, FieldDiscTotal:
+			// Written with the corresponding number.
+
+		case FieldMediaKind:
+			kind, ok := parseMediaKind(v)
+			if !ok {
+				return fmt.Errorf("invalid media kind: %q", v)
+			}
+			items = append(items, mp4Item("stik", mp4ClassInt, []byte{byte(kind)}))
+
+		default:
+			items = append(items, mp4FreeformItem("com.apple.iTunes", k, v))
+		}
+	}
+
+	replaced := make(map[string]bool, len(items))
+	for _, c := range items {
+		replaced[mp4ItemKey(c)] = true
+	}
+	kept := ilst.Children[:0]
+	for _, c := range ilst.Children {
+		if c.Name == "covr" || merge && !replaced[mp4ItemKey(c)] {
+			kept = append(kept, c)
+		}
+	}
+	ilst.Children = append(items, kept...)
+
+	return writeMP4Moov(rw, moov, offset, size)
+}
+
+// mp4ItemKey returns the name of the metadata item a, or for a "----" item its mean and upper
+// case name, so that an item is replaced by a new item with the same key.
+func mp4ItemKey(a *mp4Atom) string {
+	if a.Name != "----" {
+		return a.Name
+	}
+	key := a.Name
+	for _, name := range []string{"mean", "name"} {
+		var v string
+		if c := a.child(name); c != nil && len(c.Data) >= 4 {
+			v = string(c.Data[4:])
+		}
+		key += ":" + strings.ToUpper(v)
+	}
+	return key
+}
+
+// mergeMP4Numbers adds the existing number or total of the trkn (or disk) item in ilst to data
+// (which must already be normalised) when only one of them is being written.
+func mergeMP4Numbers(ilst *mp4Atom, data map[string]string) {
+	for _, f := range [][3]string{
+		{"trkn", FieldTrackNumber, FieldTrackTotal},
+		{"disk", FieldDiscNumber, FieldDiscTotal},
+	} {
+		number, hasNumber := data[f[1]]
+		_, hasTotal := data[f[2]]
+		if hasNumber == hasTotal || strings.Contains(number, "/") {
+			continue
+		}
+		d := ilst.find(f[0], "data")
+		if d == nil || len(d.Data) < 14 {
+			continue
+		}
+		// Data atoms have a 4 byte class and 4 bytes of locale (see encodeTrkn).
+		x, n := binary.BigEndian.Uint16(d.Data[10:12]), binary.BigEndian.Uint16(d.Data[12:14])
+		if !hasNumber {
+			data[f[1]] = strconv.Itoa(int(x))
+		} else if n > 0 {
+			data[f[2]] = strconv.Itoa(int(n))
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+)
+
+// addTestMP4Items adds the given items to the ilst atom of the MP4 data in f.
+func addTestMP4Items(t *testing.T, f *os.File, items ...*mp4Atom) {
+	t.Helper()
+	moov, offset, size, err := readMP4Moov(f)
+	if err != nil {
+		t.Fatalf("readMP4Moov() = %v", err)
+	}
+	ilst := moov.find("udta", "meta", "ilst")
+	ilst.Children = append(ilst.Children, items...)
+	if err := writeMP4Moov(f, moov, offset, size); err != nil {
+		t.Fatalf("writeMP4Moov() = %v", err)
+	}
+}
+
+// testMP4FastStart returns the MP4 data in b (which must be ftyp, free, mdat, moov) with the
+// moov atom moved before the mdat atom, as done by "faststart" tools.
+func testMP4FastStart(t *testing.T, b []byte) []byte {
+	t.Helper()
+	atoms, _, err := parseMP4Atoms(b, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(atoms) != 4 || atoms[2].Name != "mdat" || atoms[3].Name != "moov" {
+		t.Fatalf("unexpected atom layout")
+	}
+	moov := atoms[3]
+	if err := moov.adjustChunkOffsets(0, moov.size()); err != nil {
+		t.Fatal(err)
+	}
+
+	var out []byte
+	for _, a := range []*mp4Atom{atoms[0], atoms[1], moov, atoms[2]} {
+		out = append(out, a.bytes()...)
+	}
+	return out
+}
+
+// testMP4ChunkOffset returns the first chunk offset of the MP4 data in f.
+func testMP4ChunkOffset(t *testing.T, f *os.File) int64 {
+	t.Helper()
+	moov, _, _, err := readMP4Moov(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stco := moov.find("trak", "mdia", "minf", "stbl", "stco")
+	return int64(binary.BigEndian.Uint32(stco.Data[8:12]))
+}
+
+func TestMP4AtomRoundTrip(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atoms, suffix, err := parseMP4Atoms(b, "")
+	if err != nil {
+		t.Fatalf("parseMP4Atoms() = %v", err)
+	}
+	testValue(t, 0, len(suffix))
+
+	var got []byte
+	for _, a := range atoms {
+		got = append(got, a.bytes()...)
+	}
+	if !bytes.Equal(got, b) {
+		t.Errorf("encoded atoms differ from original")
+	}
+}
+
+func TestMP4FastStartChunkOffsets(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tempFile(t, testMP4FastStart(t, b))
+
+	want, err := SumAtoms(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := testMP4ChunkOffset(t, f)
+
+	addTestMP4Items(t, f, mp4Item("covr", 13, []byte("not really a jpeg")))
+	after := testMP4ChunkOffset(t, f)
+	testValue(t, before+8+8+8+17, after)
+
+	f.Seek(0, 0)
+	got, err := SumAtoms(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testValue(t, want, got)
+}
+
+func TestEncodeTrkn(t *testing.T) {
+	tests := []struct {
+		index, total int
+		want         []byte
+	}{
+		{5, 12, []byte{0, 0, 0, 5, 0, 12, 0, 0}},
+		{300, 0, []byte{0, 0, 0x01, 0x2C, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := encodeTrkn(tt.index, tt.total); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeTrkn(%d, %d) = %x, expected %x", tt.index, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestMP4Disc(t *testing.T) {
+	tests := []struct {
+		data        []byte
+		disc, total int
+	}{
+		{[]byte{0, 0, 0, 1, 0, 2}, 1, 2}, // as written by iTunes
+		{[]byte{0, 0, 0, 1, 0, 2, 0, 0}, 1, 2},
+		{[]byte{0, 0, 0x01, 0x2C, 0x01, 0x2D}, 300, 301},
+		{[]byte{0, 0, 0, 3}, 3, 0}, // no total
+	}
+	for _, tt := range tests {
+		f := tempCopy(t, "without_tags/sample.m4a")
+		addTestMP4Items(t, f, mp4Item("disk", mp4ClassImplicit, tt.data))
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%x: ReadFrom() = %v", tt.data, err)
+		}
+		disc, total := m.Disc()
+		testValue(t, tt.disc, disc)
+		testValue(t, tt.total, total)
+	}
+}
+
+func TestWriteMP4Tags(t *testing.T) {
+	for _, path := range []string{"with_tags/sample.m4a", "without_tags/sample.m4a"} {
+		f := tempCopy(t, path)
+		addTestMP4Items(t, f, mp4Item("covr", 13, []byte("not really a jpeg")))
+
+		err := WriteMP4Tags(f, map[string]string{
+			"title":       "New Title",
+			"albumartist": "Album Artist",
+			"tracknumber": "5",
+			"tracktotal":  "12",
+			"discnumber":  "1/2",
+			"date":        "2001-02-03",
+			"mood":        "Happy",
+		})
+		if err != nil {
+			t.Fatalf("%v: WriteMP4Tags() = %v", path, err)
+		}
+
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+		testValue(t, "New Title", m.Title())
+		testValue(t, "Album Artist", m.AlbumArtist())
+		testValue(t, "", m.Artist()) // existing items are replaced
+		testValue(t, 2001, m.Year())
+		testValue(t, "Happy", m.Mood())
+		track, trackTotal := m.Track()
+		testValue(t, 5, track)
+		testValue(t, 12, trackTotal)
+		disc, discTotal := m.Disc()
+		testValue(t, 1, disc)
+		testValue(t, 2, discTotal)
+		if m.Picture() == nil {
+			t.Errorf("%v: cover art not kept", path)
+		}
+	}
+}
+
+func TestUpdateMP4Tags(t *testing.T) {
+	f := tempCopy(t, "without_tags/sample.m4a")
+	err := WriteMP4Tags(f, map[string]string{
+		"title":       "Title",
+		"artist":      "Artist",
+		"tracknumber": "5",
+		"tracktotal":  "12",
+		"discnumber":  "1/2",
+	})
+	if err != nil {
+		t.Fatalf("WriteMP4Tags() = %v", err)
+	}
+	addTestMP4Items(t, f, mp4FreeformItem("com.apple.iTunes", "Mood", "Happy"))
+
+	err = updateMP4Tags(f, map[string]string{
+		"title":      "New Title",
+		"tracktotal": "14",
+		"discnumber": "2",
+		"mood":       "Sad",
+	})
+	if err != nil {
+		t.Fatalf("updateMP4Tags() = %v", err)
+	}
+
+	f.Seek(0, io.SeekStart)
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "New Title", m.Title())
+	testValue(t, "Artist", m.Artist()) // other items are kept
+	testValue(t, "Sad", m.Mood())
+	track, trackTotal := m.Track()
+	testValue(t, 5, track) // the existing number is kept
+	testValue(t, 14, trackTotal)
+	disc, discTotal := m.Disc()
+	testValue(t, 2, disc)
+	testValue(t, 2, discTotal) // the existing total is kept
+
+	moov, _, _, err := readMP4Moov(f)
+	if err != nil {
+		t.Fatalf("readMP4Moov() = %v", err)
+	}
+	var n int
+	for _, c := range moov.find("udta", "meta", "ilst").Children {
+		if c.Name == "----" {
+			n++
+		}
+	}
+	testValue(t, 1, n) // "Mood" is replaced by "MOOD"
+}
@@ -0,0 +1,190 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"errors"
+	"io"
+)
+
+// mpegFrameHeader is the header of an MPEG audio frame.
+type mpegFrameHeader []byte
+
+// valid returns true if h is the header of an MPEG Layer III audio frame.
+func (h mpegFrameHeader) valid() bool {
+	return len(h) >= 4 && h[0] == 0xFF && h[1]&0xE0 == 0xE0 &&
+		h.version() != 1 && (h[1]>>1)&0x3 == 1
+}
+
+// version returns the MPEG audio version ID (3: MPEG 1, 2: MPEG 2, 0: MPEG 2.5, 1: reserved).
+func (h mpegFrameHeader) version() byte {
+	return (h[1] >> 3) & 0x3
+}
+
+// mono returns true if the frame has a single channel.
+func (h mpegFrameHeader) mono() bool {
+	return h[3]>>6 == 3
+}
+
+// mpegBitrates are the Layer III bitrates (in kbit/s) for MPEG 1 and MPEG 2/2.5, indexed
+// by the bitrate index in the frame header.
+var mpegBitrates = [2][15]int{
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// mpegSampleRates are the sample rates (in Hz) for MPEG 1, MPEG 2 and MPEG 2.5, indexed by
+// the sample rate index in the frame header.
+var mpegSampleRates = [3][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+// bitrate returns the bitrate of the frame in bits per second, or zero if it is free format
+// or invalid.
+func (h mpegFrameHeader) bitrate() int {
+	i := h[2] >> 4
+	if i >= 15 {
+		return 0
+	}
+	if h.version() == 3 {
+		return mpegBitrates[0][i] * 1000
+	}
+	return mpegBitrates[1][i] * 1000
+}
+
+// sampleRate returns the sample rate of the frame in Hz, or zero if it is invalid.
+func (h mpegFrameHeader) sampleRate() int {
+	i := (h[2] >> 2) & 0x3
+	if i == 3 {
+		return 0
+	}
+	switch h.version() {
+	case 3:
+		return mpegSampleRates[0][i]
+	case 2:
+		return mpegSampleRates[1][i]
+	}
+	return mpegSampleRates[2][i]
+}
+
+// samplesPerFrame returns the number of samples (per channel) in a Layer III frame.
+func (h mpegFrameHeader) samplesPerFrame() int {
+	if h.version() == 3 {
+		return 1152
+	}
+	return 576
+}
+
+// sideInfoSize returns the size of the Layer III side information which follows the header.
+func (h mpegFrameHeader) sideInfoSize() int {
+	switch {
+	case h.version() == 3 && h.mono():
+		return 17
+	case h.version() == 3:
+		return 32
+	case h.mono():
+		return 9
+	}
+	return 17
+}
+
+// xingHeader is the Xing (VBR) or Info (CBR) header stored in the first frame of an MPEG
+// audio stream (see http://gabriel.mp3-tech.org/mp3infotag.html).
+type xingHeader struct {
+	Frames  int    // Number of frames, zero if unavailable.
+	Bytes   int    // Number of bytes, zero if unavailable.
+	Quality int    // Encoder quality indicator, -1 if unavailable.
+	LAME    []byte // LAME extension (36 bytes), nil if unavailable.
+}
+
+// Xing header flags.
+const (
+	xingFrames  = 0x1
+	xingBytes   = 0x2
+	xingTOC     = 0x4
+	xingQuality = 0x8
+)
+
+// xingLAMESize is the size of the LAME extension of the Xing header.
+const xingLAMESize = 36
+
+// readXingHeader reads the Xing header from the MPEG audio frame at the current position
+// of r, returning nil if there isn't one.
+func readXingHeader(r io.Reader) (*xingHeader, error) {
+	// frame header (4) + side info (<= 32) + tag (4) + flags (4) + frames (4) + bytes (4) +
+	// TOC (100) + quality (4) + LAME extension (36)
+	b := make([]byte, 4+32+4+4+4+4+100+4+xingLAMESize)
+	n, err := io.ReadFull(r, b)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	b = b[:n]
+
+	h := mpegFrameHeader(b)
+	if !h.valid() {
+		return nil, nil
+	}
+
+	b = b[4+h.sideInfoSize():]
+	if len(b) < 8 || (string(b[0:4]) != "Xing" && string(b[0:4]) != "Info") {
+		return nil, nil
+	}
+	flags := getInt(b[4:8])
+	b = b[8:]
+
+	x := &xingHeader{Quality: -1}
+	field := func(size int) ([]byte, error) {
+		if len(b) < size {
+			return nil, errors.New("invalid Xing header")
+		}
+		f := b[:size]
+		b = b[size:]
+		return f, nil
+	}
+
+	if flags&xingFrames != 0 {
+		f, err := field(4)
+		if err != nil {
+			return nil, err
+		}
+		x.Frames = getInt(f)
+	}
+	if flags&xingBytes != 0 {
+		f, err := field(4)
+		if err != nil {
+			return nil, err
+		}
+		x.Bytes = getInt(f)
+	}
+	if flags&xingTOC != 0 {
+		if _, err := field(100); err != nil {
+			return nil, err
+		}
+	}
+	if flags&xingQuality != 0 {
+		f, err := field(4)
+		if err != nil {
+			return nil, err
+		}
+		x.Quality = getInt(f)
+	}
+
+	if len(b) >= xingLAMESize && printableASCII(b[0:4]) {
+		x.LAME = b[:xingLAMESize]
+	}
+	return x, nil
+}
+
+// printableASCII returns true if b only contains printable ASCII characters.
+func printableASCII(b []byte) bool {
+	for _, x := range b {
+		if x < 0x20 || x > 0x7E {
+			return false
+		}
+	}
+	return true
+}
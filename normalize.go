@@ -0,0 +1,182 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"io"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeUnicode rewrites the text fields of rw in Unicode Normalization Form C (NFC), so
+// that equivalent strings (i.e. "é" as a single code point or as "e" followed by a combining
+// accent) compare equal.  Vorbis comments (FLAC and Ogg), MP4 text items and ID3v2 text,
+// comment and lyrics frames are normalized.  Nothing is written if the text is already in NFC.
+func NormalizeUnicode(rw io.ReadWriteSeeker) error {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	format, fileType, err := sniffFormat(rw)
+	if err != nil {
+		return err
+	}
+	if format == MP4 {
+		return normalizeMP4(rw)
+	}
+
+	switch fileType {
+	case FLAC:
+		blocks, _, err := readFLACBlocks(rw)
+		if err != nil {
+			return err
+		}
+		m, err := readFLACVorbis(blocks)
+		if err != nil || m == nil {
+			return err
+		}
+		return normalizeVorbis(rw, m)
+
+	case OGG:
+		m, err := ReadOGGTags(rw)
+		if err != nil {
+			return err
+		}
+		return normalizeVorbis(rw, m.(*metadataOGG).metadataVorbis)
+
+	case MP3:
+		return normalizeID3v2(rw)
+	}
+	return ErrUnsupportedFormat
+}
+
+// normalizeVorbis converts the values of the Vorbis comments in m (read from the FLAC or Ogg
+// data in rw) to NFC.  Each value of a repeated field is converted separately.
+func normalizeVorbis(rw io.ReadWriteSeeker, m *metadataVorbis) error {
+	comments := m.vorbisComments()
+	comments["vendor"] = []string{m.c["vendor"]}
+
+	var changed bool
+	for _, v := range comments {
+		for i, x := range v {
+			if n := norm.NFC.String(x); n != x {
+				v[i] = n
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return WriteVorbisComments(rw, comments)
+}
+
+// normalizeMP4 converts the text items (children of moov.udta.meta.ilst) of the MP4 data in
+// rw to NFC.
+func normalizeMP4(rw io.ReadWriteSeeker) error {
+	moov, offset, size, err := readMP4Moov(rw)
+	if err != nil {
+		return err
+	}
+	ilst := moov.find("udta", "meta", "ilst")
+	if ilst == nil {
+		return nil
+	}
+
+	var changed bool
+	for _, item := range ilst.Children {
+		for _, d := range item.Children {
+			// Data atoms have a 4 byte class (1 for UTF-8 text) and 4 bytes of locale.
+			if d.Name != "data" || len(d.Data) < 8 || d.Data[3] != 1 {
+				continue
+			}
+			if n := norm.NFC.Bytes(d.Data[8:]); string(n) != string(d.Data[8:]) {
+				d.Data = append(d.Data[:8:8], n...)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeMP4Moov(rw, moov, offset, size)
+}
+
+// normalizeID3v2 converts the text (T***), comment and lyrics frames of the ID3v2 tag at the
+// start of rw to NFC.  Frames with format flags (i.e. compression) are left alone, as are
+// ISO-8859-1 frames (which can only contain precomposed characters anyway).  Changed frames
+// are rewritten using the same encoding (UTF-16 is written with a BOM).
+func normalizeID3v2(rw io.ReadWriteSeeker) error {
+	t, err := readID3v2RawTag(rw)
+	if err != nil || t == nil {
+		return err
+	}
+
+	var changed bool
+	for i, f := range t.Frames {
+		if f.Flags[1] != 0 || len(f.Data) == 0 || f.Data[0] == encodingISO8859 || f.Data[0] > encodingUTF8 {
+			continue
+		}
+		enc := f.Data[0]
+		if enc == encodingUTF16 {
+			enc = encodingUTF16WithBOM
+		}
+
+		var b []byte
+		switch f.Name {
+		case "COMM", "COM", "USLT", "ULT", "TXXX", "TXX":
+			var lang string
+			data := f.Data[1:]
+			if f.Name[0] != 'T' {
+				if len(data) < 3 {
+					continue
+				}
+				lang, data = string(data[:3]), data[3:]
+			}
+			parts := dataSplit(data, f.Data[0])
+			if len(parts) != 2 {
+				continue
+			}
+			desc, err := decodeText(f.Data[0], parts[0])
+			if err != nil {
+				continue
+			}
+			text, err := decodeText(f.Data[0], parts[1])
+			if err != nil {
+				continue
+			}
+			ndesc, ntext := norm.NFC.String(desc), norm.NFC.String(text)
+			if ndesc == desc && ntext == text {
+				continue
+			}
+			b = id3v24TextWithDescrFrame(enc, lang, ndesc, ntext)
+
+		default:
+			if f.Name[0] != 'T' {
+				continue
+			}
+			text, err := decodeText(f.Data[0], f.Data[1:])
+			if err != nil {
+				continue
+			}
+			n := norm.NFC.String(text)
+			if n == text {
+				continue
+			}
+			b = id3v24TextFrame(enc, n)
+		}
+		t.Frames[i].Data = b
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	// Keep the size of the tag if possible, so that the audio data does not have to be moved.
+	padding := int(t.Size) - 10 - t.framesSize()
+	if padding < 0 {
+		padding = t.Padding
+	}
+	return writeID3v2RawTag(rw, t, padding)
+}
@@ -0,0 +1,73 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeUnicode(t *testing.T) {
+	const nfd, nfc = "Cafe\u0301", "Caf\u00e9"
+
+	for _, name := range []string{"sample.flac", "sample.ogg", "sample.m4a", "sample.mp3"} {
+		f := tempCopy(t, "without_tags/"+name)
+		if err := writeTags(f, map[string]string{FieldTitle: nfd, FieldComment: nfd}); err != nil {
+			t.Errorf("%s: writeTags() = %v", name, err)
+			continue
+		}
+		if err := NormalizeUnicode(f); err != nil {
+			t.Errorf("%s: NormalizeUnicode() = %v", name, err)
+			continue
+		}
+
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("%s: ReadFrom() = %v", name, err)
+			continue
+		}
+		if got := m.Title(); got != nfc {
+			t.Errorf("%s: Title() = %q, expected: %q", name, got, nfc)
+		}
+		if got := m.Comment(); got != nfc {
+			t.Errorf("%s: Comment() = %q, expected: %q", name, got, nfc)
+		}
+	}
+}
+
+func TestNormalizeUnicodeRepeatedFields(t *testing.T) {
+	const nfd, nfc = "Cafe\u0301", "Caf\u00e9"
+
+	for _, name := range []string{"sample.flac", "sample.ogg"} {
+		f := tempCopy(t, "without_tags/"+name)
+		comments := map[string][]string{
+			"TITLE":            {nfd},
+			"ARTIST":           {nfd, "Artist"},
+			FieldVorbisPicture: {testVorbisPicture("front"), testVorbisPicture("back")},
+		}
+		if err := WriteVorbisComments(f, comments); err != nil {
+			t.Errorf("%s: WriteVorbisComments() = %v", name, err)
+			continue
+		}
+		if err := NormalizeUnicode(f); err != nil {
+			t.Errorf("%s: NormalizeUnicode() = %v", name, err)
+			continue
+		}
+
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("%s: ReadFrom() = %v", name, err)
+			continue
+		}
+		comments["TITLE"] = []string{nfc}
+		comments["ARTIST"] = []string{nfc, "Artist"}
+		if got := VorbisCommentsFrom(m); !reflect.DeepEqual(got, comments) {
+			t.Errorf("%s: VorbisCommentsFrom() = %v, expected %v", name, got, comments)
+		}
+	}
+}
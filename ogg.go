@@ -46,6 +46,17 @@ func oggCRCUpdate(crc uint32, tab *crc32Table, p []byte) uint32 {
 	return crc
 }
 
+// oggCRC returns the checksum of the encoded Ogg page, which is computed over the whole page
+// (header, segment table and data) with the CRC field taken as zero.
+func oggCRC(page []byte) uint32 {
+	if len(page) < 26 {
+		return oggCRCUpdate(0, oggCRC32Poly04c11db7, page)
+	}
+	crc := oggCRCUpdate(0, oggCRC32Poly04c11db7, page[:22])
+	crc = oggCRCUpdate(crc, oggCRC32Poly04c11db7, []byte{0, 0, 0, 0})
+	return oggCRCUpdate(crc, oggCRC32Poly04c11db7, page[26:])
+}
+
 type oggPageHeader struct {
 	Magic           [4]byte // "OggS"
 	Version         uint8
@@ -138,7 +149,12 @@ func (o *oggDemuxer) Read(r io.Reader) ([][]byte, error) {
 // See http://www.xiph.org/vorbis/doc/Vorbis_I_spec.html
 // and http://www.xiph.org/ogg/doc/framing.html for details.
 // For Opus see https://tools.ietf.org/html/rfc7845
+// If r is an io.ReadSeeker then the end of the data is read to find the duration of Vorbis audio
+// (see AudioMetadata).
 func ReadOGGTags(r io.Reader) (Metadata, error) {
+	m := &metadataOGG{
+		metadataVorbis: newMetadataVorbis(),
+	}
 	od := &oggDemuxer{}
 	for {
 		bs, err := od.Read(r)
@@ -148,16 +164,21 @@ func ReadOGGTags(r io.Reader) (Metadata, error) {
 
 		for _, b := range bs {
 			switch {
+			case bytes.HasPrefix(b, vorbisIdentPrefix):
+				// The identification header is the first packet, so is read before the comment.
+				m.audio, _ = readVorbisIdentHeader(b[len(vorbisIdentPrefix):])
 			case bytes.HasPrefix(b, vorbisCommentPrefix):
-				m := &metadataOGG{
-					newMetadataVorbis(),
-				}
 				err = m.readVorbisComment(bytes.NewReader(b[len(vorbisCommentPrefix):]))
+				if rs, ok := r.(io.ReadSeeker); ok && err == nil && m.audio != nil {
+					m.audio.TotalSamples, _ = oggLastGranulePosition(rs)
+				}
 				return m, err
-			case bytes.HasPrefix(b, opusTagsPrefix):
-				m := &metadataOGG{
-					newMetadataVorbis(),
+			case bytes.HasPrefix(b, opusHeadPrefix):
+				// The pre-skip (in samples at 48kHz) follows the version and channel count.
+				if len(b) >= 19 {
+					m.gapless = &GaplessInfo{EncoderDelay: int(binary.LittleEndian.Uint16(b[10:12]))}
 				}
+			case bytes.HasPrefix(b, opusTagsPrefix):
 				err = m.readVorbisComment(bytes.NewReader(b[len(opusTagsPrefix):]))
 				return m, err
 			}
@@ -167,6 +188,18 @@ func ReadOGGTags(r io.Reader) (Metadata, error) {
 
 type metadataOGG struct {
 	*metadataVorbis
+	audio   *AudioProperties // nil unless the Vorbis identification header is read
+	gapless *GaplessInfo     // nil unless the Opus identification header is read
+}
+
+// Gapless returns the pre-skip of Opus audio as the encoder delay (in samples at 48kHz, which
+// is the rate Opus is always decoded at).  The end of the audio is trimmed using the granule
+// position of the last page, so the encoder padding is not known.
+func (m *metadataOGG) Gapless() (GaplessInfo, bool) {
+	if m.gapless == nil {
+		return m.metadataVorbis.Gapless()
+	}
+	return *m.gapless, true
 }
 
 func (m *metadataOGG) FileType() FileType {
@@ -0,0 +1,364 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	vorbisIdentificationPrefix = []byte("\x01vorbis")
+	opusHeadPrefix             = []byte("OpusHead")
+)
+
+// oggMaxSegments is the maximum number of segments in an Ogg page.
+const oggMaxSegments = 255
+
+// oggPage is an Ogg page.  See http://www.xiph.org/ogg/doc/framing.html.
+type oggPage struct {
+	Header   oggPageHeader
+	Segments []byte // Segment table (lacing values).
+	Data     []byte
+}
+
+// readOGGPage reads an Ogg page from r.  The CRC is not checked.
+func readOGGPage(r io.Reader) (*oggPage, error) {
+	p := &oggPage{}
+	if err := binary.Read(r, binary.LittleEndian, &p.Header); err != nil {
+		return nil, err
+	}
+	if string(p.Header.Magic[:]) != "OggS" {
+		return nil, errors.New("expected 'OggS'")
+	}
+
+	var err error
+	p.Segments, err = readBytes(r, uint(p.Header.Segments))
+	if err != nil {
+		return nil, err
+	}
+	var n uint
+	for _, s := range p.Segments {
+		n += uint(s)
+	}
+	p.Data, err = readBytes(r, n)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// size returns the encoded size of the page.
+func (p *oggPage) size() int64 {
+	return 27 + int64(len(p.Segments)) + int64(len(p.Data))
+}
+
+// bytes returns the encoded page, with the CRC recomputed.
+func (p *oggPage) bytes() []byte {
+	p.Header.Segments = uint8(len(p.Segments))
+
+	buf := bytes.NewBuffer(make([]byte, 0, p.size()))
+	binary.Write(buf, binary.LittleEndian, &p.Header)
+	buf.Write(p.Segments)
+	buf.Write(p.Data)
+
+	b := buf.Bytes()
+	p.Header.CRC = oggCRC(b)
+	binary.LittleEndian.PutUint32(b[22:26], p.Header.CRC)
+	return b
+}
+
+// packets returns the packets which are completed in the page, with the data of any
+// unterminated packet at the end of the page appended to partial (which should contain the
+// data of the packet continued from the previous page).
+func (p *oggPage) packets(partial []byte) (packets [][]byte, rest []byte) {
+	var i int
+	for _, s := range p.Segments {
+		partial = append(partial, p.Data[i:i+int(s)]...)
+		i += int(s)
+		if s < 255 {
+			packets = append(packets, partial)
+			partial = nil
+		}
+	}
+	return packets, partial
+}
+
+// oggPaginate returns the pages containing packets, with the given serial number and
+// sequence numbers starting at seq.  The granule position of pages on which a packet ends
+// is set to granule, and -1 otherwise (as required by the Ogg specification).
+func oggPaginate(packets [][]byte, serial, seq uint32, granule uint64) []*oggPage {
+	var pages []*oggPage
+	p := &oggPage{}
+	continued := false
+	for _, b := range packets {
+		for {
+			if len(p.Segments) == oggMaxSegments {
+				pages = append(pages, p)
+				p = &oggPage{}
+			}
+			if len(p.Segments) == 0 && continued {
+				p.Header.Flags |= 0x1
+			}
+
+			n := len(b)
+			if n > 255 {
+				n = 255
+			}
+			p.Segments = append(p.Segments, byte(n))
+			p.Data = append(p.Data, b[:n]...)
+			b = b[n:]
+
+			// A packet which is a multiple of 255 bytes is terminated by a zero length segment.
+			if n < 255 {
+				continued = false
+				break
+			}
+			continued = true
+		}
+	}
+	if len(p.Segments) > 0 {
+		pages = append(pages, p)
+	}
+
+	for i, p := range pages {
+		copy(p.Header.Magic[:], "OggS")
+		p.Header.SerialNumber = serial
+		p.Header.SequenceNumber = seq + uint32(i)
+		p.Header.GranulePosition = ^uint64(0)
+		for _, s := range p.Segments {
+			if s < 255 {
+				p.Header.GranulePosition = granule
+				break
+			}
+		}
+	}
+	return pages
+}
+
+// WriteOGGTags replaces the comment header of the Ogg Vorbis or Opus data in rw with a
+// Vorbis comment containing the fields in data (see WriteFLACTags).  The pages containing the
+// header packets which follow the identification header are rewritten.  The bitstream serial
+// number is unchanged, and if the number of header pages changes then the sequence numbers
+// (and CRCs) of the following pages of the stream are updated.
+func WriteOGGTags(rw io.ReadWriteSeeker, data map[string]string) error {
+	data = normaliseFields(data)
+	return writeOGGComment(rw, func(vendor string) ([]byte, error) {
+		if _, ok := data["VENDOR"]; !ok {
+			data["VENDOR"] = vendor
+		}
+		return PrepareVorbisComment(data)
+	})
+}
+
+// updateOGGTags merges the fields in data into the Vorbis comment of the Ogg data in rw, as
+// UpdateFLACTags does for FLAC data.
+func updateOGGTags(rw io.ReadWriteSeeker, data map[string]*string) error {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	m, err := ReadOGGTags(rw)
+	if err != nil {
+		return err
+	}
+	values := m.(*metadataOGG).values
+	return writeOGGComment(rw, func(vendor string) ([]byte, error) {
+		return updateVorbisComment(vendor, values, data)
+	})
+}
+
+// setOGGPicture replaces the METADATA_BLOCK_PICTURE fields of the Ogg Vorbis or Opus data in
+// rw which have the same picture type as pic with pic (see EncodeVorbisPicture).  The other
+// fields, including pictures of other types, are kept unchanged (see WriteVorbisComments).
+func setOGGPicture(rw io.ReadWriteSeeker, pic *Picture) error {
+	v, err := EncodeVorbisPicture(pic)
+	if err != nil {
+		return err
+	}
+
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	m, err := ReadOGGTags(rw)
+	if err != nil {
+		return err
+	}
+	comments := VorbisCommentsFrom(m)
+
+	code := pictureTypeCode(pic.Type)
+	var pics []string
+	for _, p := range comments[FieldVorbisPicture] {
+		// The picture type is the first field of the block (32-bit big-endian).
+		b, err := base64.StdEncoding.DecodeString(p)
+		if err == nil && len(b) >= 4 && getInt(b[0:4]) == int(code) {
+			continue
+		}
+		pics = append(pics, p)
+	}
+	comments[FieldVorbisPicture] = append(pics, v)
+	return WriteVorbisComments(rw, comments)
+}
+
+// writeOGGComment replaces the comment header of the Ogg Vorbis or Opus data in rw with the
+// Vorbis comment returned by comment, which is passed the existing vendor string (see
+// WriteOGGTags).
+func writeOGGComment(rw io.ReadWriteSeeker, comment func(vendor string) ([]byte, error)) error {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	first, err := readOGGPage(rw)
+	if err != nil {
+		return err
+	}
+	serial := first.Header.SerialNumber
+	start := first.size()
+
+	var prefix []byte
+	var headers int // Number of header packets after the identification header.
+	switch {
+	case bytes.HasPrefix(first.Data, vorbisIdentificationPrefix):
+		prefix, headers = vorbisCommentPrefix, 2
+	case bytes.HasPrefix(first.Data, opusHeadPrefix):
+		prefix, headers = opusTagsPrefix, 1
+	default:
+		return errors.New("expected Vorbis or Opus identification header")
+	}
+
+	// Read the pages containing the remaining header packets, the audio data must begin on
+	// a new page.
+	var packets [][]byte
+	var partial []byte
+	var seq uint32
+	end := start
+	for pages := 0; len(packets) < headers; pages++ {
+		p, err := readOGGPage(rw)
+		if err != nil {
+			return fmt.Errorf("error reading Ogg header pages: %v", err)
+		}
+		if p.Header.SerialNumber != serial {
+			return errors.New("multiplexed Ogg streams are not supported")
+		}
+		if pages == 0 {
+			seq = p.Header.SequenceNumber
+		}
+		end += p.size()
+
+		var ps [][]byte
+		ps, partial = p.packets(partial)
+		packets = append(packets, ps...)
+	}
+	if len(packets) != headers || partial != nil {
+		return errors.New("audio data does not begin on a new Ogg page")
+	}
+	if !bytes.HasPrefix(packets[0], prefix) {
+		return errors.New("expected comment header")
+	}
+
+	m := newMetadataVorbis()
+	if err := m.readVorbisComment(bytes.NewReader(packets[0][len(prefix):])); err != nil {
+		return fmt.Errorf("error reading comment header: %v", err)
+	}
+	c, err := comment(m.c["vendor"])
+	if err != nil {
+		return err
+	}
+	packets[0] = append(append([]byte{}, prefix...), c...)
+	if headers == 2 {
+		packets[0] = append(packets[0], 1) // Vorbis framing bit
+	}
+
+	var b []byte
+	pages := oggPaginate(packets, serial, seq, 0)
+	for _, p := range pages {
+		b = append(b, p.bytes()...)
+	}
+
+	// Sequence number of the first page after the header pages.
+	if _, err := rw.Seek(end, io.SeekStart); err != nil {
+		return err
+	}
+	next := seq + uint32(len(pages))
+	if p, err := readOGGPage(rw); err == nil {
+		next = p.Header.SequenceNumber
+	}
+
+	if err := replaceRegion(rw, start, end-start, b); err != nil {
+		return err
+	}
+	return renumberOGGPages(rw, start+int64(len(b)), serial, int64(seq)+int64(len(pages))-int64(next))
+}
+
+// oggHeaderSize returns the size of the pages at the start of the Ogg Vorbis or Opus data in r
+// which contain the header packets (the audio data begins on a new page).
+func oggHeaderSize(r io.ReadSeeker) (int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var headers int // Number of header packets, including the identification header.
+	var n int64
+	var packets [][]byte
+	var partial []byte
+	for len(packets) < headers || headers == 0 {
+		p, err := readOGGPage(r)
+		if err != nil {
+			return 0, fmt.Errorf("error reading Ogg header pages: %v", err)
+		}
+		n += p.size()
+
+		var ps [][]byte
+		ps, partial = p.packets(partial)
+		packets = append(packets, ps...)
+
+		if headers == 0 && len(packets) > 0 {
+			switch {
+			case bytes.HasPrefix(packets[0], vorbisIdentificationPrefix):
+				headers = 3
+			case bytes.HasPrefix(packets[0], opusHeadPrefix):
+				headers = 2
+			default:
+				return 0, errors.New("expected Vorbis or Opus identification header")
+			}
+		}
+	}
+	return n, nil
+}
+
+// renumberOGGPages adds delta to the sequence numbers of the pages of the stream with the
+// given serial number in rw from offset onwards, updating the page CRCs.
+func renumberOGGPages(rw io.ReadWriteSeeker, offset int64, serial uint32, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+
+	for {
+		if _, err := rw.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		p, err := readOGGPage(rw)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("error reading Ogg page at offset %d: %v", offset, err)
+		}
+
+		if p.Header.SerialNumber == serial {
+			p.Header.SequenceNumber = uint32(int64(p.Header.SequenceNumber) + delta)
+			if _, err := rw.Seek(offset, io.SeekStart); err != nil {
+				return err
+			}
+			if _, err := rw.Write(p.bytes()); err != nil {
+				return err
+			}
+		}
+		offset += p.size()
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testOGGStream reads all the pages of the Ogg data in r (checking the CRCs), returning the
+// packets and the page headers.
+func testOGGStream(t *testing.T, r io.ReadSeeker) (packets [][]byte, headers []oggPageHeader) {
+	t.Helper()
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	od := &oggDemuxer{}
+	br := bytes.NewReader(b)
+	for br.Len() > 0 {
+		ps, err := od.Read(br)
+		if err != nil {
+			t.Fatalf("oggDemuxer.Read() = %v", err)
+		}
+		packets = append(packets, ps...)
+	}
+
+	br = bytes.NewReader(b)
+	for br.Len() > 0 {
+		p, err := readOGGPage(br)
+		if err != nil {
+			t.Fatalf("readOGGPage() = %v", err)
+		}
+		headers = append(headers, p.Header)
+	}
+	return packets, headers
+}
+
+func TestOGGCRC(t *testing.T) {
+	// The first page (Vorbis identification header) of testdata/without_tags/sample.ogg.
+	page, _ := hex.DecodeString("4f676753000200000000000000005c05e67e00000000a8a701f2011e01766f72626973" +
+		"000000000244ac00000000000000fa000000000000b801")
+	testValue(t, uint32(0xf201a7a8), oggCRC(page))
+
+	// The CRC field is ignored.
+	page[22], page[23], page[24], page[25] = 0, 0, 0, 0
+	testValue(t, uint32(0xf201a7a8), oggCRC(page))
+
+	testValue(t, uint32(0), oggCRC(nil))
+}
+
+func TestWriteOGGTags(t *testing.T) {
+	tests := []struct {
+		path  string
+		title string
+	}{
+		{"with_tags/sample.ogg", "New Title"},
+		{"with_tags/sample.ogg", strings.Repeat("Long Title ", 10000)}, // more pages
+		{"with_tags/sample.multipage.ogg", "New Title"},                // fewer pages
+		{"without_tags/sample.ogg", "New Title"},
+	}
+
+	for _, tt := range tests {
+		f := tempCopy(t, tt.path)
+		origPackets, origHeaders := testOGGStream(t, f)
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		orig, err := ReadOGGTags(f)
+		if err != nil {
+			t.Fatalf("%v: ReadOGGTags() = %v", tt.path, err)
+		}
+
+		if err := WriteOGGTags(f, map[string]string{"TITLE": tt.title}); err != nil {
+			t.Fatalf("%v: WriteOGGTags() = %v", tt.path, err)
+		}
+
+		packets, headers := testOGGStream(t, f)
+		if len(packets) != len(origPackets) {
+			t.Fatalf("%v: got %d packets, expected %d", tt.path, len(packets), len(origPackets))
+		}
+		// The identification, setup and audio packets are unchanged.
+		for i := range packets {
+			if i != 1 && !bytes.Equal(packets[i], origPackets[i]) {
+				t.Errorf("%v: packet %d changed", tt.path, i)
+			}
+		}
+
+		for i, h := range headers {
+			if h.SerialNumber != origHeaders[0].SerialNumber {
+				t.Errorf("%v: page %d serial number = %d, expected %d", tt.path, i, h.SerialNumber, origHeaders[0].SerialNumber)
+			}
+			if h.SequenceNumber != uint32(i) {
+				t.Errorf("%v: page %d sequence number = %d", tt.path, i, h.SequenceNumber)
+			}
+		}
+		last, origLast := headers[len(headers)-1], origHeaders[len(origHeaders)-1]
+		testValue(t, origLast.GranulePosition, last.GranulePosition)
+		testValue(t, origLast.Flags, last.Flags)
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadOGGTags(f)
+		if err != nil {
+			t.Fatalf("%v: ReadOGGTags() = %v", tt.path, err)
+		}
+		testValue(t, tt.title, m.Title())
+		testValue(t, "", m.Artist())
+		testValue(t, orig.Raw()["vendor"], m.Raw()["vendor"])
+	}
+}
+
+func TestOGGPaginate(t *testing.T) {
+	packets := [][]byte{
+		bytes.Repeat([]byte{1}, 255*300), // spans pages, ends with a zero length segment
+		{2, 3},
+	}
+	pages := oggPaginate(packets, 7, 1, 0)
+	if len(pages) != 2 {
+		t.Fatalf("got %d pages, expected 2", len(pages))
+	}
+	testValue(t, ^uint64(0), pages[0].Header.GranulePosition)
+	testValue(t, uint8(0), pages[0].Header.Flags)
+	testValue(t, uint8(1), pages[1].Header.Flags)
+	testValue(t, uint32(2), pages[1].Header.SequenceNumber)
+
+	var got [][]byte
+	var partial []byte
+	for _, p := range pages {
+		var ps [][]byte
+		ps, partial = p.packets(partial)
+		got = append(got, ps...)
+	}
+	if !reflect.DeepEqual(got, packets) {
+		t.Errorf("packets do not round trip")
+	}
+}
+
+func TestWriteOGGPicture(t *testing.T) {
+	pic := &Picture{
+		MIMEType:    "image/jpeg",
+		Type:        "Cover (back)",
+		Description: "Back",
+		Data:        append([]byte("\xff\xd8\xff\xe0"), bytes.Repeat([]byte{1, 2, 3}, 1000)...),
+	}
+	v, err := EncodeVorbisPicture(pic)
+	if err != nil {
+		t.Fatalf("EncodeVorbisPicture() = %v", err)
+	}
+
+	f := tempCopy(t, "with_tags/sample.ogg")
+	if err := WriteOGGTags(f, map[string]string{FieldTitle: "Title", FieldVorbisPicture: v}); err != nil {
+		t.Fatalf("WriteOGGTags() = %v", err)
+	}
+
+	// Rewriting the fields read from the file keeps the picture.
+	f.Seek(0, io.SeekStart)
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	data := make(map[string]string)
+	for k, v := range m.Raw() {
+		data[k] = v.(string)
+	}
+	data["title"] = "New Title"
+	if err := WriteOGGTags(f, data); err != nil {
+		t.Fatalf("WriteOGGTags() = %v", err)
+	}
+
+	f.Seek(0, io.SeekStart)
+	m, err = ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "New Title", m.Title())
+	got := m.Picture()
+	if got == nil {
+		t.Fatal("Picture() = nil")
+	}
+	testValue(t, "image/jpeg", got.MIMEType)
+	testValue(t, "jpg", got.Ext)
+	testValue(t, "Cover (back)", got.Type)
+	testValue(t, "Back", got.Description)
+	if !bytes.Equal(got.Data, pic.Data) {
+		t.Errorf("Picture().Data differs from the written data")
+	}
+}
+
+func TestReadOGGCoverArt(t *testing.T) {
+	data := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 100)...)
+	f := tempCopy(t, "with_tags/sample.ogg")
+	if err := WriteOGGTags(f, map[string]string{"COVERART": base64.StdEncoding.EncodeToString(data)}); err != nil {
+		t.Fatalf("WriteOGGTags() = %v", err)
+	}
+
+	f.Seek(0, io.SeekStart)
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	got := m.Picture()
+	if got == nil {
+		t.Fatal("Picture() = nil")
+	}
+	testValue(t, "image/png", got.MIMEType)
+	testValue(t, "png", got.Ext)
+	testValue(t, "Cover (front)", got.Type)
+	if !bytes.Equal(got.Data, data) {
+		t.Errorf("Picture().Data differs from the written data")
+	}
+}
+
+func TestReadOGGPictures(t *testing.T) {
+	front := &Picture{MIMEType: "image/jpeg", Type: pictureFrontCover, Description: "Front", Data: []byte("\xff\xd8\xff\xe0 front")}
+	back := &Picture{MIMEType: "image/png", Type: "Cover (back)", Description: "Back", Data: append(append([]byte{}, pngHeader...), "back"...)}
+	var values []string
+	for _, p := range []*Picture{front, back} {
+		v, err := EncodeVorbisPicture(p)
+		if err != nil {
+			t.Fatalf("EncodeVorbisPicture() = %v", err)
+		}
+		values = append(values, v)
+	}
+
+	f := tempCopy(t, "with_tags/sample.ogg")
+	if err := WriteVorbisComments(f, map[string][]string{FieldTitle: {"Title"}, FieldVorbisPicture: values}); err != nil {
+		t.Fatalf("WriteVorbisComments() = %v", err)
+	}
+
+	f.Seek(0, io.SeekStart)
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	pics := m.Pictures()
+	if len(pics) != 2 {
+		t.Fatalf("Pictures() returned %d pictures, expected 2", len(pics))
+	}
+	for i, want := range []*Picture{front, back} {
+		testValue(t, want.Type, pics[i].Type)
+		testValue(t, want.Description, pics[i].Description)
+		if !bytes.Equal(pics[i].Data, want.Data) {
+			t.Errorf("[%d] Data = %q, expected %q", i, pics[i].Data, want.Data)
+		}
+	}
+	if got := m.Picture(); got == nil || got.Description != "Front" {
+		t.Errorf("Picture() = %v, expected the front cover", got)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import "strconv"
+
+// Overlay returns Metadata which reflects the fields in edits (keyed by the Field* names, as
+// passed to the Write* functions) layered over m, so that changes can be previewed before they
+// are written.  Fields which are not in edits are taken from m, and a field with an empty value
+// clears it.  A TRACKNUMBER or DISCNUMBER of the form "x/n" also sets the total.  Neither m nor
+// any file is modified.
+func Overlay(m Metadata, edits map[string]string) Metadata {
+	return &metadataOverlay{Metadata: m, edits: normaliseFields(edits)}
+}
+
+// metadataOverlay is Metadata with edited fields (see Overlay).
+type metadataOverlay struct {
+	Metadata
+	edits map[string]string // normalised
+}
+
+// get returns the edited value of field, or orig if it hasn't been edited.
+func (m *metadataOverlay) get(field, orig string) string {
+	if v, ok := m.edits[field]; ok {
+		return v
+	}
+	return orig
+}
+
+func (m *metadataOverlay) Title() string {
+	return m.get(FieldTitle, m.Metadata.Title())
+}
+
+func (m *metadataOverlay) Album() string {
+	return m.get(FieldAlbum, m.Metadata.Album())
+}
+
+func (m *metadataOverlay) Artist() string {
+	return m.get(FieldArtist, m.Metadata.Artist())
+}
+
+func (m *metadataOverlay) AlbumArtist() string {
+	return m.get(FieldAlbumArtist, m.Metadata.AlbumArtist())
+}
+
+func (m *metadataOverlay) Composer() string {
+	return m.get(FieldComposer, m.Metadata.Composer())
+}
+
+func (m *metadataOverlay) Genre() string {
+	return m.get(FieldGenre, m.Metadata.Genre())
+}
+
+func (m *metadataOverlay) Comment() string {
+	return m.get(FieldComment, m.Metadata.Comment())
+}
+
+func (m *metadataOverlay) Lyrics() string {
+	return m.get(FieldLyrics, m.Metadata.Lyrics())
+}
+
+func (m *metadataOverlay) Mood() string {
+	return m.get(FieldMood, m.Metadata.Mood())
+}
+
+func (m *metadataOverlay) Copyright() string {
+	return m.get(FieldCopyright, m.Metadata.Copyright())
+}
+
+func (m *metadataOverlay) Publisher() string {
+	return m.get(FieldPublisher, m.Metadata.Publisher())
+}
+
+func (m *metadataOverlay) Owner() string {
+	return m.get(FieldOwner, m.Metadata.Owner())
+}
+
+func (m *metadataOverlay) Year() int {
+	_, date := m.edits[FieldDate]
+	_, year := m.edits[FieldYear]
+	if !date && !year {
+		return m.Metadata.Year()
+	}
+	y, _ := strconv.Atoi(fieldYear(m.edits))
+	return y
+}
+
+func (m *metadataOverlay) Track() (int, int) {
+	x, n := m.Metadata.Track()
+	return m.xOfN(FieldTrackNumber, FieldTrackTotal, x, n)
+}
+
+func (m *metadataOverlay) Disc() (int, int) {
+	x, n := m.Metadata.Disc()
+	return m.xOfN(FieldDiscNumber, FieldDiscTotal, x, n)
+}
+
+// xOfN returns the edited number and total, where x and n are the original values.
+func (m *metadataOverlay) xOfN(number, total string, x, n int) (int, int) {
+	if v, ok := m.edits[number]; ok {
+		var vn int
+		x, vn = parseXofN(v)
+		if vn != 0 {
+			n = vn
+		}
+	}
+	if v, ok := m.edits[total]; ok {
+		n, _ = strconv.Atoi(v)
+	}
+	return x, n
+}
@@ -0,0 +1,37 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import "testing"
+
+func TestOverlay(t *testing.T) {
+	m := testReadFLAC(t, tempCopy(t, "with_tags/sample.flac"))
+	title, artist, year := m.Title(), m.Artist(), m.Year()
+	_, total := m.Track()
+
+	o := Overlay(m, map[string]string{
+		"title":       "New Title",
+		"TRACKNUMBER": "7",
+		FieldComment:  "",
+	})
+	testValue(t, "New Title", o.Title())
+	testValue(t, artist, o.Artist())
+	testValue(t, year, o.Year())
+	testValue(t, "", o.Comment())
+	x, n := o.Track()
+	testValue(t, 7, x)
+	testValue(t, total, n)
+
+	// The original is unchanged.
+	testValue(t, title, m.Title())
+
+	o = Overlay(m, map[string]string{FieldTrackNumber: "3/12", FieldDiscTotal: "2", FieldDate: "1999-01-02"})
+	x, n = o.Track()
+	testValue(t, 3, x)
+	testValue(t, 12, n)
+	_, n = o.Disc()
+	testValue(t, 2, n)
+	testValue(t, 1999, o.Year())
+}
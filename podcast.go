@@ -0,0 +1,14 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+// PodcastInfo is the information stored by podcast clients (i.e. iTunes) about a podcast
+// episode.
+type PodcastInfo struct {
+	FeedURL     string // URL of the podcast feed.
+	EpisodeGUID string // GUID of the episode in the feed.
+	Keywords    string
+	Description string
+}
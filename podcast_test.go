@@ -0,0 +1,132 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestPodcastInfoID3v2(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Frames as written by iTunes for a subscribed podcast episode.
+	b := testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Episode 1")},
+		id3v2RawFrame{Name: "PCST", Data: []byte{0, 0, 0, 1}},
+		id3v2RawFrame{Name: "WFED", Data: []byte("\x00https://example.com/feed.xml\x00")},
+		id3v2RawFrame{Name: "TGID", Data: []byte("\x00urn:uuid:1234")},
+		id3v2RawFrame{Name: "TKWD", Data: []byte("\x00music,interview")},
+		id3v2RawFrame{Name: "TDES", Data: []byte("\x00The first episode.")},
+	)
+	b = append(b, audio...)
+
+	m, err := ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+
+	got, ok := m.PodcastInfo()
+	want := PodcastInfo{
+		FeedURL:     "https://example.com/feed.xml",
+		EpisodeGUID: "urn:uuid:1234",
+		Keywords:    "music,interview",
+		Description: "The first episode.",
+	}
+	if !ok || got != want {
+		t.Errorf("PodcastInfo() = %+v, %v, expected %+v, true", got, ok, want)
+	}
+}
+
+func TestPodcastInfoNotPodcast(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.flac",
+		"with_tags/sample.id3v24.mp3",
+		"with_tags/sample.m4a",
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+		if p, ok := m.PodcastInfo(); ok {
+			t.Errorf("%v: PodcastInfo() = %+v, true, expected false", path, p)
+		}
+	}
+}
+
+func TestPodcastInfoMP4(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, f,
+		mp4Item("pcst", 21, []byte{1}),
+		mp4Item("purl", 0, []byte("https://example.com/feed.xml")),
+		mp4Item("egid", 0, []byte("urn:uuid:1234")),
+		mp4Item("desc", 1, []byte("The first episode.")),
+	)
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+
+	got, ok := m.PodcastInfo()
+	want := PodcastInfo{
+		FeedURL:     "https://example.com/feed.xml",
+		EpisodeGUID: "urn:uuid:1234",
+		Description: "The first episode.",
+	}
+	if !ok || got != want {
+		t.Errorf("PodcastInfo() = %+v, %v, expected %+v, true", got, ok, want)
+	}
+}
+
+func TestWritePodcastInfo(t *testing.T) {
+	data := map[string]string{
+		FieldTitle:       "Episode 1",
+		FieldPodcastURL:  "https://example.com/feed.xml",
+		FieldPodcastGUID: "urn:uuid:1234",
+	}
+	want := PodcastInfo{
+		FeedURL:     "https://example.com/feed.xml",
+		EpisodeGUID: "urn:uuid:1234",
+	}
+
+	for _, path := range []string{"without_tags/sample.mp3", "without_tags/sample.flac"} {
+		f := tempCopy(t, path)
+		var err error
+		if path == "without_tags/sample.mp3" {
+			err = WriteID3Both(f, data)
+		} else {
+			err = WriteFLACTags(f, data)
+		}
+		if err != nil {
+			t.Fatalf("%v: write = %v", path, err)
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+		if got, ok := m.PodcastInfo(); !ok || got != want {
+			t.Errorf("%v: PodcastInfo() = %+v, %v, expected %+v, true", path, got, ok, want)
+		}
+	}
+}
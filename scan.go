@@ -0,0 +1,113 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TagResult is the result of reading the metadata of a single file in ScanDir.
+type TagResult struct {
+	Path     string   // Path of the file.
+	Metadata Metadata // Metadata read from the file, nil if Err is non-nil.
+	Err      error    // Error reading the file (or walking the directory).
+}
+
+// ScanDir walks the directory tree rooted at dir and reads the metadata of each regular file
+// using the given number of concurrent workers.  Results are sent on the returned channel (in
+// no particular order), which is closed once all files have been read.  The channel must be
+// drained by the caller, see ScanDirContext to stop a scan early.
+func ScanDir(dir string, workers int) (<-chan TagResult, error) {
+	return ScanDirContext(context.Background(), dir, workers)
+}
+
+// ScanDirContext is like ScanDir, but stops the scan when ctx is done.  The returned channel is
+// closed once all goroutines started by the scan have exited.
+func ScanDirContext(ctx context.Context, dir string, workers int) (<-chan TagResult, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%v is not a directory", dir)
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	paths := make(chan string)
+	results := make(chan TagResult)
+
+	send := func(r TagResult) bool {
+		select {
+		case results <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(paths)
+
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if !send(TagResult{Path: path, Err: err}) {
+					return ctx.Err()
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				m, err := readFile(path)
+				if !send(TagResult{Path: path, Metadata: m, Err: err}) {
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results, nil
+}
+
+// readFile reads the metadata from the file at path.
+func readFile(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadFrom(f)
+}
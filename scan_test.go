@@ -0,0 +1,100 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// scanTestDir copies the tagged testdata files into a temporary directory tree.
+func scanTestDir(t *testing.T) (string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	names := []string{
+		"sample.flac",
+		"sample.id3v23.mp3",
+		"sample.id3v24.mp3",
+		"sample.m4a",
+		"sample.ogg",
+	}
+	for i, name := range names {
+		b, err := os.ReadFile(filepath.Join("testdata", "with_tags", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sub := dir
+		if i%2 == 0 {
+			sub = filepath.Join(dir, "sub")
+			if err := os.MkdirAll(sub, 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := os.WriteFile(filepath.Join(sub, name), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, names
+}
+
+func TestScanDir(t *testing.T) {
+	dir, names := scanTestDir(t)
+
+	ch, err := ScanDir(dir, 3)
+	if err != nil {
+		t.Fatalf("ScanDir() = %v", err)
+	}
+
+	got := make(map[string]bool)
+	for r := range ch {
+		if r.Err != nil {
+			t.Errorf("%v: %v", r.Path, r.Err)
+			continue
+		}
+		testValue(t, "Test Title", r.Metadata.Title())
+		got[filepath.Base(r.Path)] = true
+	}
+
+	for _, name := range names {
+		if !got[name] {
+			t.Errorf("no result for %v", name)
+		}
+	}
+}
+
+func TestScanDirNotDirectory(t *testing.T) {
+	if _, err := ScanDir(filepath.Join("testdata", "with_tags", "sample.flac"), 1); err == nil {
+		t.Errorf("expected error scanning a file")
+	}
+}
+
+func TestScanDirContextCancel(t *testing.T) {
+	dir, _ := scanTestDir(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := ScanDirContext(ctx, dir, 2)
+	if err != nil {
+		t.Fatalf("ScanDirContext() = %v", err)
+	}
+
+	<-ch
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("results channel not closed after cancel")
+		}
+	}
+}
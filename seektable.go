@@ -0,0 +1,108 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// flacSeekPointSize is the size of a seek point in a SEEKTABLE block.
+const flacSeekPointSize = 18
+
+// HasSeekTable returns true if the FLAC data in r has a SEEKTABLE metadata block.
+func HasSeekTable(r io.ReadSeeker) (bool, error) {
+	blocks, _, err := readFLACBlocks(r)
+	if err != nil {
+		return false, err
+	}
+	for _, b := range blocks {
+		if b.Type == seekTableBlock {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GenerateSeekTable adds a SEEKTABLE metadata block to the FLAC data in rw (replacing any
+// existing one) with a seek point for every interval of audio.  Each seek point refers to the
+// frame containing the target sample, found by scanning the audio frames.  Trailing padding is
+// used to make room for the block where possible, so that the audio data does not have to be moved.
+func GenerateSeekTable(rw io.ReadWriteSeeker, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid seek table interval: %v", interval)
+	}
+	blocks, audioOffset, err := readFLACBlocks(rw)
+	if err != nil {
+		return err
+	}
+	if blocks[0].Type != streamInfoBlock {
+		return errors.New("first FLAC metadata block must be STREAMINFO")
+	}
+
+	si, err := readFLACStreamInfo(blocks[0].Data)
+	if err != nil {
+		return err
+	}
+	step := uint64(interval.Seconds() * float64(si.SampleRate))
+	if step == 0 {
+		return fmt.Errorf("invalid seek table interval: %v", interval)
+	}
+
+	if _, err := rw.Seek(audioOffset, io.SeekStart); err != nil {
+		return err
+	}
+	frames, err := scanFLACFrames(rw, si)
+	if err != nil {
+		return err
+	}
+	if len(frames) == 0 {
+		return errors.New("no FLAC audio frames found")
+	}
+
+	total := si.TotalSamples
+	if total == 0 {
+		last := frames[len(frames)-1]
+		total = last.Sample + uint64(last.BlockSize)
+	}
+
+	// Seek points must be unique and in ascending order, so targets within the same
+	// frame share a single point.
+	var data []byte
+	i := 0
+	for target := uint64(0); target < total; target += step {
+		prev := i
+		for i+1 < len(frames) && frames[i+1].Sample <= target {
+			i++
+		}
+		if i == prev && len(data) > 0 {
+			continue
+		}
+
+		p := make([]byte, flacSeekPointSize)
+		binary.BigEndian.PutUint64(p[0:8], frames[i].Sample)
+		binary.BigEndian.PutUint64(p[8:16], uint64(frames[i].Offset))
+		binary.BigEndian.PutUint16(p[16:18], uint16(frames[i].BlockSize))
+		data = append(data, p...)
+	}
+
+	// Replace any existing seek tables, the new one follows STREAMINFO.
+	delta := 4 + len(data)
+	kept := blocks[:1]
+	for _, b := range blocks[1:] {
+		if b.Type == seekTableBlock {
+			delta -= 4 + len(b.Data)
+			continue
+		}
+		kept = append(kept, b)
+	}
+	blocks = append(kept[:1], append([]flacBlock{{Type: seekTableBlock, Data: data}}, kept[1:]...)...)
+
+	resizeFLACPadding(blocks, delta)
+	return writeFLACBlocks(rw, blocks, audioOffset, FLACWriteOptions{})
+}
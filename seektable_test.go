@@ -0,0 +1,119 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestScanFLACFrames(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	blocks, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+	si, err := readFLACStreamInfo(blocks[0].Data)
+	if err != nil {
+		t.Fatalf("readFLACStreamInfo() = %v", err)
+	}
+	testValue(t, 11025, si.SampleRate)
+	testValue(t, uint64(37478), si.TotalSamples)
+
+	if _, err := f.Seek(audioOffset, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	frames, err := scanFLACFrames(f, si)
+	if err != nil {
+		t.Fatalf("scanFLACFrames() = %v", err)
+	}
+
+	// 37478 samples in blocks of 1152.
+	testValue(t, 33, len(frames))
+	last := frames[len(frames)-1]
+	testValue(t, si.TotalSamples, last.Sample+uint64(last.BlockSize))
+}
+
+func TestGenerateSeekTable(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	size := int64(len(readAll(t, f)))
+
+	ok, err := HasSeekTable(f)
+	if err != nil {
+		t.Fatalf("HasSeekTable() = %v", err)
+	}
+	if ok {
+		t.Fatal("HasSeekTable() = true, expected false for sample")
+	}
+
+	// Run twice to check that the existing seek table is replaced.
+	for i := 0; i < 2; i++ {
+		if err := GenerateSeekTable(f, time.Second); err != nil {
+			t.Fatalf("GenerateSeekTable() = %v", err)
+		}
+	}
+
+	ok, err = HasSeekTable(f)
+	if err != nil {
+		t.Fatalf("HasSeekTable() = %v", err)
+	}
+	if !ok {
+		t.Fatal("HasSeekTable() = false, expected true")
+	}
+
+	blocks, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+	var tables [][]byte
+	for _, b := range blocks {
+		if b.Type == seekTableBlock {
+			tables = append(tables, b.Data)
+		}
+	}
+	if len(tables) != 1 {
+		t.Fatalf("found %d SEEKTABLE blocks, expected 1", len(tables))
+	}
+
+	// 3.4s of audio, with points at 0s, 1s, 2s and 3s.
+	table := tables[0]
+	testValue(t, 4*flacSeekPointSize, len(table))
+
+	b := readAll(t, f)
+	for i := 0; i < len(table); i += flacSeekPointSize {
+		sample := binary.BigEndian.Uint64(table[i:])
+		offset := audioOffset + int64(binary.BigEndian.Uint64(table[i+8:]))
+		if target := uint64(i/flacSeekPointSize) * 11025; sample > target || target-sample >= 1152 {
+			t.Errorf("seek point %d: sample %d, expected frame containing sample %d", i/flacSeekPointSize, sample, target)
+		}
+		if b[offset] != 0xFF || b[offset+1] != 0xF8 {
+			t.Errorf("seek point %d: offset %d is not a frame header", i/flacSeekPointSize, offset)
+		}
+	}
+
+	// The seek table is made from the padding.
+	testValue(t, size, int64(len(b)))
+	compareMetadata(t, testReadFLAC(t, f), fullMetadata)
+}
+
+func TestGenerateSeekTableInvalid(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		f := tempCopy(t, "with_tags/sample.flac")
+		if err := GenerateSeekTable(f, interval); err == nil {
+			t.Errorf("GenerateSeekTable(%v) = nil, expected error", interval)
+		}
+	}
+
+	// The first block must be STREAMINFO, even if its content would be valid.
+	f := tempCopy(t, "with_tags/sample.flac")
+	if _, err := f.WriteAt([]byte{byte(paddingBlock)}, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateSeekTable(f, time.Second); err == nil {
+		t.Errorf("GenerateSeekTable() = nil, expected error for missing STREAMINFO block")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SyncedLyric is a line of lyrics with the time at which it starts.
+type SyncedLyric struct {
+	Time time.Duration
+	Text string
+}
+
+// SyncedLyricsMetadata is implemented by Metadata with synchronised lyrics (i.e. as returned
+// by ReadWithSidecars when there is an .lrc file).
+type SyncedLyricsMetadata interface {
+	Metadata
+
+	// SyncedLyrics returns the lines of the lyrics, ordered by time.
+	SyncedLyrics() []SyncedLyric
+}
+
+// ReadWithSidecars reads the metadata from the file at path (see ReadFrom).  If the file has no
+// embedded lyrics, then the lyrics are read from an LRC file with the same name and the extension
+// ".lrc" (if there is one): the returned Metadata implements SyncedLyricsMetadata, and Lyrics
+// returns the text of the lines.  As the returned Metadata wraps that of the file, other optional
+// interfaces (i.e. AudioMetadata) are not available in that case.
+func ReadWithSidecars(path string) (Metadata, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil || m.Lyrics() != "" {
+		return m, err
+	}
+
+	b, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".lrc")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	lines := parseLRC(string(b))
+	if len(lines) == 0 {
+		return m, nil
+	}
+	return &metadataSidecar{Metadata: m, lyrics: lines}, nil
+}
+
+// metadataSidecar is Metadata with lyrics read from an LRC file.
+type metadataSidecar struct {
+	Metadata
+	lyrics []SyncedLyric
+}
+
+func (m *metadataSidecar) Lyrics() string {
+	text := make([]string, len(m.lyrics))
+	for i, l := range m.lyrics {
+		text[i] = l.Text
+	}
+	return strings.Join(text, "\n")
+}
+
+func (m *metadataSidecar) SyncedLyrics() []SyncedLyric {
+	return m.lyrics
+}
+
+// parseLRC returns the timed lines of the LRC data in s, ordered by time.  Lines may have
+// several timestamps ("[mm:ss.xx]"), and ID tags (i.e. "[ar:Artist]") are ignored.
+func parseLRC(s string) []SyncedLyric {
+	var lines []SyncedLyric
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		var times []time.Duration
+		for strings.HasPrefix(line, "[") {
+			i := strings.IndexByte(line, ']')
+			if i < 0 {
+				break
+			}
+			t, ok := parseLRCTime(line[1:i])
+			if !ok {
+				break
+			}
+			times = append(times, t)
+			line = line[i+1:]
+		}
+		for _, t := range times {
+			lines = append(lines, SyncedLyric{Time: t, Text: strings.TrimSpace(line)})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time < lines[j].Time })
+	return lines
+}
+
+// parseLRCTime parses an LRC timestamp of the form "mm:ss" or "mm:ss.xx".
+func parseLRCTime(s string) (time.Duration, bool) {
+	mm, ss, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, false
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil || m < 0 {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(ss, 64)
+	if err != nil || f < 0 || f >= 60 {
+		return 0, false
+	}
+	return time.Duration(m)*time.Minute + time.Duration(f*float64(time.Second)+0.5), true
+}
@@ -0,0 +1,57 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadWithSidecars(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.id3v24.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "track.mp3")
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a sidecar file.
+	m, err := ReadWithSidecars(path)
+	if err != nil {
+		t.Fatalf("ReadWithSidecars() = %v", err)
+	}
+	if _, ok := m.(SyncedLyricsMetadata); ok {
+		t.Errorf("ReadWithSidecars() implements SyncedLyricsMetadata without a sidecar file")
+	}
+
+	lrc := "[ar:Artist]\n[ti:Title]\r\n[00:12.50]Second line\n[00:01.00][00:30.00]First line\n"
+	if err := os.WriteFile(filepath.Join(dir, "track.lrc"), []byte(lrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadWithSidecars(path)
+	if err != nil {
+		t.Fatalf("ReadWithSidecars() = %v", err)
+	}
+	sm, ok := m.(SyncedLyricsMetadata)
+	if !ok {
+		t.Fatalf("ReadWithSidecars() does not implement SyncedLyricsMetadata")
+	}
+	want := []SyncedLyric{
+		{time.Second, "First line"},
+		{12500 * time.Millisecond, "Second line"},
+		{30 * time.Second, "First line"},
+	}
+	if got := sm.SyncedLyrics(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SyncedLyrics() = %v, expected: %v", got, want)
+	}
+	testValue(t, "First line\nSecond line\nFirst line", m.Lyrics())
+	testValue(t, MP3, m.FileType())
+}
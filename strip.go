@@ -0,0 +1,71 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"errors"
+	"io"
+)
+
+// StripTagsTo writes a copy of the data in r without metadata to w, leaving r unchanged.  For
+// MP3 data the ID3v2 tag and any trailing APEv2 and ID3v1 tags are removed.  For FLAC data only
+// the STREAMINFO and SEEKTABLE blocks (which describe the audio) are kept.  Returns
+// ErrUnsupportedFormat for other file types.
+func StripTagsTo(w io.Writer, r io.ReadSeeker) error {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, fileType, err := sniffFormat(r)
+	if err != nil {
+		return err
+	}
+
+	var start, end int64
+	switch fileType {
+	case FLAC:
+		blocks, audioOffset, err := readFLACBlocks(r)
+		if err != nil {
+			return err
+		}
+		kept := blocks[:0]
+		for _, b := range blocks {
+			if b.Type == streamInfoBlock || b.Type == seekTableBlock {
+				kept = append(kept, b)
+			}
+		}
+		b, err := encodeFLACBlocks(kept)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		start = audioOffset
+
+	case MP3:
+		if start, err = id3v2TagSize(r); err != nil {
+			return err
+		}
+		if end, err = trailerTagSize(r); err != nil {
+			return err
+		}
+
+	default:
+		return ErrUnsupportedFormat
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if start+end > size {
+		return errors.New("tag sizes exceed the size of the data")
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, r, size-end-start)
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestStripTagsTo(t *testing.T) {
+	// The tagged MP3 files have the same audio as the untagged file.
+	mp3, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"with_tags/sample.id3v11.mp3",
+		"with_tags/sample.id3v24.mp3",
+		"without_tags/sample.mp3",
+	} {
+		f := tempCopy(t, path)
+		orig := readAll(t, f)
+
+		var buf bytes.Buffer
+		if err := StripTagsTo(&buf, f); err != nil {
+			t.Fatalf("%v: StripTagsTo() = %v", path, err)
+		}
+		if !bytes.Equal(orig, readAll(t, f)) {
+			t.Errorf("%v: source was modified", path)
+		}
+		if !bytes.Equal(buf.Bytes(), mp3) {
+			t.Errorf("%v: output is not the untagged audio (%d bytes, expected %d)", path, buf.Len(), len(mp3))
+		}
+	}
+
+	f := tempCopy(t, "with_tags/sample.flac")
+	var buf bytes.Buffer
+	if err := StripTagsTo(&buf, f); err != nil {
+		t.Fatalf("StripTagsTo() = %v", err)
+	}
+	blocks, _, err := readFLACBlocks(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readFLACBlocks() = %v", err)
+	}
+	for _, b := range blocks {
+		if b.Type != streamInfoBlock && b.Type != seekTableBlock {
+			t.Errorf("%v block was not removed", b.Type)
+		}
+	}
+	f.Seek(0, io.SeekStart)
+	want, err := SumFLAC(f)
+	if err != nil {
+		t.Fatalf("SumFLAC() = %v", err)
+	}
+	got, err := SumFLAC(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("SumFLAC() = %v", err)
+	}
+	testValue(t, want, got)
+
+	if err := StripTagsTo(&buf, tempCopy(t, "with_tags/sample.m4a")); err != ErrUnsupportedFormat {
+		t.Errorf("StripTagsTo() = %v, expected %v", err, ErrUnsupportedFormat)
+	}
+
+	// An ID3v2 tag size larger than the data is an error, rather than an empty copy.
+	corrupt := append([]byte("ID3\x04\x00\x00\x7f\x7f\x7f\x7f"), mp3[:64]...)
+	if err := StripTagsTo(&buf, bytes.NewReader(corrupt)); err == nil {
+		t.Errorf("StripTagsTo() with corrupt ID3v2 size = nil, expected error")
+	}
+}
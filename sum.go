@@ -12,24 +12,19 @@ import (
 // Sum creates a checksum of the audio file data provided by the io.ReadSeeker which is metadata
 // (ID3, MP4) invariant.
 func Sum(r io.ReadSeeker) (string, error) {
-	b, err := readBytes(r, 11)
+	format, fileType, err := sniffFormat(r)
 	if err != nil {
 		return "", err
 	}
 
-	_, err = r.Seek(-11, io.SeekCurrent)
-	if err != nil {
-		return "", fmt.Errorf("could not seek back to original position: %v", err)
-	}
-
 	switch {
-	case string(b[0:4]) == "fLaC":
+	case fileType == FLAC:
 		return SumFLAC(r)
 
-	case string(b[4:11]) == "ftypM4A":
+	case fileType == M4A:
 		return SumAtoms(r)
 
-	case string(b[0:3]) == "ID3":
+	case fileType == MP3 && format != UnknownFormat:
 		return SumID3v2(r)
 	}
 
@@ -194,18 +189,164 @@ func SumFLAC(r io.ReadSeeker) (string, error) {
 	return hashSum(h), nil
 }
 
-func skipFLACMetadataBlock(r io.ReadSeeker) (last bool, err error) {
-	blockHeader, err := readBytes(r, 1)
+// AudioFingerprint returns a checksum of the audio data in r, excluding all metadata, so that
+// it is unchanged when the tags are edited (unlike Sum, which only excludes some metadata).
+// The audio data is: the frames after the FLAC metadata blocks, the MPEG frames between the
+// ID3v2 tag and any trailing APEv2 and ID3v1 tags of MP3 data, the mdat atoms of MP4 data,
+// the data of the Ogg pages after the header pages (as the page sequence numbers change if the
+// comment header is resized) and the SSND chunk of AIFF data.  Returns ErrUnsupportedFormat
+// for other file types.
+func AudioFingerprint(r io.ReadSeeker) (string, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	format, fileType, err := sniffFormat(r)
 	if err != nil {
-		return
+		return "", err
+	}
+
+	h := sha1.New()
+	var start, end int64 // audio data region, end is relative to the end of r
+	switch {
+	case format == MP4:
+		if err := hashMP4Data(h, r); err != nil {
+			return "", err
+		}
+		return hashSum(h), nil
+
+	case fileType == OGG:
+		if err := hashOGGAudioPages(h, r); err != nil {
+			return "", err
+		}
+		return hashSum(h), nil
+
+	case fileType == FLAC:
+		if _, err := r.Seek(4, io.SeekStart); err != nil {
+			return "", err
+		}
+		n, err := flacMetadataSize(r)
+		if err != nil {
+			return "", err
+		}
+		start = 4 + n
+
+	case fileType == MP3:
+		if start, err = id3v2TagSize(r); err != nil {
+			return "", err
+		}
+		if end, err = trailerTagSize(r); err != nil {
+			return "", err
+		}
+
+	case fileType == AIFF:
+		chunks, _, err := readAIFFChunks(r)
+		if err != nil {
+			return "", err
+		}
+		for _, c := range chunks {
+			if c.ID != "SSND" {
+				continue
+			}
+			if _, err := r.Seek(c.Offset, io.SeekStart); err != nil {
+				return "", err
+			}
+			if _, err := io.CopyN(h, r, c.Size); err != nil {
+				return "", fmt.Errorf("error reading AIFF SSND chunk: %v", err)
+			}
+			return hashSum(h), nil
+		}
+		return "", errors.New("no AIFF SSND chunk found")
+
+	default:
+		return "", ErrUnsupportedFormat
+	}
+
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return "", err
+	}
+	if start+end > size {
+		return "", errors.New("tag sizes exceed the size of the data")
+	}
+	if _, err := r.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	if _, err := io.CopyN(h, r, size-end-start); err != nil {
+		return "", fmt.Errorf("error reading audio data: %v", err)
+	}
+	return hashSum(h), nil
+}
+
+// hashMP4Data writes the contents of the top level mdat atoms of the MP4 data in r to h.
+func hashMP4Data(h hash.Hash, r io.ReadSeeker) error {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for offset := int64(0); offset+8 <= size; {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		name, n, err := readAtomHeader(r)
+		if err != nil {
+			return err
+		}
+
+		atomSize, headerSize := int64(n), int64(8)
+		switch n {
+		case 0:
+			atomSize = size - offset
+		case 1:
+			var n64 uint64
+			if err := binary.Read(r, binary.BigEndian, &n64); err != nil {
+				return err
+			}
+			atomSize, headerSize = int64(n64), 16
+		}
+		if atomSize < headerSize || atomSize > size-offset {
+			return fmt.Errorf("invalid %q atom size: %d", name, atomSize)
+		}
+
+		if name == "mdat" {
+			if _, err := io.CopyN(h, r, atomSize-headerSize); err != nil {
+				return fmt.Errorf("error reading audio data: %v", err)
+			}
+			found = true
+		}
+		offset += atomSize
+	}
+	if !found {
+		return errors.New("no mdat atom found")
 	}
+	return nil
+}
 
-	if getBit(blockHeader[0], 7) {
-		blockHeader[0] ^= (1 << 7)
-		last = true
+// hashOGGAudioPages writes the data of the pages following the header pages of the Ogg data
+// in r to h.
+func hashOGGAudioPages(h hash.Hash, r io.ReadSeeker) error {
+	n, err := oggHeaderSize(r)
+	if err != nil {
+		return err
 	}
+	if _, err := r.Seek(n, io.SeekStart); err != nil {
+		return err
+	}
+	for {
+		p, err := readOGGPage(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("error reading Ogg page: %v", err)
+		}
+		h.Write(p.Data)
+	}
+}
 
-	blockLen, err := readInt(r, 3)
+func skipFLACMetadataBlock(r io.ReadSeeker) (last bool, err error) {
+	_, last, blockLen, err := readFLACBlockHeader(r)
 	if err != nil {
 		return
 	}
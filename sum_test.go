@@ -2,6 +2,7 @@ package tag
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 )
 
@@ -38,3 +39,50 @@ func fuzzIssue73(in []byte) {
 
 	Sum(r)
 }
+
+func TestAudioFingerprint(t *testing.T) {
+	data := map[string]string{
+		FieldTitle:  strings.Repeat("Title", 500), // larger than the existing padding
+		FieldArtist: "Artist",
+	}
+	for _, name := range []string{
+		"with_tags/sample.flac",
+		"with_tags/sample.id3v24.mp3",
+		"without_tags/sample.mp3",
+		"with_tags/sample.m4a",
+		"with_tags/sample.ogg",
+	} {
+		f := tempCopy(t, name)
+		want, err := AudioFingerprint(f)
+		if err != nil {
+			t.Fatalf("%s: AudioFingerprint() = %v", name, err)
+		}
+		if err := writeTags(f, data); err != nil {
+			t.Fatalf("%s: writeTags() = %v", name, err)
+		}
+		got, err := AudioFingerprint(f)
+		if err != nil {
+			t.Fatalf("%s: AudioFingerprint() after writing = %v", name, err)
+		}
+		if got != want {
+			t.Errorf("%s: AudioFingerprint() = %v after writing tags, expected %v", name, got, want)
+		}
+	}
+
+	// The audio data is included.
+	flac := testFLAC(testFLACBlock(paddingBlock, true, make([]byte, 16)))
+	want, err := AudioFingerprint(bytes.NewReader(flac))
+	if err != nil {
+		t.Fatalf("AudioFingerprint() = %v", err)
+	}
+	flac[len(flac)-1]++
+	if got, _ := AudioFingerprint(bytes.NewReader(flac)); got == want {
+		t.Errorf("AudioFingerprint() unchanged when the audio data changed")
+	}
+
+	// An ID3v2 tag size larger than the data is an error, rather than an empty fingerprint.
+	corrupt := append([]byte("ID3\x04\x00\x00\x7f\x7f\x7f\x7f"), make([]byte, 64)...)
+	if _, err := AudioFingerprint(bytes.NewReader(corrupt)); err == nil {
+		t.Errorf("AudioFingerprint() with corrupt ID3v2 size = nil, expected error")
+	}
+}
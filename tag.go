@@ -16,7 +16,6 @@ package tag
 
 import (
 	"errors"
-	"fmt"
 	"io"
 )
 
@@ -24,35 +23,97 @@ import (
 // cannot be identified.
 var ErrNoTagsFound = errors.New("no tags found")
 
-// ReadFrom detects and parses audio file metadata tags (currently supports ID3v1,2.{2,3,4}, MP4, FLAC/OGG).
+// MaxPictureBytes is the size of the largest picture which is read.  Larger pictures are
+// skipped without being read into memory (and reported as warnings by ReadFromStrict).  If
+// MaxPictureBytes is zero or negative then pictures of any size are read.
+var MaxPictureBytes int64 = 32 << 20 // 32MiB
+
+// ReadOptions are the options of ReadFromWithOptions.  The zero value is used by ReadFrom.
+type ReadOptions struct {
+	// LazyPictures stops picture data in ID3v2 APIC frames, FLAC PICTURE blocks and MP4 covr
+	// atoms from being read with the metadata.  The Data of these pictures is nil, and the data
+	// is read on demand by Picture.Read using the original reader.  ID3v2 pictures in
+	// unsynchronised, compressed or encrypted frames are always read.
+	LazyPictures bool
+}
+
+// errPictureTooLarge is used internally when a picture is skipped because of MaxPictureBytes.
+var errPictureTooLarge = errors.New("picture exceeds MaxPictureBytes")
+
+// pictureTooLarge returns true if a picture of n bytes should be skipped.
+func pictureTooLarge(n int64) bool {
+	return MaxPictureBytes > 0 && n > MaxPictureBytes
+}
+
+// ReadFrom detects and parses audio file metadata tags (currently supports ID3v1,2.{2,3,4}, MP4, FLAC/OGG, DSF, DSDIFF and AIFF).
 // Returns non-nil error if the format of the given data could not be determined, or if there was a problem
 // parsing the data.
 func ReadFrom(r io.ReadSeeker) (Metadata, error) {
-	b, err := readBytes(r, 11)
+	return readFrom(r, nil, ReadOptions{})
+}
+
+// ReadFromWithOptions is like ReadFrom, but with the given options.
+func ReadFromWithOptions(r io.ReadSeeker, opts ReadOptions) (Metadata, error) {
+	return readFrom(r, nil, opts)
+}
+
+// ReadFromAt is like ReadFrom, but reads the metadata from the first size bytes of r using
+// positioned reads, so r can be shared between concurrent readers (i.e. an *os.File or a reader
+// making HTTP range requests).
+func ReadFromAt(r io.ReaderAt, size int64) (Metadata, error) {
+	return ReadFrom(io.NewSectionReader(r, 0, size))
+}
+
+// ExtractPictures returns all the pictures embedded in the metadata read from r (see ReadFrom),
+// with their type and description.  The picture data is always read (see ReadOptions), so the
+// pictures can be used after r is closed.
+func ExtractPictures(r io.ReadSeeker) ([]*Picture, error) {
+	m, err := ReadFrom(r)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = r.Seek(-11, io.SeekCurrent)
+	var pics []*Picture
+	for _, p := range m.Pictures() {
+		data, err := p.Read()
+		if err != nil {
+			return nil, err
+		}
+		c := *p
+		c.Data, c.r = data, nil
+		pics = append(pics, &c)
+	}
+	return pics, nil
+}
+
+// readFrom implements ReadFrom, adding any non-fatal problems to w.
+func readFrom(r io.ReadSeeker, w *warnings, opts ReadOptions) (Metadata, error) {
+	format, fileType, err := sniffFormat(r)
 	if err != nil {
-		return nil, fmt.Errorf("could not seek back to original position: %v", err)
+		return nil, err
 	}
 
 	switch {
-	case string(b[0:4]) == "fLaC":
-		return ReadFLACTags(r)
+	case fileType == FLAC:
+		return readFLACTags(r, w, opts)
 
-	case string(b[0:4]) == "OggS":
+	case fileType == OGG:
 		return ReadOGGTags(r)
 
-	case string(b[4:8]) == "ftyp":
-		return ReadAtoms(r)
+	case format == MP4:
+		return readAtoms(r, w, opts)
+
+	case fileType == MP3 && format != UnknownFormat:
+		return readID3v2Tags(r, w, opts)
+
+	case fileType == DSF:
+		return readDSFTags(r, w, opts)
 
-	case string(b[0:3]) == "ID3":
-		return ReadID3v2Tags(r)
+	case fileType == AIFF:
+		return readAIFFTags(r, w, opts)
 
-	case string(b[0:4]) == "DSD ":
-		return ReadDSFTags(r)
+	case fileType == DFF:
+		return readDFFTags(r, w, opts)
 	}
 
 	m, err := ReadID3v1Tags(r)
@@ -79,6 +140,15 @@ const (
 	VORBIS        Format = "VORBIS"  // Vorbis Comment tag format.
 )
 
+// String returns the name of the format, or "unknown" for UnknownFormat.  Note that DSF (and
+// AIFF and DSDIFF) files use ID3v2 tags, so Format returns the ID3v2 version for these file types.
+func (f Format) String() string {
+	if f == UnknownFormat {
+		return "unknown"
+	}
+	return string(f)
+}
+
 // FileType is an enumeration of the audio file types supported by this package, in particular
 // there are audio file types which share metadata formats, and this type is used to distinguish
 // between them.
@@ -95,6 +165,8 @@ const (
 	FLAC            FileType = "FLAC" // FLAC file
 	OGG             FileType = "OGG"  // OGG file
 	DSF             FileType = "DSF"  // DSF file DSD Sony format see https://dsd-guide.com/sites/default/files/white-papers/DSFFileFormatSpec_E.pdf
+	AIFF            FileType = "AIFF" // AIFF (or AIFF-C) file
+	DFF             FileType = "DFF"  // DSDIFF file see https://dsd-guide.com/sites/default/files/white-papers/DSDIFF_1.5_Spec.pdf
 )
 
 // Metadata is an interface which is used to describe metadata retrieved by this package.
@@ -108,6 +180,9 @@ type Metadata interface {
 	// Title returns the title of the track.
 	Title() string
 
+	// Subtitle returns the subtitle of the track (i.e. the movement of a classical work).
+	Subtitle() string
+
 	// Album returns the album name of the track.
 	Album() string
 
@@ -132,15 +207,84 @@ type Metadata interface {
 	// Disc returns the disc number and total discs, or zero values if unavailable.
 	Disc() (int, int)
 
-	// Picture returns a picture, or nil if not available.
+	// DiscSubtitle returns the subtitle of the disc (i.e. the title of a disc in a box set).
+	DiscSubtitle() string
+
+	// Picture returns a picture (the front cover if there is one), or nil if not available.
 	Picture() *Picture
 
+	// Pictures returns all the pictures (i.e. the front and back covers), or nil if there are
+	// none.
+	Pictures() []*Picture
+
 	// Lyrics returns the lyrics, or an empty string if unavailable.
 	Lyrics() string
 
 	// Comment returns the comment, or an empty string if unavailable.
 	Comment() string
 
+	// MediaType returns the type of media the audio was sourced from (i.e. "CD", "Vinyl" or
+	// "Digital Media"), or an empty string if unavailable.
+	MediaType() string
+
+	// Mood returns the mood of the track (i.e. "Happy" or "Melancholic"), as used by smart
+	// playlists.
+	Mood() string
+
+	// Rating returns the star rating of the track (1 to 5), or zero if the track is unrated.
+	// Only ID3v2 POPM frames and the MP4 rate item are currently supported (see SetRating).
+	Rating() int
+
+	// ContentGroup returns the grouping of the track (the ID3v2 GRP1 frame written by iTunes
+	// 12.5.2 and later, or TIT1 in older files, and the MP4 ©grp item).
+	ContentGroup() string
+
+	// Work returns the title of the work (i.e. a classical composition) the track is part
+	// of (the ID3v2 TIT1 frame when there is also a GRP1 frame, and the MP4 ©wrk item).
+	Work() string
+
+	// ClassicalInfo returns the work, movement and composer of the track, the boolean is
+	// false if there is no work or movement.
+	ClassicalInfo() (ClassicalInfo, bool)
+
+	// Bitrate returns the bitrate of the audio in bits per second, the boolean is false if it
+	// is unavailable.  It is calculated from the size and duration of the audio for FLAC and
+	// MP4 files with a variable bitrate, taken from the Xing header (or first frame header)
+	// for MP3, the sample format for DSF, DSDIFF and AIFF, and is the nominal bitrate for
+	// Ogg Vorbis.
+	Bitrate() (int, bool)
+
+	// Gapless returns the encoder delay and padding required for gapless playback, the
+	// boolean is false if unavailable.  For Opus the encoder delay is the pre-skip.
+	Gapless() (GaplessInfo, bool)
+
+	// Conductor returns the conductor of the track.
+	Conductor() string
+
+	// Remixer returns the remixer of the track.
+	Remixer() string
+
+	// InvolvedPeople returns the people involved in the recording of the track and their
+	// roles (i.e. engineer or producer), or nil if unavailable.
+	InvolvedPeople() []Credit
+
+	// Copyright returns the copyright message of the track.
+	Copyright() string
+
+	// Publisher returns the publisher (or record label) of the track.
+	Publisher() string
+
+	// Owner returns the name of the owner (or purchaser) of the file.
+	Owner() string
+
+	// PodcastInfo returns the podcast information of the episode, the boolean is false if
+	// the track is not a podcast episode.
+	PodcastInfo() (PodcastInfo, bool)
+
+	// ITunesTags returns the album artist, album artist sort order, compilation flag and
+	// grouping of the track, as used by iTunes to group tracks into albums.
+	ITunesTags() ITunesInfo
+
 	// Raw returns the raw mapping of retrieved tag names and associated values.
 	// NB: tag/atom names are not standardised between formats.
 	Raw() map[string]interface{}
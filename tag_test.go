@@ -5,7 +5,12 @@
 package tag
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -83,6 +88,34 @@ func TestReadFrom(t *testing.T) {
 	}
 }
 
+func TestReadFromAt(t *testing.T) {
+	testdata := map[string]testMetadata{
+		"with_tags/sample.flac":       fullMetadata,
+		"with_tags/sample.id3v24.mp3": fullMetadata,
+		"with_tags/sample.m4a":        fullMetadata,
+		"with_tags/sample.ogg":        fullMetadata,
+	}
+
+	for path, metadata := range testdata {
+		b, err := os.ReadFile("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Offset the data within a larger buffer to check that reads are positioned
+		// relative to the section.
+		buf := append([]byte("junk"), b...)
+		r := io.NewSectionReader(bytes.NewReader(buf), 4, int64(len(b)))
+
+		m, err := ReadFromAt(r, r.Size())
+		if err != nil {
+			t.Errorf("%v: ReadFromAt() = %v", path, err)
+			continue
+		}
+		compareMetadata(t, m, metadata)
+	}
+}
+
 func test(t *testing.T, path string, metadata testMetadata) error {
 	t.Log("testing " + path)
 	f, err := os.Open("testdata/" + path)
@@ -124,3 +157,559 @@ func testValue(t *testing.T, expected interface{}, found interface{}) {
 		t.Errorf("expected '%v', found '%v'", expected, found)
 	}
 }
+
+func TestMediaType(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v22 := &id3v2RawTag{Version: 2, Frames: []id3v2RawFrame{{Name: "TMT", Data: []byte("\x00Vinyl")}}}
+	m4a := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, m4a, mp4FreeformItem("com.apple.iTunes", "MEDIA", "Digital Media"))
+
+	tests := []struct {
+		r        io.ReadSeeker
+		expected string
+	}{
+		{bytes.NewReader(append(testID3v2Tag(id3v2RawFrame{Name: "TMED", Data: []byte("\x00CD")}), audio...)), "CD"},
+		{bytes.NewReader(append(v22.bytes(0), audio...)), "Vinyl"},
+		{m4a, "Digital Media"},
+		{bytes.NewReader(testFLAC(testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{"MEDIA": "CD"})))), "CD"},
+	}
+
+	for ii, tt := range tests {
+		tt.r.Seek(0, io.SeekStart)
+		m, err := ReadFrom(tt.r)
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", ii, err)
+			continue
+		}
+		if got := m.MediaType(); got != tt.expected {
+			t.Errorf("[%d] MediaType() = %q, expected %q", ii, got, tt.expected)
+		}
+	}
+}
+
+func TestMaxPictureBytes(t *testing.T) {
+	// Picture block claiming 500MB of data, followed by a Vorbis comment.
+	picture := testFLACPictureData("image/jpeg", nil)
+	copy(picture[len(picture)-4:], []byte{0x1D, 0xCD, 0x65, 0x00})
+	b := testFLAC(
+		testFLACBlock(pictureBlock, false, picture),
+		testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{"TITLE": "Title"})),
+	)
+
+	m, w, err := ReadFromStrict(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFromStrict() = %v", err)
+	}
+	if m.Picture() != nil {
+		t.Errorf("Picture() = %v, expected nil", m.Picture())
+	}
+	testValue(t, "Title", m.Title())
+	testValue(t, 1, len(w))
+
+	// Lower the limit to skip an ID3v2 picture.
+	defer func(n int64) { MaxPictureBytes = n }(MaxPictureBytes)
+	MaxPictureBytes = 16
+
+	apic := append([]byte("\x00image/jpeg\x00\x03\x00"), make([]byte, 32)...)
+	b = testID3v2Tag(
+		id3v2RawFrame{Name: "APIC", Data: apic},
+		id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")},
+	)
+	m, w, err = ReadFromStrict(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFromStrict() = %v", err)
+	}
+	if m.Picture() != nil {
+		t.Errorf("Picture() = %v, expected nil", m.Picture())
+	}
+	testValue(t, "Title", m.Title())
+	testValue(t, 1, len(w))
+
+	MaxPictureBytes = 0 // no limit
+	m, err = ReadFrom(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if m.Picture() == nil {
+		t.Errorf("Picture() = nil, expected picture with MaxPictureBytes = 0")
+	}
+}
+
+func TestWriteArbitraryFields(t *testing.T) {
+	data := map[string]string{
+		FieldBPM:        "120",
+		FieldInitialKey: "Am",
+		FieldLanguage:   "eng",
+		"MYFIELD":       "custom",
+	}
+
+	// Vorbis comments (and ID3v2 TXXX frames) store keys without a native field as is.
+	flac := tempCopy(t, "without_tags/sample.flac")
+	if err := writeTags(flac, data); err != nil {
+		t.Fatalf("writeTags() = %v", err)
+	}
+	flac.Seek(0, io.SeekStart)
+	m, err := ReadFrom(flac)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	raw := m.Raw()
+	for k, v := range data {
+		testValue(t, v, raw[strings.ToLower(k)])
+	}
+
+	mp3 := tempCopy(t, "without_tags/sample.mp3")
+	if err := writeTags(mp3, data); err != nil {
+		t.Fatalf("writeTags() = %v", err)
+	}
+	mp3.Seek(0, io.SeekStart)
+	m, err = ReadFrom(mp3)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	raw = m.Raw()
+	testValue(t, "120", raw["TBPM"])
+	testValue(t, "Am", raw["TKEY"])
+	testValue(t, "eng", raw["TLAN"])
+}
+
+func TestWriteAlbumArtist(t *testing.T) {
+	data := map[string]string{
+		FieldAlbumArtist:     "Various Artists",
+		FieldAlbumArtistSort: "Various",
+		FieldArtistSort:      "Beatles, The",
+	}
+
+	for _, name := range []string{"sample.flac", "sample.ogg", "sample.m4a", "sample.mp3"} {
+		f := tempCopy(t, "without_tags/"+name)
+		if err := writeTags(f, data); err != nil {
+			t.Errorf("%s: writeTags() = %v", name, err)
+			continue
+		}
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("%s: ReadFrom() = %v", name, err)
+			continue
+		}
+		if got := m.AlbumArtist(); got != "Various Artists" {
+			t.Errorf("%s: AlbumArtist() = %q, expected: %q", name, got, "Various Artists")
+		}
+		if got := m.ITunesTags().AlbumArtistSort; got != "Various" {
+			t.Errorf("%s: AlbumArtistSort = %q, expected: %q", name, got, "Various")
+		}
+	}
+}
+
+func TestLazyPictures(t *testing.T) {
+	data := bytes.Repeat([]byte{0xFF, 0xD8, 0x01}, 100)
+	flac := testFLAC(
+		testFLACBlock(pictureBlock, false, testFLACPictureData("image/jpeg", data)),
+		testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{"TITLE": "Title"})),
+	)
+	m4a := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, m4a, mp4Item("covr", 13, data))
+	mp3 := testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")},
+		id3v2RawFrame{Name: "APIC", Data: append([]byte("\x00image/jpeg\x00\x03Front\x00"), data...)},
+	)
+
+	for _, r := range []io.ReadSeeker{bytes.NewReader(flac), m4a, bytes.NewReader(mp3)} {
+		r.Seek(0, io.SeekStart)
+		m, err := ReadFrom(r)
+		if err != nil {
+			t.Fatalf("ReadFrom() = %v", err)
+		}
+		title, want := m.Title(), m.Picture()
+		if want == nil || !bytes.Equal(want.Data, data) {
+			t.Fatalf("Picture() = %v, expected picture data", want)
+		}
+
+		r.Seek(0, io.SeekStart)
+		m, err = ReadFromWithOptions(r, ReadOptions{LazyPictures: true})
+		if err != nil {
+			t.Fatalf("ReadFromWithOptions() = %v", err)
+		}
+		testValue(t, title, m.Title())
+		p := m.Picture()
+		if p == nil {
+			t.Fatalf("Picture() = nil with LazyPictures")
+		}
+		if p.Data != nil {
+			t.Errorf("Picture().Data = %d bytes, expected nil with LazyPictures", len(p.Data))
+		}
+		testValue(t, want.MIMEType, p.MIMEType)
+
+		got, err := p.Read()
+		if err != nil {
+			t.Fatalf("Picture.Read() = %v", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("Picture.Read() = %x, expected %x", got, data)
+		}
+	}
+
+	// Read returns the data of pictures which were read with the metadata.
+	got, err := (Picture{Data: data}).Read()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Picture.Read() = %v, %v, expected data", got, err)
+	}
+}
+
+func TestWriteCopyrightFields(t *testing.T) {
+	data := map[string]string{
+		FieldTitle:     "Test Title",
+		FieldCopyright: "2000 Test Records",
+		FieldPublisher: "Test Records",
+		FieldOwner:     "Test Owner",
+	}
+
+	writers := map[string]func(io.ReadWriteSeeker, map[string]string) error{
+		"without_tags/sample.mp3":  WriteID3Both,
+		"without_tags/sample.flac": WriteFLACTags,
+	}
+	for path, write := range writers {
+		f := tempCopy(t, path)
+		if err := write(f, data); err != nil {
+			t.Fatalf("%v: write = %v", path, err)
+		}
+
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+		testValue(t, "2000 Test Records", m.Copyright())
+		testValue(t, "Test Records", m.Publisher())
+		testValue(t, "Test Owner", m.Owner())
+	}
+}
+
+func TestReadCopyrightFieldsMP4(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, f,
+		mp4Item("cprt", 1, []byte("2000 Test Records")),
+		mp4FreeformItem("com.apple.iTunes", "LABEL", "Test Records"),
+		mp4Item("ownr", 1, []byte("Test Owner")),
+	)
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "2000 Test Records", m.Copyright())
+	testValue(t, "Test Records", m.Publisher())
+	testValue(t, "Test Owner", m.Owner())
+}
+
+func TestReadCopyrightFieldsVorbis(t *testing.T) {
+	m := readVorbisFields(t, map[string]string{
+		"COPYRIGHT": "2000 Test Records",
+		"LABEL":     "Test Records",
+	})
+	testValue(t, "2000 Test Records", m.Copyright())
+	testValue(t, "Test Records", m.Publisher())
+	testValue(t, "", m.Owner())
+}
+
+func TestReadCopyrightFieldsEmpty(t *testing.T) {
+	f, err := os.Open("testdata/with_tags/sample.id3v24.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "", m.Copyright())
+	testValue(t, "", m.Publisher())
+	testValue(t, "", m.Owner())
+}
+
+func TestMP4Freeform(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, f,
+		mp4FreeformItem("com.example.player", "Play Position", "12345"),
+		mp4FreeformItem("com.apple.iTunes", "MEDIA", "Digital Media"),
+	)
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "Digital Media", m.MediaType()) // atoms after an unknown freeform atom are read
+
+	ff, ok := m.(FreeformMetadata)
+	if !ok {
+		t.Fatalf("%T does not implement FreeformMetadata", m)
+	}
+	want := map[string]string{
+		"com.example.player:Play Position": "12345",
+		"com.apple.iTunes:MEDIA":           "Digital Media",
+	}
+	for k, v := range want {
+		if got := ff.Freeform()[k]; got != v {
+			t.Errorf("Freeform()[%q] = %q, expected %q", k, got, v)
+		}
+	}
+}
+
+func TestMP4FreeformLocale(t *testing.T) {
+	// The data atom of a "----" item has 4 bytes of class and 4 bytes of locale before the
+	// value, which were once read as part of the value.
+	f := tempCopy(t, "with_tags/sample.mp4")
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	testValue(t, "album;compilation", m.Raw()["MusicBrainz Album Type"])
+	testValue(t, "album;compilation", m.(FreeformMetadata).Freeform()["com.apple.iTunes:MusicBrainz Album Type"])
+
+	// A data atom too short for the class and locale is an error.
+	f = tempCopy(t, "without_tags/sample.m4a")
+	addTestMP4Items(t, f, &mp4Atom{
+		Name:      "----",
+		container: true,
+		Children: []*mp4Atom{
+			{Name: "mean", Data: []byte("\x00\x00\x00\x00com.apple.iTunes")},
+			{Name: "name", Data: []byte("\x00\x00\x00\x00MEDIA")},
+			{Name: "data", Data: []byte{0, 0, 0, mp4ClassText}},
+		},
+	})
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFrom(f); err == nil {
+		t.Errorf("ReadFrom() = nil, expected error for truncated data atom")
+	}
+}
+
+func TestFormatString(t *testing.T) {
+	tests := map[Format]string{
+		UnknownFormat: "unknown",
+		ID3v1:         "ID3v1",
+		ID3v2_2:       "ID3v2.2",
+		ID3v2_3:       "ID3v2.3",
+		ID3v2_4:       "ID3v2.4",
+		MP4:           "MP4",
+		VORBIS:        "VORBIS",
+	}
+	for f, want := range tests {
+		if got := f.String(); got != want {
+			t.Errorf("%q.String() = %q, expected %q", string(f), got, want)
+		}
+		if got := fmt.Sprint(f); got != want {
+			t.Errorf("fmt.Sprint(%q) = %q, expected %q", string(f), got, want)
+		}
+	}
+}
+
+func TestFormatConstants(t *testing.T) {
+	tests := []struct {
+		path   string
+		format Format
+	}{
+		{"with_tags/sample.flac", VORBIS},
+		{"with_tags/sample.id3v24.mp3", ID3v2_4},
+		{"with_tags/sample.m4a", MP4},
+		{"with_tags/sample.dsf", ID3v2_4},
+		{"with_tags/sample.id3v11.mp3", ID3v1},
+	}
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", tt.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := ReadFrom(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", tt.path, err)
+		}
+		if m.Format() != tt.format {
+			t.Errorf("%v: Format() = %v, expected %v", tt.path, m.Format(), tt.format)
+		}
+	}
+}
+
+func TestSubtitles(t *testing.T) {
+	id3v24 := &id3v2RawTag{Version: 4, Frames: []id3v2RawFrame{
+		{Name: "TIT3", Data: []byte("\x03Allegro con brio")},
+		{Name: "TSST", Data: []byte("\x03The Early Symphonies")},
+	}}
+	id3v22 := &id3v2RawTag{Version: 2, Frames: []id3v2RawFrame{{Name: "TT3", Data: []byte("\x00Allegro con brio")}}}
+	m4a := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, m4a,
+		mp4FreeformItem("com.apple.iTunes", "SUBTITLE", "Allegro con brio"),
+		mp4FreeformItem("com.apple.iTunes", "DISCSUBTITLE", "The Early Symphonies"),
+	)
+	flac := testFLAC(testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{
+		"SUBTITLE":     "Allegro con brio",
+		"DISCSUBTITLE": "The Early Symphonies",
+	})))
+
+	tests := []struct {
+		r            io.ReadSeeker
+		subtitle     string
+		discSubtitle string
+	}{
+		{bytes.NewReader(id3v24.bytes(0)), "Allegro con brio", "The Early Symphonies"},
+		{bytes.NewReader(id3v22.bytes(0)), "Allegro con brio", ""},
+		{m4a, "Allegro con brio", "The Early Symphonies"},
+		{bytes.NewReader(flac), "Allegro con brio", "The Early Symphonies"},
+	}
+
+	for ii, tt := range tests {
+		tt.r.Seek(0, io.SeekStart)
+		m, err := ReadFrom(tt.r)
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", ii, err)
+			continue
+		}
+		if got := m.Subtitle(); got != tt.subtitle {
+			t.Errorf("[%d] Subtitle() = %q, expected %q", ii, got, tt.subtitle)
+		}
+		if got := m.DiscSubtitle(); got != tt.discSubtitle {
+			t.Errorf("[%d] DiscSubtitle() = %q, expected %q", ii, got, tt.discSubtitle)
+		}
+	}
+}
+
+func TestMood(t *testing.T) {
+	id3v24 := &id3v2RawTag{Version: 4, Frames: []id3v2RawFrame{{Name: "TMOO", Data: []byte("\x03Melancholic")}}}
+	id3v23 := &id3v2RawTag{Version: 3, Frames: []id3v2RawFrame{{Name: "TMOO", Data: []byte("\x00Melancholic")}}}
+	m4a := tempCopy(t, "with_tags/sample.m4a")
+	addTestMP4Items(t, m4a, mp4FreeformItem("com.apple.iTunes", "MOOD", "Melancholic"))
+	flac := testFLAC(testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{"MOOD": "Melancholic"})))
+
+	tests := []io.ReadSeeker{
+		bytes.NewReader(id3v24.bytes(0)),
+		bytes.NewReader(id3v23.bytes(0)),
+		m4a,
+		bytes.NewReader(flac),
+	}
+	for ii, r := range tests {
+		r.Seek(0, io.SeekStart)
+		m, err := ReadFrom(r)
+		if err != nil {
+			t.Errorf("[%d] ReadFrom() = %v", ii, err)
+			continue
+		}
+		if got := m.Mood(); got != "Melancholic" {
+			t.Errorf("[%d] Mood() = %q, expected %q", ii, got, "Melancholic")
+		}
+	}
+
+	// Written as TMOO in ID3v2.4 tags, and as MOOD in Vorbis comments.
+	mp3 := tempCopy(t, "without_tags/sample.mp3")
+	flacFile := tempCopy(t, "without_tags/sample.flac")
+	for _, f := range []*os.File{mp3, flacFile} {
+		if err := writeTags(f, map[string]string{FieldMood: "Happy"}); err != nil {
+			t.Fatalf("writeTags() = %v", err)
+		}
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("ReadFrom() = %v", err)
+		}
+		testValue(t, "Happy", m.Mood())
+	}
+}
+
+func TestPictures(t *testing.T) {
+	apic := func(pictureType byte, desc string, data []byte) id3v2RawFrame {
+		b := append([]byte("\x00image/jpeg\x00"), pictureType)
+		b = append(b, desc+"\x00"...)
+		return id3v2RawFrame{Name: "APIC", Data: append(b, data...)}
+	}
+	flacPicture := func(pictureType byte, data []byte) []byte {
+		b := testFLACPictureData("image/jpeg", data)
+		b[3] = pictureType
+		return b
+	}
+	front, back := []byte{1, 2, 3}, []byte{4, 5, 6, 7}
+
+	tests := []io.ReadSeeker{
+		bytes.NewReader(testID3v2Tag(apic(4, "Back", back), apic(3, "Front", front))),
+		bytes.NewReader(testFLAC(
+			testFLACBlock(pictureBlock, false, flacPicture(4, back)),
+			testFLACBlock(pictureBlock, true, flacPicture(3, front)),
+		)),
+	}
+	for ii, r := range tests {
+		m, err := ReadFrom(r)
+		if err != nil {
+			t.Fatalf("[%d] ReadFrom() = %v", ii, err)
+		}
+
+		pics := m.Pictures()
+		if len(pics) != 2 {
+			t.Fatalf("[%d] Pictures() = %v, expected 2 pictures", ii, pics)
+		}
+		testValue(t, "Cover (back)", pics[0].Type)
+		testValue(t, "Cover (front)", pics[1].Type)
+		if !bytes.Equal(pics[0].Data, back) || !bytes.Equal(pics[1].Data, front) {
+			t.Errorf("[%d] Pictures() = %v, expected back then front cover data", ii, pics)
+		}
+		if m.Format() != VORBIS {
+			testValue(t, "Back", pics[0].Description)
+			testValue(t, "Front", pics[1].Description)
+		}
+
+		// Picture prefers the front cover.
+		if p := m.Picture(); p == nil || p.Type != "Cover (front)" {
+			t.Errorf("[%d] Picture() = %v, expected front cover", ii, p)
+		}
+	}
+
+	// Without a front cover Picture returns the first picture.
+	m, err := ReadFrom(bytes.NewReader(testID3v2Tag(apic(4, "Back", back), apic(5, "Leaflet", front))))
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if p := m.Picture(); p == nil || p.Type != "Cover (back)" {
+		t.Errorf("Picture() = %v, expected back cover", p)
+	}
+}
+
+func TestExtractPictures(t *testing.T) {
+	front := &Picture{MIMEType: "image/jpeg", Type: pictureFrontCover, Description: "Front", Data: []byte("\xff\xd8\xff\xe0 JFIF data")}
+	back := &Picture{MIMEType: "image/png", Type: "Cover (back)", Description: "Back", Data: append(append([]byte{}, pngHeader...), "data"...)}
+	var blocks [][]byte
+	for i, p := range []*Picture{front, back} {
+		b, err := buildFLACPictureBlock(p)
+		if err != nil {
+			t.Fatalf("buildFLACPictureBlock() = %v", err)
+		}
+		blocks = append(blocks, testFLACBlock(pictureBlock, i == 1, b))
+	}
+	flac := testFLAC(blocks...)
+
+	pics, err := ExtractPictures(bytes.NewReader(flac))
+	if err != nil {
+		t.Fatalf("ExtractPictures() = %v", err)
+	}
+	if len(pics) != 2 {
+		t.Fatalf("ExtractPictures() returned %d pictures, expected 2", len(pics))
+	}
+	for i, want := range []*Picture{front, back} {
+		testValue(t, want.Type, pics[i].Type)
+		testValue(t, want.Description, pics[i].Description)
+		testValue(t, want.MIMEType, pics[i].MIMEType)
+		if !bytes.Equal(pics[i].Data, want.Data) {
+			t.Errorf("[%d] Data = %x, expected %x", i, pics[i].Data, want.Data)
+		}
+	}
+}
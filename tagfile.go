@@ -0,0 +1,47 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"io"
+	"os"
+)
+
+// TagFile is a file opened for reading and writing metadata (see OpenForTagging).
+type TagFile struct {
+	f *os.File
+}
+
+// OpenForTagging opens the file at path for reading and writing.  Reading and then writing
+// through the returned TagFile uses a single file descriptor, so the file can't be replaced
+// between the two (as when the file is opened twice).  The caller must call Close.
+func OpenForTagging(path string) (*TagFile, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &TagFile{f: f}, nil
+}
+
+// Read reads the metadata from the file (see ReadFrom).
+func (t *TagFile) Read() (Metadata, error) {
+	if _, err := t.f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ReadFrom(t.f)
+}
+
+// Write merges the fields in data into the metadata of the file: each field in data replaces
+// the existing value, and fields which are not in data are kept (as by UpdateFLACTags, whatever
+// the file type).  MP3 files are written with an ID3v2.4 tag and a matching ID3v1.1 tag (see
+// WriteID3Both).  Returns ErrUnsupportedFormat if the file type can't be written.
+func (t *TagFile) Write(data map[string]string) error {
+	return writeTags(t.f, data)
+}
+
+// Close closes the file.
+func (t *TagFile) Close() error {
+	return t.f.Close()
+}
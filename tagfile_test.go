@@ -0,0 +1,121 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"testing"
+)
+
+func TestTagFile(t *testing.T) {
+	paths := []string{
+		"with_tags/sample.id3v24.mp3",
+		"without_tags/sample.mp3",
+		"with_tags/sample.flac",
+		"with_tags/sample.ogg",
+		"with_tags/sample.m4a",
+	}
+
+	for _, path := range paths {
+		f := tempCopy(t, path)
+		f.Close()
+
+		tf, err := OpenForTagging(f.Name())
+		if err != nil {
+			t.Fatalf("%v: OpenForTagging() = %v", path, err)
+		}
+
+		m, err := tf.Read()
+		if err != nil && err != ErrNoTagsFound {
+			t.Fatalf("%v: Read() = %v", path, err)
+		}
+		data := map[string]string{FieldTitle: "New Title"}
+		if m != nil {
+			data[FieldArtist] = m.Artist()
+		}
+		if err := tf.Write(data); err != nil {
+			t.Fatalf("%v: Write() = %v", path, err)
+		}
+
+		got, err := tf.Read()
+		if err != nil {
+			t.Fatalf("%v: Read() after Write() = %v", path, err)
+		}
+		testValue(t, "New Title", got.Title())
+		testValue(t, data[FieldArtist], got.Artist())
+
+		if err := tf.Close(); err != nil {
+			t.Errorf("%v: Close() = %v", path, err)
+		}
+	}
+}
+
+func TestTagFileWriteKeepsFields(t *testing.T) {
+	paths := []string{
+		"with_tags/sample.flac",
+		"with_tags/sample.ogg",
+		"with_tags/sample.m4a",
+		"with_tags/sample.id3v24.mp3",
+	}
+
+	for _, path := range paths {
+		f := tempCopy(t, path)
+		f.Close()
+
+		tf, err := OpenForTagging(f.Name())
+		if err != nil {
+			t.Fatalf("%v: OpenForTagging() = %v", path, err)
+		}
+		orig, err := tf.Read()
+		if err != nil {
+			t.Fatalf("%v: Read() = %v", path, err)
+		}
+		if orig.Artist() == "" || orig.Album() == "" || orig.Genre() == "" {
+			t.Fatalf("%v: expected sample to have artist, album and genre", path)
+		}
+
+		if err := tf.Write(map[string]string{FieldTitle: "New"}); err != nil {
+			t.Fatalf("%v: Write() = %v", path, err)
+		}
+		m, err := tf.Read()
+		if err != nil {
+			t.Fatalf("%v: Read() after Write() = %v", path, err)
+		}
+		testValue(t, "New", m.Title())
+		testValue(t, orig.Artist(), m.Artist())
+		testValue(t, orig.Album(), m.Album())
+		testValue(t, orig.Genre(), m.Genre())
+		testValue(t, orig.Year(), m.Year())
+
+		if orig.FileType() == MP3 {
+			// The ID3v1 tag is rewritten from the merged ID3v2 tag.
+			v1, err := ReadID3v1Tags(tf.f)
+			if err != nil {
+				t.Fatalf("%v: ReadID3v1Tags() = %v", path, err)
+			}
+			testValue(t, "New", v1.Title())
+			testValue(t, orig.Artist(), v1.Artist())
+			testValue(t, orig.Album(), v1.Album())
+		}
+
+		if err := tf.Close(); err != nil {
+			t.Errorf("%v: Close() = %v", path, err)
+		}
+	}
+}
+
+func TestTagFileUnsupported(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.dsf")
+	f.Close()
+
+	tf, err := OpenForTagging(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.Close()
+
+	if err := tf.Write(map[string]string{FieldTitle: "x"}); err != ErrUnsupportedFormat {
+		t.Errorf("Write() = %v, expected %v", err, ErrUnsupportedFormat)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// apeFooterSize is the size of an APEv2 tag header or footer.
+const apeFooterSize = 32
+
+// TagSize returns the number of bytes in r which are occupied by metadata rather than audio:
+// the ID3v2 tag (including padding) and any trailing APEv2 and ID3v1 tags of MP3 data, all the
+// FLAC metadata blocks (including the STREAMINFO and PADDING blocks), or the moov.udta.meta atom
+// of MP4 data.  Returns ErrUnsupportedFormat for other file types.
+func TagSize(r io.ReadSeeker) (int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	format, fileType, err := sniffFormat(r)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case fileType == FLAC:
+		if _, err := r.Seek(4, io.SeekStart); err != nil {
+			return 0, err
+		}
+		return flacMetadataSize(r)
+
+	case format == MP4:
+		moov, _, _, err := readMP4Moov(r)
+		if err != nil {
+			return 0, err
+		}
+		if meta := moov.find("udta", "meta"); meta != nil {
+			return meta.size(), nil
+		}
+		return 0, nil
+
+	case fileType == MP3:
+		n, err := id3v2TagSize(r)
+		if err != nil {
+			return 0, err
+		}
+		t, err := trailerTagSize(r)
+		return n + t, err
+	}
+	return 0, ErrUnsupportedFormat
+}
+
+// RawTagBytes returns the metadata at the start of the data in r, up to the start of the audio
+// data: the ID3v2 tag of MP3 data, the "fLaC" marker and metadata blocks of FLAC data, or the
+// header pages of Ogg Vorbis and Opus data.  Returns ErrUnsupportedFormat for other file types.
+func RawTagBytes(r io.ReadSeeker) ([]byte, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	_, fileType, err := sniffFormat(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	switch fileType {
+	case FLAC:
+		if _, err := r.Seek(4, io.SeekStart); err != nil {
+			return nil, err
+		}
+		n, err = flacMetadataSize(r)
+		n += 4
+
+	case OGG:
+		n, err = oggHeaderSize(r)
+
+	case MP3:
+		n, err = id3v2TagSize(r)
+
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return readBytes(r, uint(n))
+}
+
+// flacMetadataSize returns the total size of the metadata blocks (including their headers) of
+// the FLAC data in r, which must be positioned after the "fLaC" marker.
+func flacMetadataSize(r io.ReadSeeker) (int64, error) {
+	var n int64
+	for {
+		_, last, blockLen, err := readFLACBlockHeader(r)
+		if err != nil {
+			return 0, err
+		}
+		n += 4 + int64(blockLen)
+
+		if last {
+			return n, nil
+		}
+		if _, err := r.Seek(int64(blockLen), io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// trailerTagSize returns the total size of the ID3v1 tag and the APEv2 tag (which precedes
+// the ID3v1 tag if both are present) at the end of r.
+func trailerTagSize(r io.ReadSeeker) (int64, error) {
+	var n int64
+	ok, err := hasID3v1Tag(r)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		n = id3v1Size
+	}
+
+	end, err := r.Seek(-n, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if end < apeFooterSize {
+		return n, nil
+	}
+	if _, err := r.Seek(-apeFooterSize, io.SeekCurrent); err != nil {
+		return 0, err
+	}
+	b, err := readBytes(r, apeFooterSize)
+	if err != nil {
+		return 0, err
+	}
+	if string(b[0:8]) != "APETAGEX" {
+		return n, nil
+	}
+
+	// The tag size includes the items and footer, but not the optional header.
+	size := int64(binary.LittleEndian.Uint32(b[12:16]))
+	if flags := binary.LittleEndian.Uint32(b[20:24]); flags&(1<<31) != 0 {
+		size += apeFooterSize
+	}
+	if size > end {
+		size = end
+	}
+	return n + size, nil
+}
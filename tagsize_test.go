@@ -0,0 +1,156 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+// testAPETag returns an APEv2 tag (with header and footer) containing a single text item.
+func testAPETag(key, value string) []byte {
+	item := make([]byte, 8, 8+len(key)+1+len(value))
+	binary.LittleEndian.PutUint32(item[0:4], uint32(len(value)))
+	item = append(append(append(item, key...), 0), value...)
+
+	headerFooter := func(flags uint32) []byte {
+		b := make([]byte, apeFooterSize)
+		copy(b, "APETAGEX")
+		binary.LittleEndian.PutUint32(b[8:12], 2000)
+		binary.LittleEndian.PutUint32(b[12:16], uint32(len(item)+apeFooterSize))
+		binary.LittleEndian.PutUint32(b[16:20], 1)
+		binary.LittleEndian.PutUint32(b[20:24], flags)
+		return b
+	}
+
+	b := headerFooter(1<<31 | 1<<29) // has header, is header
+	b = append(b, item...)
+	return append(b, headerFooter(1<<31)...)
+}
+
+func TestTagSizeMP3(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	id3 := testID3v2Tag(id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")})
+	ape := testAPETag("Title", "Title")
+	id3v1 := id3v1Tag(map[string]string{FieldTitle: "Title"})
+
+	tests := []struct {
+		name string
+		b    []byte
+		want int
+	}{
+		{"untagged", audio, 0},
+		{"ID3v2", append(append([]byte{}, id3...), audio...), len(id3)},
+		{"ID3v2+APE+ID3v1", bytes.Join([][]byte{id3, audio, ape, id3v1}, nil), len(id3) + len(ape) + len(id3v1)},
+		{"APE", bytes.Join([][]byte{audio, ape}, nil), len(ape)},
+	}
+	for _, tt := range tests {
+		n, err := TagSize(bytes.NewReader(tt.b))
+		if err != nil {
+			t.Errorf("%v: TagSize() = %v", tt.name, err)
+			continue
+		}
+		if n != int64(tt.want) {
+			t.Errorf("%v: TagSize() = %d, expected %d", tt.name, n, tt.want)
+		}
+	}
+}
+
+func TestTagSizeFLAC(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	_, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := TagSize(f)
+	if err != nil {
+		t.Fatalf("TagSize() = %v", err)
+	}
+	testValue(t, audioOffset-4, n) // everything but the "fLaC" marker and the audio
+}
+
+func TestTagSizeMP4(t *testing.T) {
+	f := tempCopy(t, "without_tags/sample.m4a")
+	before, err := TagSize(f)
+	if err != nil {
+		t.Fatalf("TagSize() = %v", err)
+	}
+
+	item := mp4Item("\xa9nam", 1, []byte("Title"))
+	addTestMP4Items(t, f, item)
+	after, err := TagSize(f)
+	if err != nil {
+		t.Fatalf("TagSize() = %v", err)
+	}
+	testValue(t, item.size(), after-before)
+}
+
+func TestTagSizeUnsupported(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.ogg")
+	if _, err := TagSize(f); err != ErrUnsupportedFormat {
+		t.Errorf("TagSize() = %v, expected %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestRawTagBytesNoOpEdit(t *testing.T) {
+	data := map[string]string{FieldTitle: "Title", FieldArtist: "Artist"}
+	tests := []struct {
+		path   string
+		prefix string
+		write  func(f *os.File) error
+	}{
+		{"with_tags/sample.flac", "fLaC", func(f *os.File) error { return UpdateFLACTags(f, nil) }},
+		{"with_tags/sample.id3v23.mp3", "ID3", func(f *os.File) error { return WriteID3Both(f, data) }},
+		{"with_tags/sample.ogg", "OggS", func(f *os.File) error { return WriteOGGTags(f, data) }},
+	}
+
+	for _, tt := range tests {
+		f := tempCopy(t, tt.path)
+		// The first write normalises the tags (i.e. field order and padding).
+		if err := tt.write(f); err != nil {
+			t.Fatalf("%v: write = %v", tt.path, err)
+		}
+		before, err := RawTagBytes(f)
+		if err != nil {
+			t.Fatalf("%v: RawTagBytes() = %v", tt.path, err)
+		}
+		if !bytes.HasPrefix(before, []byte(tt.prefix)) {
+			t.Errorf("%v: RawTagBytes() does not start with %q", tt.path, tt.prefix)
+		}
+
+		if err := tt.write(f); err != nil {
+			t.Fatalf("%v: write = %v", tt.path, err)
+		}
+		after, err := RawTagBytes(f)
+		if err != nil {
+			t.Fatalf("%v: RawTagBytes() = %v", tt.path, err)
+		}
+		if !bytes.Equal(before, after) {
+			t.Errorf("%v: tag bytes changed by a no-op edit", tt.path)
+		}
+
+		// The audio data follows immediately.
+		b := readAll(t, f)
+		if len(after) >= len(b) {
+			t.Errorf("%v: RawTagBytes() returned %d of %d bytes", tt.path, len(after), len(b))
+		}
+	}
+
+	f := tempCopy(t, "with_tags/sample.flac")
+	_, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := RawTagBytes(f)
+	if err != nil {
+		t.Fatalf("RawTagBytes() = %v", err)
+	}
+	testValue(t, audioOffset, int64(len(b)))
+}
@@ -24,6 +24,61 @@ func get7BitChunkedInt(b []byte) int {
 	return n
 }
 
+// format7BitChunkedUint returns n encoded as a size byte big-endian integer using the
+// lower 7 bits of each byte (i.e. an ID3v2 synch-safe integer).
+func format7BitChunkedUint(n uint, size int) []byte {
+	b := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		b[i] = byte(n & 0x7F)
+		n >>= 7
+	}
+	return b
+}
+
+// formatUintBigEndian returns the lower size*8 bits of n encoded as a size byte big-endian
+// integer, for widths which are not supported by encoding/binary (i.e. the 3 byte lengths
+// of FLAC metadata blocks and ID3v2.2 frames).
+func formatUintBigEndian(n uint, size int) []byte {
+	b := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		b[i] = byte(n)
+		n >>= 8
+	}
+	return b
+}
+
+// formatUintLittleEndian returns the lower size*8 bits of n encoded as a size byte
+// little-endian integer.
+func formatUintLittleEndian(n uint, size int) []byte {
+	b := make([]byte, size)
+	for i := 0; i < size; i++ {
+		b[i] = byte(n)
+		n >>= 8
+	}
+	return b
+}
+
+// SynchSafeEncode returns n encoded as an ID3v2 synch-safe integer (a 4 byte big-endian
+// integer using the lower 7 bits of each byte).  Only the lower 28 bits of n are encoded.
+func SynchSafeEncode(n uint32) [4]byte {
+	var b [4]byte
+	copy(b[:], format7BitChunkedUint(uint(n&0x0FFFFFFF), 4))
+	return b
+}
+
+// SynchSafeDecode returns the value of the ID3v2 synch-safe integer in the first 4 bytes of b
+// (or all of b if it is shorter).  The top bit of each byte is ignored.
+func SynchSafeDecode(b []byte) uint32 {
+	if len(b) > 4 {
+		b = b[:4]
+	}
+	var n uint32
+	for _, x := range b {
+		n = n<<7 | uint32(x&0x7F)
+	}
+	return n
+}
+
 func getInt(b []byte) int {
 	var n int
 	for _, x := range b {
@@ -100,3 +155,11 @@ func readUint32LittleEndian(r io.Reader) (uint32, error) {
 	}
 	return binary.LittleEndian.Uint32(b), nil
 }
+
+// minInt returns the smaller of a and b.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
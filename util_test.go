@@ -46,6 +46,71 @@ func TestGet7BitChunkedInt(t *testing.T) {
 	}
 }
 
+func TestSynchSafe(t *testing.T) {
+	tests := []struct {
+		n uint32
+		b [4]byte
+	}{
+		{0, [4]byte{0, 0, 0, 0}},
+		{0x7F, [4]byte{0, 0, 0, 0x7F}},
+		{0x80, [4]byte{0, 0, 0x01, 0x00}},
+		{0x3FFF, [4]byte{0, 0, 0x7F, 0x7F}},
+		{257, [4]byte{0, 0, 0x02, 0x01}},
+		{0x0FFFFFFF, [4]byte{0x7F, 0x7F, 0x7F, 0x7F}},
+	}
+
+	for ii, tt := range tests {
+		if got := SynchSafeEncode(tt.n); got != tt.b {
+			t.Errorf("[%d] SynchSafeEncode(%#x) = %v, expected %v", ii, tt.n, got, tt.b)
+		}
+		if got := SynchSafeDecode(tt.b[:]); got != tt.n {
+			t.Errorf("[%d] SynchSafeDecode(%v) = %#x, expected %#x", ii, tt.b, got, tt.n)
+		}
+	}
+
+	// Values are masked to 28 bits when encoding, and the top bit of each byte is ignored
+	// when decoding.
+	if got := SynchSafeEncode(0xFFFFFFFF); got != [4]byte{0x7F, 0x7F, 0x7F, 0x7F} {
+		t.Errorf("SynchSafeEncode(0xFFFFFFFF) = %v, expected 0x0FFFFFFF encoding", got)
+	}
+	if got := SynchSafeDecode([]byte{0xFF, 0xFF, 0xFF, 0xFF}); got != 0x0FFFFFFF {
+		t.Errorf("SynchSafeDecode(FF FF FF FF) = %#x, expected 0x0FFFFFFF", got)
+	}
+	if got := SynchSafeDecode([]byte{0, 0, 0x01, 0x00, 0x7F}); got != 0x80 {
+		t.Errorf("SynchSafeDecode() = %#x, expected only the first 4 bytes to be used", got)
+	}
+}
+
+func TestFormatUint(t *testing.T) {
+	const n = 0x0102030405060708
+	tests := []struct {
+		size   int
+		be, le []byte
+	}{
+		{1, []byte{8}, []byte{8}},
+		{2, []byte{7, 8}, []byte{8, 7}},
+		{3, []byte{6, 7, 8}, []byte{8, 7, 6}},
+		{4, []byte{5, 6, 7, 8}, []byte{8, 7, 6, 5}},
+		{5, []byte{4, 5, 6, 7, 8}, []byte{8, 7, 6, 5, 4}},
+		{6, []byte{3, 4, 5, 6, 7, 8}, []byte{8, 7, 6, 5, 4, 3}},
+		{7, []byte{2, 3, 4, 5, 6, 7, 8}, []byte{8, 7, 6, 5, 4, 3, 2}},
+		{8, []byte{1, 2, 3, 4, 5, 6, 7, 8}, []byte{8, 7, 6, 5, 4, 3, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		if got := formatUintBigEndian(n, tt.size); !bytes.Equal(got, tt.be) {
+			t.Errorf("formatUintBigEndian(%#x, %d) = %v, expected %v", uint64(n), tt.size, got, tt.be)
+		}
+		if got := formatUintLittleEndian(n, tt.size); !bytes.Equal(got, tt.le) {
+			t.Errorf("formatUintLittleEndian(%#x, %d) = %v, expected %v", uint64(n), tt.size, got, tt.le)
+		}
+		// The encoding is read back by getInt.
+		if want := n & (1<<(8*tt.size) - 1); tt.size < 8 && getInt(tt.be) != want {
+			t.Errorf("getInt(%v) = %#x, expected %#x", tt.be, getInt(tt.be), want)
+		}
+	}
+}
+
 func TestGetInt(t *testing.T) {
 	tests := []struct {
 		input  []byte
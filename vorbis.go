@@ -12,18 +12,19 @@ import (
 	"io"
 	"strconv"
 	"strings"
-	"time"
 )
 
 func newMetadataVorbis() *metadataVorbis {
 	return &metadataVorbis{
-		c: make(map[string]string),
+		c:      make(map[string]string),
+		values: make(map[string][]string),
 	}
 }
 
 type metadataVorbis struct {
-	c map[string]string // the vorbis comments
-	p *Picture
+	c      map[string]string   // the vorbis comments
+	values map[string][]string // all the values of each comment, by upper case field name
+	pics   []*Picture
 }
 
 func (m *metadataVorbis) readVorbisComment(r io.Reader) error {
@@ -57,20 +58,40 @@ func (m *metadataVorbis) readVorbisComment(r io.Reader) error {
 			return err
 		}
 		m.c[strings.ToLower(k)] = v
+		m.values[strings.ToUpper(k)] = append(m.values[strings.ToUpper(k)], v)
 	}
 
-	if b64data, ok := m.c["metadata_block_picture"]; ok {
+	if pics := m.values["METADATA_BLOCK_PICTURE"]; len(pics) > 0 {
+		// There can be a field for each picture (i.e. the front and back covers).
+		for _, b64data := range pics {
+			data, err := base64.StdEncoding.DecodeString(b64data)
+			if err != nil {
+				return err
+			}
+			m.readPictureBlock(bytes.NewReader(data), false)
+		}
+	} else if b64data, ok := m.c["coverart"]; ok {
+		// Deprecated: the base64 encoded image data, with the MIME type in COVERARTMIME.
 		data, err := base64.StdEncoding.DecodeString(b64data)
 		if err != nil {
 			return err
 		}
-		m.readPictureBlock(bytes.NewReader(data))
+		mime := m.c["coverartmime"]
+		if mime == "" {
+			mime = sniffImageMIME(data)
+		}
+		p := &Picture{MIMEType: mime, Type: pictureFrontCover, Data: data}
+		p.Ext = strings.TrimPrefix(p.Extension(), ".")
+		p.Width, p.Height = imageSize(data)
+		m.pics = append(m.pics, p)
 	}
 
 	return nil
 }
 
-func (m *metadataVorbis) readPictureBlock(r io.Reader) error {
+// readPictureBlock reads a FLAC PICTURE block from r.  If lazy is true and r is an
+// io.ReadSeeker then the picture data is not read (see ReadOptions).
+func (m *metadataVorbis) readPictureBlock(r io.Reader, lazy bool) error {
 	b, err := readInt(r, 4)
 	if err != nil {
 		return err
@@ -107,12 +128,12 @@ func (m *metadataVorbis) readPictureBlock(r io.Reader) error {
 		return err
 	}
 
-	// We skip width <32>, height <32>, colorDepth <32>, coloresUsed <32>
-	_, err = readInt(r, 4) // width
+	// We skip colorDepth <32>, coloresUsed <32>
+	width, err := readInt(r, 4)
 	if err != nil {
 		return err
 	}
-	_, err = readInt(r, 4) // height
+	height, err := readInt(r, 4)
 	if err != nil {
 		return err
 	}
@@ -129,22 +150,80 @@ func (m *metadataVorbis) readPictureBlock(r io.Reader) error {
 	if err != nil {
 		return err
 	}
-	data := make([]byte, dataLen)
-	_, err = io.ReadFull(r, data)
-	if err != nil {
-		return err
+	if pictureTooLarge(int64(dataLen)) {
+		return errPictureTooLarge // the caller skips the rest of the block
 	}
 
-	m.p = &Picture{
+	p := &Picture{
 		Ext:         ext,
 		MIMEType:    mime,
 		Type:        pictureType,
 		Description: desc,
-		Data:        data,
+		Width:       width,
+		Height:      height,
+	}
+
+	if rs, ok := r.(io.ReadSeeker); ok && lazy {
+		offset, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+		p.r, p.offset, p.size = readerAt{rs}, offset, int64(dataLen)
+		m.pics = append(m.pics, p)
+		_, err = rs.Seek(int64(dataLen), io.SeekCurrent)
+		return err
 	}
+
+	p.Data = make([]byte, dataLen)
+	if _, err := io.ReadFull(r, p.Data); err != nil {
+		return err
+	}
+
+	// Encoders don't always set the dimensions.
+	if width == 0 || height == 0 {
+		p.Width, p.Height = imageSize(p.Data)
+	}
+	m.pics = append(m.pics, p)
 	return nil
 }
 
+// merge adds the fields (and pictures) of d which are not already in m.
+func (m *metadataVorbis) merge(d *metadataVorbis) {
+	for k, v := range d.c {
+		if _, ok := m.c[k]; !ok {
+			m.c[k] = v
+		}
+	}
+	for k, v := range d.values {
+		if _, ok := m.values[k]; !ok {
+			m.values[k] = v
+		}
+	}
+	if len(m.pics) == 0 {
+		m.pics = d.pics
+	}
+}
+
+// VorbisCommentsFrom returns all the values of each Vorbis comment field in m (by upper case
+// field name, with the values in the order they were read), or nil if m was not read from
+// FLAC or Ogg data.  The vendor string is not included.  Pictures stored in FLAC PICTURE
+// blocks are not comments so are not included either.  See WriteVorbisComments.
+func VorbisCommentsFrom(m Metadata) map[string][]string {
+	v, ok := m.(interface{ vorbisComments() map[string][]string })
+	if !ok {
+		return nil
+	}
+	return v.vorbisComments()
+}
+
+func (m *metadataVorbis) vorbisComments() map[string][]string {
+	c := make(map[string][]string, len(m.values))
+	for k, v := range m.values {
+		c[k] = append([]string(nil), v...)
+	}
+	return c
+}
+
 func parseComment(c string) (k, v string, err error) {
 	kv := strings.SplitN(c, "=", 2)
 	if len(kv) != 2 {
@@ -210,44 +289,40 @@ func (m *metadataVorbis) Genre() string {
 }
 
 func (m *metadataVorbis) Year() int {
-	var dateFormat string
-
 	// The date need to follow the international standard https://en.wikipedia.org/wiki/ISO_8601
 	// and obviously the VorbisComment standard https://wiki.xiph.org/VorbisComment#Date_and_time
-	switch len(m.c["date"]) {
-	case 0:
-		// Fallback on year tag as some files use that.
-		if len(m.c["year"]) != 0 {
-			year, err := strconv.Atoi(m.c["year"])
-			if err == nil {
-				return year
-			}
-		}
-		return 0
-	case 4:
-		dateFormat = "2006"
-	case 7:
-		dateFormat = "2006-01"
-	case 10:
-		dateFormat = "2006-01-02"
+	if year := parseYear(m.c["date"]); year != 0 {
+		return year
 	}
 
-	t, _ := time.Parse(dateFormat, m.c["date"])
-	return t.Year()
+	// Fallback on year tag as some files use that.
+	if year, err := strconv.Atoi(strings.TrimSpace(m.c["year"])); err == nil {
+		return year
+	}
+	return parseYear(m.c["year"])
+}
+
+// xOfN returns the number and total from the given fields.  The total may be
+// given as part of the number (i.e. "5/12"), or in one of the total fields.
+func (m *metadataVorbis) xOfN(number string, totals ...string) (int, int) {
+	x, n := parseXofN(m.c[number])
+	for _, t := range totals {
+		if n != 0 {
+			break
+		}
+		n, _ = strconv.Atoi(strings.TrimSpace(m.c[t]))
+	}
+	return x, n
 }
 
 func (m *metadataVorbis) Track() (int, int) {
-	x, _ := strconv.Atoi(m.c["tracknumber"])
 	// https://wiki.xiph.org/Field_names
-	n, _ := strconv.Atoi(m.c["tracktotal"])
-	return x, n
+	return m.xOfN("tracknumber", "tracktotal", "totaltracks")
 }
 
 func (m *metadataVorbis) Disc() (int, int) {
 	// https://wiki.xiph.org/Field_names
-	x, _ := strconv.Atoi(m.c["discnumber"])
-	n, _ := strconv.Atoi(m.c["disctotal"])
-	return x, n
+	return m.xOfN("discnumber", "disctotal", "totaldiscs")
 }
 
 func (m *metadataVorbis) Lyrics() string {
@@ -261,6 +336,110 @@ func (m *metadataVorbis) Comment() string {
 	return m.c["description"]
 }
 
+func (m *metadataVorbis) MediaType() string {
+	return m.c["media"]
+}
+
+func (m *metadataVorbis) Mood() string {
+	return m.c["mood"]
+}
+
+func (m *metadataVorbis) Rating() int {
+	// There is no standard rating field.
+	return 0
+}
+
+func (m *metadataVorbis) ContentGroup() string {
+	return m.c["grouping"]
+}
+
+func (m *metadataVorbis) Work() string {
+	return m.c["work"]
+}
+
+func (m *metadataVorbis) ClassicalInfo() (ClassicalInfo, bool) {
+	// The fields written by MusicBrainz Picard.
+	x, n := m.xOfN("movement", "movementtotal")
+	return classicalInfo(ClassicalInfo{
+		Work:           m.Work(),
+		MovementName:   m.c["movementname"],
+		MovementNumber: x,
+		MovementCount:  n,
+		Composer:       m.c["composer"], // not Composer, which falls back to the performer
+	})
+}
+
+func (m *metadataVorbis) Gapless() (GaplessInfo, bool) {
+	return GaplessInfo{}, false
+}
+
+func (m *metadataVorbis) Subtitle() string {
+	return m.c["subtitle"]
+}
+
+func (m *metadataVorbis) DiscSubtitle() string {
+	return m.c["discsubtitle"]
+}
+
+func (m *metadataVorbis) Conductor() string {
+	return m.c["conductor"]
+}
+
+func (m *metadataVorbis) Remixer() string {
+	return m.c["remixer"]
+}
+
+func (m *metadataVorbis) InvolvedPeople() []Credit {
+	var c []Credit
+	for _, r := range vorbisCreditRoles {
+		if v := m.c[r.field]; v != "" {
+			c = append(c, Credit{Role: r.role, Name: v})
+		}
+	}
+	return c
+}
+
+func (m *metadataVorbis) Copyright() string {
+	return m.c["copyright"]
+}
+
+func (m *metadataVorbis) Publisher() string {
+	// ORGANIZATION is recommended by https://wiki.xiph.org/Field_names, others are in use.
+	for _, k := range []string{"organization", "label", "publisher"} {
+		if v := m.c[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (m *metadataVorbis) Owner() string {
+	return m.c["owner"]
+}
+
+func (m *metadataVorbis) PodcastInfo() (PodcastInfo, bool) {
+	// There are no standard podcast fields, see FieldPodcastURL and FieldPodcastGUID.
+	p := PodcastInfo{
+		FeedURL:     m.c["podcasturl"],
+		EpisodeGUID: m.c["podcastguid"],
+		Description: m.c["description"],
+	}
+	return p, p.FeedURL != "" || p.EpisodeGUID != ""
+}
+
+func (m *metadataVorbis) ITunesTags() ITunesInfo {
+	return ITunesInfo{
+		AlbumArtist:     m.AlbumArtist(),
+		AlbumArtistSort: m.c["albumartistsort"],
+		Compilation:     parseCompilation(m.c["compilation"]),
+		Grouping:        m.c["grouping"],
+	}
+}
+
 func (m *metadataVorbis) Picture() *Picture {
-	return m.p
+	return frontCover(m.pics)
+}
+
+func (m *metadataVorbis) Pictures() []*Picture {
+	return m.pics
 }
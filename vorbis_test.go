@@ -0,0 +1,189 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// testVorbisComment returns the Vorbis comment for data.
+func testVorbisComment(t *testing.T, data map[string]string) []byte {
+	t.Helper()
+	b, err := PrepareVorbisComment(data)
+	if err != nil {
+		t.Fatalf("PrepareVorbisComment() = %v", err)
+	}
+	return b
+}
+
+// testVorbisPicture returns a METADATA_BLOCK_PICTURE value for a front cover with the given data.
+func testVorbisPicture(data string) string {
+	return base64.StdEncoding.EncodeToString(testFLACPictureData("image/png", []byte(data)))
+}
+
+// readVorbisFields returns the metadata from the Vorbis comment for data.
+func readVorbisFields(t *testing.T, data map[string]string) *metadataVorbis {
+	t.Helper()
+	m := newMetadataVorbis()
+	if err := m.readVorbisComment(bytes.NewReader(testVorbisComment(t, data))); err != nil {
+		t.Fatalf("readVorbisComment() = %v", err)
+	}
+	return m
+}
+
+func TestVorbisTrackTotal(t *testing.T) {
+	tests := []map[string]string{
+		{"tracknumber": "5", "tracktotal": "12"},
+		{"tracknumber": "5", "totaltracks": "12"},
+		{"tracknumber": "5/12"},
+		{"tracknumber": "5 / 12", "tracktotal": "13"},
+	}
+
+	for ii, tt := range tests {
+		m := newMetadataVorbis()
+		for k, v := range tt {
+			m.c[k] = v
+		}
+		x, n := m.Track()
+		if x != 5 || n != 12 {
+			t.Errorf("[%d] Track() = %d, %d, expected: 5, 12", ii, x, n)
+		}
+	}
+}
+
+func TestPrepareVorbisCommentTotalStyle(t *testing.T) {
+	defer func(s VorbisTotalStyle) { DefaultVorbisTotalStyle = s }(DefaultVorbisTotalStyle)
+
+	tests := []struct {
+		style    VorbisTotalStyle
+		expected map[string]string
+	}{
+		{TotalField, map[string]string{"tracknumber": "5", "tracktotal": "12", "discnumber": "1", "disctotal": "2"}},
+		{TotalLegacyField, map[string]string{"tracknumber": "5", "totaltracks": "12", "discnumber": "1", "totaldiscs": "2"}},
+		{TotalInNumber, map[string]string{"tracknumber": "5/12", "discnumber": "1/2"}},
+	}
+
+	for ii, tt := range tests {
+		DefaultVorbisTotalStyle = tt.style
+		m := readVorbisFields(t, map[string]string{
+			"TRACKNUMBER": "5/12",
+			"TotalTracks": "13",
+			"DISCNUMBER":  "1",
+			"DISCTOTAL":   "2",
+		})
+
+		if len(m.c) != len(tt.expected)+1 { // +1 for vendor
+			t.Errorf("[%d] got %d fields, expected %d: %v", ii, len(m.c)-1, len(tt.expected), m.c)
+		}
+		for k, v := range tt.expected {
+			if m.c[k] != v {
+				t.Errorf("[%d] %v = %q, expected %q", ii, k, m.c[k], v)
+			}
+		}
+
+		x, n := m.Track()
+		if x != 5 || n != 12 {
+			t.Errorf("[%d] Track() = %d, %d, expected: 5, 12", ii, x, n)
+		}
+		x, n = m.Disc()
+		if x != 1 || n != 2 {
+			t.Errorf("[%d] Disc() = %d, %d, expected: 1, 2", ii, x, n)
+		}
+	}
+}
+
+func TestPrepareVorbisCommentInvalidName(t *testing.T) {
+	if _, err := PrepareVorbisComment(map[string]string{"A=B": "C"}); err == nil {
+		t.Errorf("expected error for invalid field name")
+	}
+}
+
+func TestVorbisYear(t *testing.T) {
+	tests := []struct {
+		c    map[string]string
+		want int
+	}{
+		{map[string]string{"date": "1996-04"}, 1996},
+		{map[string]string{"date": "1996-04-12T10:00:00"}, 1996},
+		{map[string]string{"year": "1996"}, 1996},
+		{map[string]string{"date": "unknown", "year": "1996"}, 1996},
+		{map[string]string{"date": "96"}, 0},
+	}
+
+	for ii, tt := range tests {
+		m := newMetadataVorbis()
+		m.c = tt.c
+		if got := m.Year(); got != tt.want {
+			t.Errorf("[%d] Year() = %d, expected %d", ii, got, tt.want)
+		}
+	}
+}
+
+func TestPrepareVorbisCommentYear(t *testing.T) {
+	tests := []struct {
+		data       map[string]string
+		date, year string
+	}{
+		{map[string]string{"YEAR": "1996"}, "1996", "1996"},
+		{map[string]string{"DATE": "1996-04", "YEAR": "2000"}, "1996-04", "1996"},
+		{map[string]string{"DATE": "1996-04"}, "1996-04", ""},
+	}
+
+	for ii, tt := range tests {
+		m := readVorbisFields(t, tt.data)
+		if got := m.c["date"]; got != tt.date {
+			t.Errorf("[%d] DATE = %q, expected %q", ii, got, tt.date)
+		}
+		if got := m.c["year"]; got != tt.year {
+			t.Errorf("[%d] YEAR = %q, expected %q", ii, got, tt.year)
+		}
+	}
+}
+
+func TestWriteVorbisComments(t *testing.T) {
+	comments := map[string][]string{
+		"TITLE":  {"Title"},
+		"ARTIST": {"Artist 1", "Artist 2"},
+		"GENRE":  {"Rock", "", "Pop"},
+	}
+	b, err := encodeVorbisComment("test", comments)
+	if err != nil {
+		t.Fatalf("encodeVorbisComment() = %v", err)
+	}
+	flac := testReadFLAC(t, tempFile(t, testFLAC(testFLACBlock(vorbisCommentBlock, true, b))))
+	got := VorbisCommentsFrom(flac)
+	if !reflect.DeepEqual(got, comments) {
+		t.Fatalf("VorbisCommentsFrom() = %v, expected %v", got, comments)
+	}
+
+	f := tempCopy(t, "with_tags/sample.ogg")
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	vendor := m.Raw()["vendor"]
+	if err := WriteVorbisComments(f, got); err != nil {
+		t.Fatalf("WriteVorbisComments() = %v", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	m, err = ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	if got := VorbisCommentsFrom(m); !reflect.DeepEqual(got, comments) {
+		t.Errorf("VorbisCommentsFrom() = %v, expected %v", got, comments)
+	}
+	testValue(t, vendor, m.Raw()["vendor"])
+
+	if VorbisCommentsFrom(metadataID3v1{}) != nil {
+		t.Errorf("VorbisCommentsFrom(ID3v1) != nil")
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// vorbisVendor is the vendor string used in new Vorbis comments.
+const vorbisVendor = "github.com/dhowden/tag"
+
+// VorbisTotalStyle is an enumeration of the ways track and disc totals can be stored
+// in Vorbis comments.
+type VorbisTotalStyle int
+
+// Supported Vorbis total styles.
+const (
+	TotalField       VorbisTotalStyle = iota // TRACKNUMBER=5, TRACKTOTAL=12 (recommended by https://wiki.xiph.org/Field_names).
+	TotalLegacyField                         // TRACKNUMBER=5, TOTALTRACKS=12.
+	TotalInNumber                            // TRACKNUMBER=5/12.
+)
+
+// DefaultVorbisTotalStyle is the style used by PrepareVorbisComment to write track and disc
+// totals.  All styles are recognised when reading.
+var DefaultVorbisTotalStyle = TotalField
+
+// vorbisTotalFields lists the number field and the total fields (in order of
+// preference) recognised for tracks and discs.
+var vorbisTotalFields = [][3]string{
+	{FieldTrackNumber, FieldTrackTotal, "TOTALTRACKS"},
+	{FieldDiscNumber, FieldDiscTotal, "TOTALDISCS"},
+}
+
+// canonicaliseVorbisTotals rewrites the track and disc number/total fields in data (which
+// must already be normalised) using the given style.
+func canonicaliseVorbisTotals(data map[string]string, style VorbisTotalStyle) {
+	for _, f := range vorbisTotalFields {
+		x, n := data[f[0]], ""
+		if i := strings.Index(x, "/"); i >= 0 {
+			x, n = strings.TrimSpace(x[:i]), strings.TrimSpace(x[i+1:])
+		}
+		for _, t := range f[1:] {
+			if n == "" {
+				n = strings.TrimSpace(data[t])
+			}
+			delete(data, t)
+		}
+		delete(data, f[0])
+
+		if n == "" {
+			if x != "" {
+				data[f[0]] = x
+			}
+			continue
+		}
+
+		switch style {
+		case TotalInNumber:
+			data[f[0]] = formatXofN(x, n)
+		case TotalLegacyField:
+			data[f[2]] = n
+			if x != "" {
+				data[f[0]] = x
+			}
+		default:
+			data[f[1]] = n
+			if x != "" {
+				data[f[0]] = x
+			}
+		}
+	}
+}
+
+// PrepareVorbisComment returns the Vorbis comment (as stored in a FLAC VORBIS_COMMENT block) for
+// the fields in data.  The vendor string is taken from the "vendor" key (as returned by Raw), all
+// other keys are written as upper case field names.  Track and disc totals are written using
+// DefaultVorbisTotalStyle, and the DATE and YEAR fields are made consistent (with DATE taking
+// precedence).
+func PrepareVorbisComment(data map[string]string) ([]byte, error) {
+	data = normaliseFields(data)
+
+	vendor := vorbisVendor
+	if v, ok := data["VENDOR"]; ok {
+		vendor = v
+		delete(data, "VENDOR")
+	}
+	canonicaliseVorbisTotals(data, DefaultVorbisTotalStyle)
+	syncYearFields(data)
+
+	fields := make(map[string][]string, len(data))
+	for k, v := range data {
+		fields[k] = []string{v}
+	}
+	return encodeVorbisComment(vendor, fields)
+}
+
+// updateVorbisComment returns the Vorbis comment with the given vendor string and values
+// (by upper case field name, as in metadataVorbis) updated by the fields in data (see
+// UpdateFLACTags).  Fields which are not changed keep all of their values, so repeated
+// fields (i.e. several artists or pictures) are only replaced if they are in data.
+func updateVorbisComment(vendor string, values map[string][]string, data map[string]*string) ([]byte, error) {
+	update := make(map[string]*string, len(data))
+	for k, v := range data {
+		update[strings.ToUpper(k)] = v
+	}
+	if v, ok := update["VENDOR"]; ok {
+		if v != nil {
+			vendor = *v
+		}
+		delete(update, "VENDOR")
+	}
+
+	old := make(map[string]string, len(values))
+	fields := make(map[string]string, len(values))
+	for k, v := range values {
+		if len(v) > 0 {
+			old[k], fields[k] = v[0], v[0]
+		}
+	}
+	// A new year replaces the existing date, which would otherwise take precedence.
+	_, year := update[FieldYear]
+	_, date := update[FieldDate]
+	if year && !date {
+		delete(fields, FieldDate)
+	}
+	for k, v := range update {
+		if v == nil {
+			delete(fields, k)
+			continue
+		}
+		fields[k] = *v
+	}
+	canonicaliseVorbisTotals(fields, DefaultVorbisTotalStyle)
+	syncYearFields(fields)
+
+	res := make(map[string][]string, len(fields))
+	for k, v := range fields {
+		x, ok := old[k]
+		if _, updated := update[k]; ok && x == v && !updated {
+			res[k] = values[k]
+			continue
+		}
+		res[k] = []string{v}
+	}
+	return encodeVorbisComment(vendor, res)
+}
+
+// encodeVorbisComment returns the Vorbis comment with the given vendor string and fields, which
+// are written in order of field name with the values of each field in the given order.
+func encodeVorbisComment(vendor string, fields map[string][]string) ([]byte, error) {
+	keys := make([]string, 0, len(fields))
+	n := 0
+	for k, v := range fields {
+		if !validVorbisFieldName(k) {
+			return nil, fmt.Errorf("invalid vorbis comment field name: %q", k)
+		}
+		keys = append(keys, k)
+		n += len(v)
+	}
+	sort.Strings(keys)
+
+	b := &bytes.Buffer{}
+	writeVorbisString(b, vendor)
+	binary.Write(b, binary.LittleEndian, uint32(n))
+	for _, k := range keys {
+		for _, v := range fields[k] {
+			writeVorbisString(b, k+"="+v)
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// WriteVorbisComments replaces the Vorbis comment of the FLAC or Ogg data in rw with the
+// fields in comments, as returned by VorbisCommentsFrom, so that the comments of one file can
+// be copied to another without losing repeated fields.  Field names are written in upper case
+// and the values are written as given: unlike WriteFLACTags and WriteOGGTags, the track,
+// disc and date fields are not rewritten.  The existing vendor string is kept unless comments
+// contains a "vendor" key.
+func WriteVorbisComments(rw io.ReadWriteSeeker, comments map[string][]string) error {
+	fields := make(map[string][]string, len(comments))
+	var vendor []string
+	for k, v := range comments {
+		k = strings.ToUpper(k)
+		if k == "VENDOR" {
+			vendor = v
+			continue
+		}
+		fields[k] = append(fields[k], v...)
+	}
+	comment := func(old string) ([]byte, error) {
+		if len(vendor) > 0 {
+			old = vendor[0]
+		}
+		return encodeVorbisComment(old, fields)
+	}
+
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, fileType, err := sniffFormat(rw)
+	if err != nil {
+		return err
+	}
+	switch fileType {
+	case FLAC:
+		blocks, audioOffset, err := readFLACBlocks(rw)
+		if err != nil {
+			return err
+		}
+		old, err := readFLACComment(blocks)
+		if err != nil {
+			return err
+		}
+		b, err := comment(old["VENDOR"])
+		if err != nil {
+			return err
+		}
+		return writeFLACComment(rw, blocks, audioOffset, b, FLACWriteOptions{})
+
+	case OGG:
+		return writeOGGComment(rw, comment)
+	}
+	return ErrUnsupportedFormat
+}
+
+// FieldVorbisPicture is the Vorbis comment field used to store a picture in Ogg files, which
+// don't have PICTURE blocks (see EncodeVorbisPicture).  Pictures are read from this field
+// (or the deprecated COVERART field) in both Ogg and FLAC files.
+const FieldVorbisPicture = "METADATA_BLOCK_PICTURE"
+
+// EncodeVorbisPicture returns pic encoded as the value of a METADATA_BLOCK_PICTURE field: a
+// FLAC PICTURE block (see buildFLACPictureBlock) encoded using base64.  Add the value to the
+// data passed to WriteOGGTags with the key FieldVorbisPicture.
+func EncodeVorbisPicture(pic *Picture) (string, error) {
+	b, err := buildFLACPictureBlock(pic)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// validVorbisFieldName returns true if k is a valid field name: ASCII 0x20 through
+// 0x7D, excluding '=' (see https://xiph.org/vorbis/doc/v-comment.html).
+func validVorbisFieldName(k string) bool {
+	if k == "" {
+		return false
+	}
+	for i := 0; i < len(k); i++ {
+		if k[i] < 0x20 || k[i] > 0x7D || k[i] == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// writeVorbisString writes s to b prefixed by its 32-bit little-endian length.
+func writeVorbisString(b *bytes.Buffer, s string) {
+	binary.Write(b, binary.LittleEndian, uint32(len(s)))
+	b.WriteString(s)
+}
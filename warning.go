@@ -0,0 +1,44 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"fmt"
+	"io"
+)
+
+// Warning is a non-fatal problem found when reading metadata (see ReadFromStrict).
+type Warning struct {
+	Format  Format // Metadata format in which the problem was found.
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (w Warning) String() string {
+	return fmt.Sprintf("%v: %v", w.Format, w.Message)
+}
+
+// warnings collects the warnings found when reading metadata.  Calling add on a nil
+// *warnings is a no-op, so readers can always report problems.
+type warnings []Warning
+
+func (w *warnings) add(f Format, format string, args ...interface{}) {
+	if w == nil {
+		return
+	}
+	*w = append(*w, Warning{Format: f, Message: fmt.Sprintf(format, args...)})
+}
+
+// ReadFromStrict is like ReadFrom, but also returns any non-fatal problems found in the
+// metadata (i.e. unknown ID3v2 frame flags, unknown text encodings or duplicate FLAC
+// metadata blocks) which are otherwise silently ignored.
+func ReadFromStrict(r io.ReadSeeker) (Metadata, []Warning, error) {
+	var w warnings
+	m, err := readFrom(r, &w, ReadOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, w, nil
+}
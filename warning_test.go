@@ -0,0 +1,81 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestReadFromStrictDuplicateVorbisComment(t *testing.T) {
+	b := testFLAC(
+		testFLACBlock(vorbisCommentBlock, false, testVorbisComment(t, map[string]string{"TITLE": "First"})),
+		testFLACBlock(vorbisCommentBlock, true, testVorbisComment(t, map[string]string{"ARTIST": "Second"})),
+	)
+
+	m, w, err := ReadFromStrict(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFromStrict() = %v", err)
+	}
+	if m == nil {
+		t.Fatal("ReadFromStrict() returned nil Metadata")
+	}
+
+	want := []Warning{{Format: VORBIS, Message: "duplicate VORBIS_COMMENT metadata block"}}
+	if len(w) != len(want) || w[0] != want[0] {
+		t.Errorf("ReadFromStrict() warnings = %v, expected %v", w, want)
+	}
+}
+
+func TestReadFromStrictID3v2(t *testing.T) {
+	b := testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Flags: [2]byte{0x01, 0}, Data: []byte("\x00Title")},
+		id3v2RawFrame{Name: "TPE1", Data: []byte("\x07Artist")},
+	)
+
+	m, w, err := ReadFromStrict(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("ReadFromStrict() = %v", err)
+	}
+	if got := m.Title(); got != "Title" {
+		t.Errorf("Title() = %q, expected %q", got, "Title")
+	}
+
+	want := []Warning{
+		{Format: ID3v2_3, Message: `frame "TIT2" has unknown flags set`},
+		{Format: ID3v2_3, Message: `frame "TPE1" has unknown text encoding: 7`},
+	}
+	if len(w) != len(want) {
+		t.Fatalf("ReadFromStrict() warnings = %v, expected %v", w, want)
+	}
+	for i := range want {
+		if w[i] != want[i] {
+			t.Errorf("ReadFromStrict() warning %d = %v, expected %v", i, w[i], want[i])
+		}
+	}
+}
+
+func TestReadFromStrictNoWarnings(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.flac",
+		"with_tags/sample.id3v24.mp3",
+	} {
+		f, err := os.Open("testdata/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		_, w, err := ReadFromStrict(f)
+		if err != nil {
+			t.Errorf("%v: ReadFromStrict() = %v", path, err)
+			continue
+		}
+		if len(w) != 0 {
+			t.Errorf("%v: ReadFromStrict() warnings = %v, expected none", path, w)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrUnsupportedFormat is the error returned by the Write* functions when the format of
+// the data is not supported.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// ErrNotTruncatable is the error returned when the data must be made smaller but the
+// io.ReadWriteSeeker does not implement Truncate (as *os.File does).
+var ErrNotTruncatable = errors.New("io.ReadWriteSeeker does not implement Truncate")
+
+// truncater is implemented by types which can be resized, such as *os.File.
+type truncater interface {
+	Truncate(size int64) error
+}
+
+// Field names recognised in the map passed to the Write* functions.  Keys are matched
+// case-insensitively and follow the Vorbis comment field names (see https://wiki.xiph.org/Field_names).
+// Formats which don't have a native equivalent for a key store it as a user-defined field where
+// possible (i.e. TXXX in ID3v2).
+const (
+	FieldTitle           = "TITLE"
+	FieldArtist          = "ARTIST"
+	FieldAlbum           = "ALBUM"
+	FieldAlbumArtist     = "ALBUMARTIST"
+	FieldAlbumArtistSort = "ALBUMARTISTSORT" // Sort order of the album artist.
+	FieldArtistSort      = "ARTISTSORT"      // Sort order of the artist.
+	FieldComposer        = "COMPOSER"
+	FieldGenre           = "GENRE"
+	FieldDate            = "DATE"
+	FieldYear            = "YEAR"
+	FieldTrackNumber     = "TRACKNUMBER"
+	FieldTrackTotal      = "TRACKTOTAL"
+	FieldDiscNumber      = "DISCNUMBER"
+	FieldDiscTotal       = "DISCTOTAL"
+	FieldComment         = "COMMENT"
+	FieldLyrics          = "LYRICS" // Unsynchronised lyrics.
+	FieldMood            = "MOOD"
+	FieldInitialKey      = "INITIALKEY" // Musical key the track starts in (i.e. "Am").
+	FieldLanguage        = "LANGUAGE"   // Language of the lyrics (ISO 639-2 code).
+	FieldBPM             = "BPM"        // Beats per minute.
+	FieldCopyright       = "COPYRIGHT"
+	FieldPublisher       = "ORGANIZATION" // Publisher or record label.
+	FieldOwner           = "OWNER"        // Owner of the file.
+	FieldPodcastURL      = "PODCASTURL"   // Podcast feed URL.
+	FieldPodcastGUID     = "PODCASTGUID"  // Podcast episode GUID.
+	FieldMediaKind       = "MEDIAKIND"    // MP4 media kind (see MediaKind), as a number or name.
+)
+
+// normaliseFields returns a copy of data with all keys converted to upper case.
+func normaliseFields(data map[string]string) map[string]string {
+	res := make(map[string]string, len(data))
+	for k, v := range data {
+		res[strings.ToUpper(k)] = v
+	}
+	return res
+}
+
+// fieldYear returns the year part of the DATE (or YEAR) field in data, which must
+// already be normalised.
+func fieldYear(data map[string]string) string {
+	d := data[FieldDate]
+	if d == "" {
+		d = data[FieldYear]
+	}
+	if len(d) > 4 {
+		d = d[:4]
+	}
+	return d
+}
+
+// syncYearFields makes the DATE and YEAR fields in data (which must already be normalised)
+// consistent: DATE is set from YEAR if it is empty, and an existing YEAR field is set to the
+// year of DATE.
+func syncYearFields(data map[string]string) {
+	if data[FieldDate] == "" {
+		if y := data[FieldYear]; y != "" {
+			data[FieldDate] = y
+		}
+		return
+	}
+	if _, ok := data[FieldYear]; ok {
+		data[FieldYear] = fieldYear(data)
+	}
+}
+
+// shiftBufferSize is the size of the buffer used when moving file content.
+const shiftBufferSize = 64 << 10 // 64KB
+
+// shiftFileRight moves all content from offset to the end of rw right by n bytes.  The
+// content of the n bytes starting at offset is left unchanged and should be overwritten
+// by the caller.
+func shiftFileRight(rw io.ReadWriteSeeker, offset, n int64) error {
+	end, err := rw.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, shiftBufferSize)
+	for pos := end; pos > offset; {
+		chunk := int64(len(buf))
+		if pos-offset < chunk {
+			chunk = pos - offset
+		}
+		pos -= chunk
+
+		if _, err := rw.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(rw, buf[:chunk]); err != nil {
+			return fmt.Errorf("error reading %d bytes at %d: %v", chunk, pos, err)
+		}
+		if _, err := rw.Seek(pos+n, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := rw.Write(buf[:chunk]); err != nil {
+			return fmt.Errorf("error writing %d bytes at %d: %v", chunk, pos+n, err)
+		}
+	}
+	return nil
+}
+
+// shiftFileLeft moves all content from offset to the end of rw left by n bytes (overwriting
+// the n bytes preceding offset), and truncates rw accordingly.  Returns ErrNotTruncatable if
+// rw cannot be truncated, in which case rw is left unchanged.
+func shiftFileLeft(rw io.ReadWriteSeeker, offset, n int64) error {
+	t, ok := rw.(truncater)
+	if !ok {
+		return ErrNotTruncatable
+	}
+
+	end, err := rw.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, shiftBufferSize)
+	for pos := offset; pos < end; {
+		chunk := int64(len(buf))
+		if end-pos < chunk {
+			chunk = end - pos
+		}
+
+		if _, err := rw.Seek(pos, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(rw, buf[:chunk]); err != nil {
+			return fmt.Errorf("error reading %d bytes at %d: %v", chunk, pos, err)
+		}
+		if _, err := rw.Seek(pos-n, io.SeekStart); err != nil {
+			return err
+		}
+		if _, err := rw.Write(buf[:chunk]); err != nil {
+			return fmt.Errorf("error writing %d bytes at %d: %v", chunk, pos-n, err)
+		}
+		pos += chunk
+	}
+	return t.Truncate(end - n)
+}
+
+// replaceRegion replaces the n bytes of rw starting at offset with b, moving the
+// content which follows as required.
+func replaceRegion(rw io.ReadWriteSeeker, offset, n int64, b []byte) error {
+	switch delta := int64(len(b)) - n; {
+	case delta > 0:
+		if err := shiftFileRight(rw, offset+n, delta); err != nil {
+			return err
+		}
+	case delta < 0:
+		if err := shiftFileLeft(rw, offset+n, -delta); err != nil {
+			return err
+		}
+	}
+
+	if _, err := rw.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := rw.Write(b)
+	return err
+}
+
+// RemovePictures removes all embedded pictures from rw (FLAC PICTURE blocks, ID3v2 APIC frames
+// and MP4 covr atoms), moving the following data to reclaim the space.  Returns the number of
+// bytes removed.  As the data is made smaller, rw must implement Truncate (i.e. *os.File).
+func RemovePictures(rw io.ReadWriteSeeker) (int64, error) {
+	return RemovePicturesWithOptions(rw, FLACWriteOptions{})
+}
+
+// RemovePicturesWithOptions is like RemovePictures, but FLAC metadata is written with the given
+// options.
+func RemovePicturesWithOptions(rw io.ReadWriteSeeker, opts FLACWriteOptions) (int64, error) {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+	format, fileType, err := sniffFormat(rw)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case fileType == FLAC:
+		return removeFLACBlocks(rw, pictureBlock, opts)
+
+	case format == MP4:
+		return removeMP4Items(rw, "covr")
+
+	case fileType == MP3 && format != UnknownFormat:
+		return removeID3v2Frames(rw, "APIC", "PIC")
+	}
+	return 0, ErrUnsupportedFormat
+}
+
+// SetPicture adds pic to the metadata in rw without rewriting the text fields, replacing any
+// existing pictures of the same type: as a FLAC PICTURE block, an Ogg METADATA_BLOCK_PICTURE
+// field, an ID3v2 APIC frame (in a new ID3v2.4 tag if there isn't one) or an MP4 covr item
+// (which replaces all the covr items, as they have no type).  A picture without a Type is
+// written as the front cover.  The MIME type is checked against the picture data (see
+// ErrMIMEMismatch).
+func SetPicture(rw io.ReadWriteSeeker, pic *Picture) error {
+	data, err := pic.Read()
+	if err != nil {
+		return err
+	}
+	p := *pic
+	p.Data = data
+	if p.Type == "" {
+		p.Type = pictureFrontCover
+	}
+
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	format, fileType, err := sniffFormat(rw)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case fileType == FLAC:
+		return setFLACPicture(rw, &p)
+
+	case fileType == OGG:
+		return setOGGPicture(rw, &p)
+
+	case format == MP4:
+		return setMP4Picture(rw, &p)
+
+	case fileType == MP3:
+		return setID3v2Picture(rw, &p)
+	}
+	return ErrUnsupportedFormat
+}
+
+// SetRating sets the rating of the track to rating (0 to 100, where 20 is one star and 0 is
+// unrated) without rewriting the other fields: in the ID3v2 POPM frame with the given email
+// (the Windows Media Player email, which is preferred by Metadata.Rating, if email is empty),
+// with the rating mapped to 0 to 255 as by Windows, or in the MP4 rate item.
+func SetRating(rw io.ReadWriteSeeker, rating int, email string) error {
+	if rating < 0 || rating > 100 {
+		return fmt.Errorf("invalid rating: %d, expected 0 to 100", rating)
+	}
+	if email == "" {
+		email = popmWMPEmail
+	}
+
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	format, fileType, err := sniffFormat(rw)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case format == MP4:
+		return setMP4Rating(rw, rating)
+
+	case fileType == MP3:
+		return setID3v2Rating(rw, popmRating(rating), email)
+	}
+	return ErrUnsupportedFormat
+}
+
+// FormatCapability describes the support for a file type.
+type FormatCapability struct {
+	FileType        FileType
+	CanRead         bool // Metadata can be read (see ReadFrom).
+	CanWrite        bool // Text fields can be written.
+	CanWritePicture bool // Pictures can be written.
+}
+
+// capabilities is the support for each file type, which must be updated as readers and
+// writers are added.
+var capabilities = []FormatCapability{
+	{FileType: MP3, CanRead: true, CanWrite: true, CanWritePicture: true}, // WriteID3Both, SetPicture
+	{FileType: M4A, CanRead: true, CanWrite: true, CanWritePicture: true}, // WriteMP4Tags, SetPicture
+	{FileType: M4B, CanRead: true, CanWrite: true, CanWritePicture: true},
+	{FileType: M4P, CanRead: true, CanWrite: true, CanWritePicture: true},
+	{FileType: ALAC, CanRead: true},
+	{FileType: FLAC, CanRead: true, CanWrite: true, CanWritePicture: true}, // WriteFLACTags, UpdateFLACTags, SetPicture
+	{FileType: OGG, CanRead: true, CanWrite: true, CanWritePicture: true},  // WriteOGGTags, SetPicture
+	{FileType: DSF, CanRead: true},
+	{FileType: AIFF, CanRead: true, CanWrite: true}, // WriteAIFFTags
+	{FileType: DFF, CanRead: true},
+}
+
+// Capabilities returns the support for each file type.
+func Capabilities() []FormatCapability {
+	return append([]FormatCapability(nil), capabilities...)
+}
+
+// writeTags merges the fields in data into the metadata of rw using the writer for the detected
+// file type (see Capabilities): fields which are not in data are kept.  MP3 data is detected by
+// an ID3 tag or MPEG frame sync at the start of rw.
+func writeTags(rw io.ReadWriteSeeker, data map[string]string) error {
+	if _, err := rw.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	format, fileType, err := sniffFormat(rw)
+	if err != nil {
+		return err
+	}
+	if format == MP4 {
+		return updateMP4Tags(rw, data)
+	}
+
+	update := make(map[string]*string, len(data))
+	for k, v := range data {
+		v := v
+		update[k] = &v
+	}
+
+	switch fileType {
+	case FLAC:
+		return UpdateFLACTags(rw, update)
+
+	case OGG:
+		return updateOGGTags(rw, update)
+
+	case AIFF:
+		return WriteAIFFTags(rw, data)
+
+	case MP3:
+		if err := WriteID3v2Tags(rw, data); err != nil {
+			return err
+		}
+		// The ID3v1 tag is written from the merged ID3v2 tag so that it keeps the other fields.
+		if _, err := rw.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		m, err := ReadID3v2Tags(rw)
+		if err != nil {
+			return err
+		}
+		return writeID3v1Tag(rw, id3v1Fields(m))
+	}
+	return ErrUnsupportedFormat
+}
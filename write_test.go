@@ -0,0 +1,454 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tempCopy copies the testdata file at path to a temporary file, which is opened
+// for reading and writing.
+func tempCopy(t *testing.T, path string) *os.File {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tempFile(t, b)
+}
+
+// tempFile creates a temporary file containing b, which is opened for reading
+// and writing.
+func tempFile(t *testing.T, b []byte) *os.File {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readAll returns the full content of f.
+func readAll(t *testing.T, f *os.File) []byte {
+	t.Helper()
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestShiftFileRight(t *testing.T) {
+	tests := []struct {
+		size, offset, n int
+	}{
+		{10, 0, 5},
+		{10, 3, 1},
+		{3*shiftBufferSize + 7, 11, 1025},
+		{3*shiftBufferSize + 7, 0, shiftBufferSize + 1},
+	}
+
+	for ii, tt := range tests {
+		in := make([]byte, tt.size)
+		for i := range in {
+			in[i] = byte(i % 251)
+		}
+		f := tempFile(t, in)
+
+		if err := shiftFileRight(f, int64(tt.offset), int64(tt.n)); err != nil {
+			t.Errorf("[%d] shiftFileRight() = %v", ii, err)
+			continue
+		}
+
+		got := readAll(t, f)
+		if len(got) != tt.size+tt.n {
+			t.Errorf("[%d] got size %d, expected %d", ii, len(got), tt.size+tt.n)
+			continue
+		}
+		if !bytes.Equal(got[:tt.offset], in[:tt.offset]) {
+			t.Errorf("[%d] content before offset changed", ii)
+		}
+		if !bytes.Equal(got[tt.offset+tt.n:], in[tt.offset:]) {
+			t.Errorf("[%d] content after offset not shifted", ii)
+		}
+	}
+}
+
+func TestRemovePicturesFLAC(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	blocks, audioOffset, err := readFLACBlocks(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pic := testFLACPictureData("image/png", []byte("not really a png"))
+	blocks = append(blocks[:1], append([]flacBlock{{Type: pictureBlock, Data: pic}}, blocks[1:]...)...)
+	if err := writeFLACBlocks(f, blocks, audioOffset, FLACWriteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	before := readAll(t, f)
+	f.Seek(0, io.SeekStart)
+	m, err := ReadFLACTags(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Picture() == nil {
+		t.Fatal("expected picture before RemovePictures")
+	}
+
+	n, err := RemovePictures(f)
+	if err != nil {
+		t.Fatalf("RemovePictures() = %v", err)
+	}
+	testValue(t, int64(4+len(pic)), n)
+
+	after := readAll(t, f)
+	testValue(t, len(before)-int(n), len(after))
+
+	f.Seek(0, io.SeekStart)
+	m, err = ReadFLACTags(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Picture() != nil {
+		t.Errorf("expected no picture after RemovePictures")
+	}
+	testValue(t, "Test Title", m.Title())
+	if !bytes.Equal(before[len(before)-1000:], after[len(after)-1000:]) {
+		t.Errorf("audio data changed")
+	}
+}
+
+func TestRemovePicturesID3v2(t *testing.T) {
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apic := append([]byte("\x00image/jpeg\x00\x03\x00"), "not really a jpeg"...)
+	f := tempFile(t, append(testID3v2Tag(
+		id3v2RawFrame{Name: "TIT2", Data: []byte("\x00Title")},
+		id3v2RawFrame{Name: "APIC", Data: apic},
+	), audio...))
+	size := len(readAll(t, f))
+
+	n, err := RemovePictures(f)
+	if err != nil {
+		t.Fatalf("RemovePictures() = %v", err)
+	}
+	testValue(t, int64(10+len(apic)), n)
+	testValue(t, size-int(n), len(readAll(t, f)))
+
+	f.Seek(0, io.SeekStart)
+	m, err := ReadID3v2Tags(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Picture() != nil {
+		t.Errorf("expected no picture after RemovePictures")
+	}
+	testValue(t, "Title", m.Title())
+}
+
+func TestRemovePicturesMP4(t *testing.T) {
+	b, err := os.ReadFile("testdata/with_tags/sample.m4a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fastStart := range []bool{false, true} {
+		if fastStart {
+			b = testMP4FastStart(t, b)
+		}
+		f := tempFile(t, b)
+		addTestMP4Items(t, f, mp4Item("covr", 13, []byte("not really a jpeg")))
+
+		f.Seek(0, io.SeekStart)
+		m, err := ReadAtoms(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Picture() == nil {
+			t.Fatal("expected picture before RemovePictures")
+		}
+		size := len(readAll(t, f))
+		offset := testMP4ChunkOffset(t, f)
+
+		n, err := RemovePictures(f)
+		if err != nil {
+			t.Fatalf("RemovePictures() = %v", err)
+		}
+		testValue(t, int64(8+8+8+17), n)
+		testValue(t, size-int(n), len(readAll(t, f)))
+		if fastStart {
+			testValue(t, offset-n, testMP4ChunkOffset(t, f))
+		}
+
+		f.Seek(0, io.SeekStart)
+		m, err = ReadAtoms(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Picture() != nil {
+			t.Errorf("expected no picture after RemovePictures")
+		}
+		testValue(t, "Test Title", m.Title())
+
+		f.Seek(0, io.SeekStart)
+		got, err := SumAtoms(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := SumAtoms(bytes.NewReader(b))
+		testValue(t, want, got)
+	}
+}
+
+func TestRemovePicturesNotTruncatable(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	rw := struct{ io.ReadWriteSeeker }{f}
+	if _, err := removeFLACBlocks(rw, paddingBlock, FLACWriteOptions{}); err != ErrNotTruncatable {
+		t.Errorf("removeFLACBlocks() = %v, expected %v", err, ErrNotTruncatable)
+	}
+}
+
+func TestSetPicture(t *testing.T) {
+	png := append(append([]byte{}, pngHeader...), "new picture"...)
+	for _, path := range []string{
+		"with_tags/sample.flac",
+		"with_tags/sample.ogg",
+		"with_tags/sample.id3v23.mp3",
+		"with_tags/sample.id3v24.mp3",
+		"without_tags/sample.mp3",
+		"with_tags/sample.m4a",
+	} {
+		f := tempCopy(t, path)
+		before := map[string]interface{}{}
+		if m, err := ReadFrom(f); err == nil {
+			before = m.Raw()
+		} else if err != ErrNoTagsFound {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+		// Add a picture to replace.
+		err := SetPicture(f, &Picture{MIMEType: "image/jpeg", Data: []byte("\xff\xd8\xff old")})
+		if err != nil {
+			t.Fatalf("%v: SetPicture() = %v", path, err)
+		}
+
+		err = SetPicture(f, &Picture{MIMEType: "image/png", Description: "Front", Data: png})
+		if err != nil {
+			t.Fatalf("%v: SetPicture() = %v", path, err)
+		}
+		f.Seek(0, io.SeekStart)
+		after, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+
+		if !reflect.DeepEqual(withoutPictures(before), withoutPictures(after.Raw())) {
+			t.Errorf("%v: text fields changed: %v, expected %v", path, after.Raw(), before)
+		}
+		var covers []*Picture
+		for _, p := range after.Pictures() {
+			if p.Type == pictureFrontCover || p.Type == "" { // MP4 pictures have no type
+				covers = append(covers, p)
+			}
+		}
+		if len(covers) != 1 || !bytes.Equal(covers[0].Data, png) {
+			t.Errorf("%v: front covers = %v, expected the new picture", path, covers)
+		}
+	}
+
+	err := SetPicture(tempCopy(t, "with_tags/sample.flac"), &Picture{MIMEType: "image/jpeg", Data: pngHeader})
+	if err != ErrMIMEMismatch {
+		t.Errorf("SetPicture() = %v, expected %v", err, ErrMIMEMismatch)
+	}
+}
+
+// testCapabilityFile returns a temporary file of the given type.
+func testCapabilityFile(t *testing.T, fileType FileType) *os.File {
+	t.Helper()
+	switch fileType {
+	case MP3:
+		return tempCopy(t, "without_tags/sample.mp3")
+	case M4A:
+		return tempCopy(t, "without_tags/sample.m4a")
+	case M4B, M4P:
+		f := tempCopy(t, "without_tags/sample.m4a")
+		if _, err := f.WriteAt([]byte("ftyp"+string(fileType)+" "), 4); err != nil {
+			t.Fatal(err)
+		}
+		return f
+	case FLAC:
+		return tempCopy(t, "without_tags/sample.flac")
+	case OGG:
+		return tempCopy(t, "without_tags/sample.ogg")
+	case DSF:
+		return tempCopy(t, "with_tags/sample.dsf")
+	case AIFF:
+		return tempFile(t, testAIFF())
+	case DFF:
+		return tempFile(t, testDFF())
+	}
+	t.Fatalf("no test file for %v", fileType)
+	return nil
+}
+
+func TestSetPictureCapabilities(t *testing.T) {
+	png := append(append([]byte{}, pngHeader...), "picture"...)
+	for _, c := range Capabilities() {
+		if !c.CanWritePicture {
+			continue
+		}
+		f := testCapabilityFile(t, c.FileType)
+		if err := SetPicture(f, &Picture{MIMEType: "image/png", Data: png}); err != nil {
+			t.Errorf("%v: SetPicture() = %v", c.FileType, err)
+			continue
+		}
+
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Errorf("%v: ReadFrom() = %v", c.FileType, err)
+			continue
+		}
+		if p := m.Picture(); p == nil || !bytes.Equal(p.Data, png) {
+			t.Errorf("%v: Picture() = %v, expected the new picture", c.FileType, p)
+		}
+	}
+}
+
+// withoutPictures returns a copy of raw without the picture fields (including the encoded
+// pictures of Ogg files).
+func withoutPictures(raw map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{})
+	for k, v := range raw {
+		if _, ok := v.(*Picture); !ok && !strings.EqualFold(k, FieldVorbisPicture) {
+			res[k] = v
+		}
+	}
+	return res
+}
+
+func TestPOPMRating(t *testing.T) {
+	for stars := 0; stars <= 5; stars++ {
+		if got := popmStars(popmRating(stars * 20)); got != stars {
+			t.Errorf("popmStars(popmRating(%d)) = %d, expected %d", stars*20, got, stars)
+		}
+	}
+	testValue(t, byte(1), popmRating(5))
+	testValue(t, byte(196), popmRating(80))
+	testValue(t, byte(225), popmRating(90))
+}
+
+func TestSetRating(t *testing.T) {
+	for _, path := range []string{
+		"with_tags/sample.id3v23.mp3",
+		"without_tags/sample.mp3",
+		"with_tags/sample.m4a",
+	} {
+		f := tempCopy(t, path)
+		if err := SetRating(f, 80, ""); err != nil {
+			t.Fatalf("%v: SetRating() = %v", path, err)
+		}
+		f.Seek(0, io.SeekStart)
+		m, err := ReadFrom(f)
+		if err != nil {
+			t.Fatalf("%v: ReadFrom() = %v", path, err)
+		}
+		testValue(t, 4, m.Rating())
+	}
+
+	// The rating of an existing frame is updated, keeping the play counter.
+	audio, err := os.ReadFile("testdata/without_tags/sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := tempFile(t, append(testID3v2Tag(
+		id3v2RawFrame{Name: "POPM", Data: []byte("me@example.com\x00\x01\x00\x00\x00\x07")},
+	), audio...))
+	if err := SetRating(f, 80, "me@example.com"); err != nil {
+		t.Fatalf("SetRating() = %v", err)
+	}
+	f.Seek(0, io.SeekStart)
+	m, err := ReadFrom(f)
+	if err != nil {
+		t.Fatalf("ReadFrom() = %v", err)
+	}
+	want := &Popularimeter{Email: "me@example.com", Rating: 196, Counter: 7}
+	if got := m.Raw()["POPM"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("POPM = %v, expected %v", got, want)
+	}
+
+	if err := SetRating(f, 101, ""); err == nil {
+		t.Errorf("SetRating(101) = nil, expected error")
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps := make(map[FileType]FormatCapability)
+	for _, c := range Capabilities() {
+		caps[c.FileType] = c
+	}
+
+	tests := []FormatCapability{
+		{FileType: FLAC, CanRead: true, CanWrite: true, CanWritePicture: true},
+		{FileType: MP3, CanRead: true, CanWrite: true, CanWritePicture: true},
+		{FileType: M4A, CanRead: true, CanWrite: true, CanWritePicture: true},
+	}
+	for _, tt := range tests {
+		c, ok := caps[tt.FileType]
+		if !ok {
+			t.Errorf("Capabilities() missing %v", tt.FileType)
+			continue
+		}
+		if c != tt {
+			t.Errorf("Capabilities() %v = %+v, expected %+v", tt.FileType, c, tt)
+		}
+	}
+}
+
+func TestCapabilitiesMatchWriters(t *testing.T) {
+	png := append(append([]byte{}, pngHeader...), "picture"...)
+	for _, c := range Capabilities() {
+		if c.FileType == ALAC {
+			continue // not detected: ALAC files are identified as M4A
+		}
+
+		f := testCapabilityFile(t, c.FileType)
+		err := writeTags(f, map[string]string{FieldTitle: "Title"})
+		if (err == nil) != c.CanWrite {
+			t.Errorf("%v: writeTags() = %v, expected CanWrite = %v", c.FileType, err, c.CanWrite)
+		}
+		if err == nil {
+			f.Seek(0, io.SeekStart)
+			m, err := ReadFrom(f)
+			if err != nil {
+				t.Errorf("%v: ReadFrom() = %v", c.FileType, err)
+			} else {
+				testValue(t, "Title", m.Title())
+			}
+		}
+
+		f = testCapabilityFile(t, c.FileType)
+		err = SetPicture(f, &Picture{MIMEType: "image/png", Data: png})
+		if (err == nil) != c.CanWritePicture {
+			t.Errorf("%v: SetPicture() = %v, expected CanWritePicture = %v", c.FileType, err, c.CanWritePicture)
+		}
+	}
+}
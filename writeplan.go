@@ -0,0 +1,90 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WritePlan is the result of MinimalWrite.
+type WritePlan struct {
+	// Changes are the edits which change the metadata, keyed by the (upper case) Field* names.
+	Changes map[string]string
+}
+
+// NoOp returns true if none of the edits change the metadata, so writing can be skipped.
+func (p WritePlan) NoOp() bool {
+	return len(p.Changes) == 0
+}
+
+// stringFields maps field names to the accessors which return their values.
+var stringFields = map[string]func(Metadata) string{
+	FieldTitle:       Metadata.Title,
+	FieldAlbum:       Metadata.Album,
+	FieldArtist:      Metadata.Artist,
+	FieldAlbumArtist: Metadata.AlbumArtist,
+	FieldComposer:    Metadata.Composer,
+	FieldGenre:       Metadata.Genre,
+	FieldComment:     Metadata.Comment,
+	FieldLyrics:      Metadata.Lyrics,
+	FieldMood:        Metadata.Mood,
+	FieldCopyright:   Metadata.Copyright,
+	FieldPublisher:   Metadata.Publisher,
+	FieldOwner:       Metadata.Owner,
+}
+
+// MinimalWrite compares the fields in edits (keyed by the Field* names, as passed to the
+// Write* functions) with the metadata read from r, returning a WritePlan with the edits which
+// change it.  Edits are interpreted as by Overlay, so an edit to the current value (i.e. a
+// TRACKNUMBER of "5" when the track is 5 of 12) is not a change.  Fields which can't be
+// compared (those without a Metadata accessor, and dates with more than a year) are always
+// treated as changes.  If the plan is a no-op then writing can be skipped.
+func MinimalWrite(r io.ReadSeeker, edits map[string]string) (WritePlan, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return WritePlan{}, err
+	}
+	m, err := ReadFrom(r)
+	if err != nil {
+		return WritePlan{}, err
+	}
+
+	p := WritePlan{Changes: make(map[string]string)}
+	for k, v := range normaliseFields(edits) {
+		if !fieldUnchanged(m, k, v) {
+			p.Changes[k] = v
+		}
+	}
+	return p, nil
+}
+
+// fieldUnchanged returns true if setting field to v (which must be normalised) doesn't change m.
+func fieldUnchanged(m Metadata, field, v string) bool {
+	if f, ok := stringFields[field]; ok {
+		return f(m) == v
+	}
+
+	o := Overlay(m, map[string]string{field: v})
+	switch field {
+	case FieldTrackNumber, FieldTrackTotal:
+		x, n := m.Track()
+		ox, on := o.Track()
+		return x == ox && n == on
+
+	case FieldDiscNumber, FieldDiscTotal:
+		x, n := m.Disc()
+		ox, on := o.Disc()
+		return x == ox && n == on
+
+	case FieldDate, FieldYear:
+		y := ""
+		if m.Year() != 0 {
+			y = strconv.Itoa(m.Year())
+		}
+		return strings.TrimSpace(v) == y
+	}
+	return false
+}
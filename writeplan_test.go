@@ -0,0 +1,49 @@
+// Copyright 2015, David Howden
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tag
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestMinimalWrite(t *testing.T) {
+	f := tempCopy(t, "with_tags/sample.flac")
+	m := testReadFLAC(t, f)
+	track, _ := m.Track()
+
+	p, err := MinimalWrite(f, map[string]string{
+		"title":          m.Title(),
+		FieldArtist:      m.Artist(),
+		FieldAlbum:       m.Album(),
+		FieldDate:        strconv.Itoa(m.Year()),
+		FieldTrackNumber: strconv.Itoa(track),
+	})
+	if err != nil {
+		t.Fatalf("MinimalWrite() = %v", err)
+	}
+	if !p.NoOp() {
+		t.Errorf("NoOp() = false, expected true: changes %v", p.Changes)
+	}
+
+	p, err = MinimalWrite(f, map[string]string{
+		FieldTitle:       "New Title",
+		FieldArtist:      m.Artist(),
+		FieldTrackNumber: strconv.Itoa(track + 1),
+		"CUSTOM":         "value", // can't be compared
+	})
+	if err != nil {
+		t.Fatalf("MinimalWrite() = %v", err)
+	}
+	want := map[string]string{
+		FieldTitle:       "New Title",
+		FieldTrackNumber: strconv.Itoa(track + 1),
+		"CUSTOM":         "value",
+	}
+	if p.NoOp() || !reflect.DeepEqual(p.Changes, want) {
+		t.Errorf("Changes = %v, expected %v", p.Changes, want)
+	}
+}